@@ -0,0 +1,325 @@
+/*
+  Copyright 2026 Davide Madrisan <d.madrisan@proton.me>
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+      http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"text/template"
+	"time"
+
+	mattermost "github.com/madrisan/go-mattermost-notify/mattermost"
+	"github.com/spf13/viper"
+
+	"github.com/madrisan/go-mattermost-notify/config"
+)
+
+var (
+	// messageActions contains the buttons (in the form Label=id) added to the post.
+	messageActions []string
+	// messageMenus contains the select menus (in the form Label=id:option1,option2...) added to the post.
+	messageMenus []string
+	// actionURL is the URL of the serve endpoint /actions called when a user clicks on an action.
+	actionURL string
+)
+
+// actionIDRegexp matches the valid action IDs. Mattermost does not accept other characters.
+var actionIDRegexp = regexp.MustCompile(`^[A-Za-z0-9]+$`)
+
+// defaultActionTemplate is the template of the result of an action when not configured.
+const defaultActionTemplate = "@{{.UserName}} has run **{{.Action}}**" +
+	"{{with .SelectedOption}} ({{.}}){{end}}" +
+	"{{if .Command}}: {{if .TimedOut}}timed out{{else}}exit code {{.ExitCode}}{{end}}{{with .Output}}\n```\n{{.}}\n```{{end}}{{end}}"
+
+// actionHandler is the handler of an action, set in the 'actions.handlers' section of the
+// configuration file: an optional command and the template of the result.
+type actionHandler struct {
+	Command  []string      `mapstructure:"command"`
+	Timeout  time.Duration `mapstructure:"timeout"`
+	Template string        `mapstructure:"template"`
+}
+
+// actionRequest is the request sent by Mattermost when a user clicks on an action.
+type actionRequest struct {
+	UserID    string                 `json:"user_id"`
+	UserName  string                 `json:"user_name"`
+	ChannelID string                 `json:"channel_id"`
+	PostID    string                 `json:"post_id"`
+	Context   map[string]interface{} `json:"context"`
+}
+
+// actionUpdate is the update of the post containing the action, sent back to Mattermost.
+type actionUpdate struct {
+	Message string                   `json:"message"`
+	Props   mattermost.MsgProperties `json:"props"`
+}
+
+// actionResponse is the response sent back to Mattermost.
+type actionResponse struct {
+	Update        *actionUpdate `json:"update,omitempty"`
+	EphemeralText string        `json:"ephemeral_text,omitempty"`
+}
+
+// actionResult contains the data available to the template of the action result.
+type actionResult struct {
+	Action         string
+	UserName       string
+	ChannelID      string
+	PostID         string
+	SelectedOption string
+	Command        string
+	ExitCode       int
+	// TimedOut tells if the command has been killed because of the timeout.
+	TimedOut bool
+	Output   string
+}
+
+// getActionHandlers returns the action handlers set in the configuration file, indexed by action ID.
+func getActionHandlers() (map[string]actionHandler, error) {
+	var handlers map[string]actionHandler
+	if err := viper.UnmarshalKey("actions.handlers", &handlers); err != nil {
+		return nil, fmt.Errorf("invalid 'actions.handlers' section in the configuration file: %v", err)
+	}
+	return handlers, nil
+}
+
+// parseActions returns the buttons (in the form Label=id) and the select menus (in the form
+// Label=id:option1,option2...) calling the given integration URL with the given token.
+func parseActions(buttons, menus []string, url, token string) ([]mattermost.MsgAction, error) {
+	if len(buttons) == 0 && len(menus) == 0 {
+		return nil, nil
+	}
+	if url == "" {
+		return nil, fmt.Errorf("the action URL must be set at command-line or in the configuration file (key actions.url)")
+	}
+	if token == "" {
+		return nil, fmt.Errorf("the action token must be set in the configuration file (key actions.token)")
+	}
+
+	parse := func(value string) (string, string, error) {
+		label, id, found := strings.Cut(value, "=")
+		if !found || label == "" || !actionIDRegexp.MatchString(id) {
+			return "", "", fmt.Errorf("invalid action \"%s\": must be in the form Label=id, id being alphanumeric", value)
+		}
+		return label, id, nil
+	}
+	integration := func(id string) mattermost.MsgIntegration {
+		return mattermost.MsgIntegration{
+			URL:     url,
+			Context: map[string]interface{}{"action": id, "token": token},
+		}
+	}
+
+	var actions []mattermost.MsgAction
+	for _, button := range buttons {
+		label, id, err := parse(button)
+		if err != nil {
+			return nil, err
+		}
+		actions = append(actions, mattermost.MsgAction{
+			ID:          id,
+			Name:        label,
+			Type:        "button",
+			Integration: integration(id),
+		})
+	}
+	for _, menu := range menus {
+		definition, values, found := strings.Cut(menu, ":")
+		label, id, err := parse(definition)
+		if err != nil || !found || values == "" {
+			return nil, fmt.Errorf("invalid menu \"%s\": must be in the form Label=id:option1,option2...", menu)
+		}
+		var options []mattermost.MsgActionOption
+		for _, value := range strings.Split(values, ",") {
+			options = append(options, mattermost.MsgActionOption{Text: value, Value: value})
+		}
+		actions = append(actions, mattermost.MsgAction{
+			ID:          id,
+			Name:        label,
+			Type:        "select",
+			Options:     options,
+			Integration: integration(id),
+		})
+	}
+
+	return actions, nil
+}
+
+// getPostProperties returns the properties of the post with the given ID.
+func getPostProperties(postID string, opts config.Options) (mattermost.MsgProperties, error) {
+	var post struct {
+		Props mattermost.MsgProperties `json:"props"`
+	}
+
+	response, err := mattermostGet("/posts/"+postID, opts)
+	if err != nil {
+		return post.Props, err
+	}
+	data, err := json.Marshal(response)
+	if err != nil {
+		return post.Props, err
+	}
+	if err := json.Unmarshal(data, &post); err != nil {
+		return post.Props, err
+	}
+	if len(post.Props.Attachments) == 0 {
+		return post.Props, fmt.Errorf("the post %s has no attachment", postID)
+	}
+
+	return post.Props, nil
+}
+
+// withActionResult returns a copy of the post properties without the actions, and with the given
+// result added to the first attachment. The attachment color is changed when color is not empty.
+func withActionResult(props mattermost.MsgProperties, result, color string) mattermost.MsgProperties {
+	var attachments = make([]mattermost.MsgAttachment, len(props.Attachments))
+	for i, a := range props.Attachments {
+		a.Actions = nil
+		attachments[i] = a
+	}
+
+	attachments[0].Fields = append(append([]mattermost.MsgField(nil), attachments[0].Fields...),
+		mattermost.MsgField{Title: "Action", Value: result})
+	if color != "" {
+		attachments[0].Color = color
+	}
+
+	return mattermost.MsgProperties{Attachments: attachments}
+}
+
+// runActionCommand runs the command of the action handler in the worker pool of the server, passing
+// the action properties as MM_* environment variables, and records its exit code, timeout and output
+// in the result.
+func (s *relayServer) runActionCommand(h actionHandler, result *actionResult) {
+	result.ExitCode, result.TimedOut, result.Output = s.runJobCommand(h.Command, []string{
+		"MM_ACTION=" + result.Action,
+		"MM_USER_NAME=" + result.UserName,
+		"MM_CHANNEL_ID=" + result.ChannelID,
+		"MM_POST_ID=" + result.PostID,
+		"MM_SELECTED_OPTION=" + result.SelectedOption,
+	}, h.Timeout)
+}
+
+// renderActionResult returns the text of the action result.
+func renderActionResult(h actionHandler, result actionResult) (string, error) {
+	text := h.Template
+	if text == "" {
+		text = defaultActionTemplate
+	}
+	tmpl, err := template.New("action").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("invalid template of the action %s: %v", result.Action, err)
+	}
+
+	var b bytes.Buffer
+	if err := tmpl.Execute(&b, result); err != nil {
+		return "", fmt.Errorf("cannot render the result of the action %s: %v", result.Action, err)
+	}
+	return b.String(), nil
+}
+
+// handleAction runs the handler of the action clicked by a Mattermost user and updates the post
+// with the result. The handlers running a command answer immediately, and the post is updated
+// again when the command completes.
+func (s *relayServer) handleAction(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestBodySize)
+
+	var req actionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid JSON request: %v", err))
+		return
+	}
+
+	token, _ := req.Context["token"].(string)
+	if subtle.ConstantTimeCompare([]byte(token), []byte(s.actionToken)) != 1 {
+		writeError(w, http.StatusUnauthorized, fmt.Errorf("invalid or missing action token"))
+		return
+	}
+	if p, ok := r.Context().Value(relayClientKey{}).(*string); ok {
+		*p = "@" + req.UserName
+	}
+
+	action, _ := req.Context["action"].(string)
+	h, found := s.actionHandlers[strings.ToLower(action)]
+	if !found {
+		writeError(w, http.StatusNotFound, fmt.Errorf("no handler for the action \"%s\"", action))
+		return
+	}
+
+	props, err := getPostProperties(req.PostID, s.opts)
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+
+	result := actionResult{
+		Action:    action,
+		UserName:  req.UserName,
+		ChannelID: req.ChannelID,
+		PostID:    req.PostID,
+		Command:   getCommandLine(h.Command),
+	}
+	result.SelectedOption, _ = req.Context["selected_option"].(string)
+
+	if len(h.Command) == 0 {
+		text, err := renderActionResult(h, result)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, actionResponse{
+			Update: &actionUpdate{Props: withActionResult(props, text, "")},
+		})
+		return
+	}
+
+	s.jobs.Add(1)
+	go func() {
+		defer s.jobs.Done()
+
+		s.runActionCommand(h, &result)
+
+		text, err := renderActionResult(h, result)
+		if err != nil {
+			s.logger.Printf("action %s: %v", action, err)
+			text = err.Error()
+		}
+
+		var color = colorSuccess
+		if result.ExitCode != 0 || result.TimedOut {
+			color = colorCritical
+		}
+		payload, err := json.Marshal(map[string]interface{}{"props": withActionResult(props, text, color)})
+		if err == nil {
+			_, err = mattermostPut("/posts/"+req.PostID+"/patch", bytes.NewReader(payload), s.opts)
+		}
+		if err != nil {
+			s.logger.Printf("action %s: cannot update the post %s: %v", action, req.PostID, err)
+		}
+	}()
+
+	running := fmt.Sprintf("Running, triggered by @%s", req.UserName)
+	writeJSON(w, http.StatusOK, actionResponse{
+		Update:        &actionUpdate{Props: withActionResult(props, running, "")},
+		EphemeralText: fmt.Sprintf("The action %s has been started.", action),
+	})
+}
@@ -0,0 +1,185 @@
+/*
+  Copyright 2026 Davide Madrisan <d.madrisan@proton.me>
+
+  Licensed under the Mozilla Public License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+      https://www.mozilla.org/en-US/MPL/2.0/
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+*/
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/go-test/deep"
+
+	"github.com/madrisan/go-mattermost-notify/config"
+	mattermost "github.com/madrisan/go-mattermost-notify/mattermost"
+)
+
+func TestActionHelperProcess(t *testing.T) {
+	if os.Getenv("GO_WANT_ACTION_HELPER_PROCESS") != "1" {
+		return
+	}
+	fmt.Printf("rolling back for %s (%s)\n", os.Getenv("MM_USER_NAME"), os.Getenv("MM_SELECTED_OPTION"))
+	os.Exit(3)
+}
+
+func TestParseActions(t *testing.T) {
+	t.Parallel()
+
+	integration := mattermost.MsgIntegration{
+		URL:     "http://ops:8066/actions",
+		Context: map[string]interface{}{"action": "rollback", "token": "secret"},
+	}
+
+	actions, err := parseActions([]string{"Rollback=rollback"}, []string{"Silence=silence:1h,4h"},
+		"http://ops:8066/actions", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	shouldBe := []mattermost.MsgAction{
+		{ID: "rollback", Name: "Rollback", Type: "button", Integration: integration},
+		{ID: "silence", Name: "Silence", Type: "select",
+			Options: []mattermost.MsgActionOption{{Text: "1h", Value: "1h"}, {Text: "4h", Value: "4h"}},
+			Integration: mattermost.MsgIntegration{
+				URL:     "http://ops:8066/actions",
+				Context: map[string]interface{}{"action": "silence", "token": "secret"},
+			},
+		},
+	}
+	if diff := deep.Equal(actions, shouldBe); diff != nil {
+		t.Error(diff)
+	}
+
+	cases := []struct {
+		name    string
+		buttons []string
+		menus   []string
+		url     string
+		token   string
+	}{
+		{"no URL", []string{"Rollback=rollback"}, nil, "", "secret"},
+		{"no token", []string{"Rollback=rollback"}, nil, "http://ops:8066/actions", ""},
+		{"invalid id", []string{"Rollback=roll-back"}, nil, "http://ops:8066/actions", "secret"},
+		{"no options", nil, []string{"Silence=silence"}, "http://ops:8066/actions", "secret"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := parseActions(tc.buttons, tc.menus, tc.url, tc.token); err == nil {
+				t.Error("an error was expected")
+			}
+		})
+	}
+}
+
+func TestHandleAction(t *testing.T) {
+	oldMattermostGet, oldMattermostPut := mattermostGet, mattermostPut
+	defer func() {
+		mattermostGet, mattermostPut = oldMattermostGet, oldMattermostPut
+	}()
+
+	mattermostGet = func(endpoint string, opts config.Options) (interface{}, error) {
+		if endpoint != "/posts/post1" {
+			return nil, fmt.Errorf("unexpected endpoint %s", endpoint)
+		}
+		var data interface{}
+		err := json.Unmarshal([]byte(`{"id": "post1", "props": {"attachments": [{"title": "Deploy",
+			"actions": [{"id": "rollback", "name": "Rollback"}]}]}}`), &data)
+		return data, err
+	}
+	var patched mattermost.MsgPayload
+	mattermostPut = func(endpoint string, payload io.Reader, opts config.Options) (interface{}, error) {
+		if endpoint != "/posts/post1/patch" {
+			return nil, fmt.Errorf("unexpected endpoint %s", endpoint)
+		}
+		return nil, json.NewDecoder(payload).Decode(&patched)
+	}
+
+	t.Setenv("GO_WANT_ACTION_HELPER_PROCESS", "1")
+	s := &relayServer{
+		logger:      log.New(io.Discard, "", 0),
+		actionToken: "secret",
+		actionHandlers: map[string]actionHandler{
+			"rollback": {Command: []string{os.Args[0], "-test.run=TestActionHelperProcess"}},
+			"silence":  {Template: "Silenced for {{.SelectedOption}} by @{{.UserName}}"},
+		},
+		workers: make(chan struct{}, 1),
+		jobsCtx: context.Background(),
+	}
+	srv := httptest.NewServer(s.handler())
+	defer srv.Close()
+
+	request := func(context string) (*http.Response, actionResponse) {
+		body := `{"user_name": "alice", "post_id": "post1", "context": ` + context + `}`
+		resp, err := http.Post(srv.URL+"/actions", "application/json", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		var data actionResponse
+		json.NewDecoder(resp.Body).Decode(&data)
+		return resp, data
+	}
+
+	t.Run("invalid token", func(t *testing.T) {
+		resp, _ := request(`{"action": "silence", "token": "wrong"}`)
+		if resp.StatusCode != http.StatusUnauthorized {
+			t.Error("expected status 401, got", resp.StatusCode)
+		}
+	})
+
+	t.Run("unknown action", func(t *testing.T) {
+		resp, _ := request(`{"action": "reboot", "token": "secret"}`)
+		if resp.StatusCode != http.StatusNotFound {
+			t.Error("expected status 404, got", resp.StatusCode)
+		}
+	})
+
+	t.Run("template", func(t *testing.T) {
+		_, data := request(`{"action": "silence", "token": "secret", "selected_option": "4h"}`)
+		shouldBe := []mattermost.MsgAttachment{{
+			Title:  "Deploy",
+			Fields: []mattermost.MsgField{{Title: "Action", Value: "Silenced for 4h by @alice"}},
+		}}
+		if data.Update == nil {
+			t.Fatal("the post should be updated")
+		}
+		if diff := deep.Equal(data.Update.Props.Attachments, shouldBe); diff != nil {
+			t.Error(diff)
+		}
+	})
+
+	t.Run("command", func(t *testing.T) {
+		_, data := request(`{"action": "rollback", "token": "secret"}`)
+		if data.Update == nil || data.Update.Props.Attachments[0].Fields[0].Value != "Running, triggered by @alice" {
+			t.Error("unexpected update", data.Update)
+		}
+		s.jobs.Wait()
+
+		attachment := patched.Properties.Attachments[0]
+		if attachment.Color != colorCritical || len(attachment.Actions) != 0 {
+			t.Error("unexpected patched attachment", attachment)
+		}
+		if v := attachment.Fields[0].Value; !strings.Contains(v, "exit code 3") || !strings.Contains(v, "rolling back for alice ()") {
+			t.Error("unexpected result", v)
+		}
+	})
+}
@@ -0,0 +1,181 @@
+/*
+  Copyright 2026 Davide Madrisan <d.madrisan@proton.me>
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+      http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+*/
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	mattermost "github.com/madrisan/go-mattermost-notify/mattermost"
+)
+
+// alertmanagerAlert is an alert of a Prometheus Alertmanager webhook notification.
+type alertmanagerAlert struct {
+	Status       string            `json:"status"`
+	Labels       map[string]string `json:"labels"`
+	Annotations  map[string]string `json:"annotations"`
+	StartsAt     time.Time         `json:"startsAt"`
+	EndsAt       time.Time         `json:"endsAt"`
+	GeneratorURL string            `json:"generatorURL"`
+	Fingerprint  string            `json:"fingerprint"`
+}
+
+// alertmanagerMessage is a Prometheus Alertmanager webhook notification (version 4).
+type alertmanagerMessage struct {
+	Version           string              `json:"version"`
+	GroupKey          string              `json:"groupKey"`
+	TruncatedAlerts   int                 `json:"truncatedAlerts"`
+	Status            string              `json:"status"`
+	Receiver          string              `json:"receiver"`
+	GroupLabels       map[string]string   `json:"groupLabels"`
+	CommonLabels      map[string]string   `json:"commonLabels"`
+	CommonAnnotations map[string]string   `json:"commonAnnotations"`
+	ExternalURL       string              `json:"externalURL"`
+	Alerts            []alertmanagerAlert `json:"alerts"`
+}
+
+// level returns the criticity level of the alert group: "success" when resolved,
+// or the severity label of the alerts when firing ("critical" if not set or unknown).
+func (m *alertmanagerMessage) level() string {
+	if m.Status == "resolved" {
+		return "success"
+	}
+
+	switch severity := strings.ToLower(m.CommonLabels["severity"]); severity {
+	case "critical", "warning", "info":
+		return severity
+	}
+
+	return "critical"
+}
+
+// title returns the title of the post notifying the alert group, in the format used by
+// the Alertmanager default templates. Example: [FIRING:2] HighLatency (api production)
+func (m *alertmanagerMessage) title() string {
+	var firing int
+	for _, alert := range m.Alerts {
+		if alert.Status == "firing" {
+			firing++
+		}
+	}
+
+	var title = fmt.Sprintf("[%s", strings.ToUpper(m.Status))
+	if m.Status == "firing" {
+		title += fmt.Sprintf(":%d", firing)
+	}
+	title += "]"
+
+	if name, found := m.GroupLabels["alertname"]; found {
+		title += " " + name
+	}
+
+	var labels []string
+	for _, field := range getMsgFields(m.GroupLabels) {
+		if field.Title != "alertname" {
+			labels = append(labels, field.Value)
+		}
+	}
+	if len(labels) > 0 {
+		title += " (" + strings.Join(labels, " ") + ")"
+	}
+
+	return title
+}
+
+// text returns the markdown description of the alerts of the group.
+func (m *alertmanagerMessage) text() string {
+	var b strings.Builder
+
+	for _, alert := range m.Alerts {
+		summary := alert.Annotations["summary"]
+		if summary == "" {
+			summary = alert.Labels["alertname"]
+		}
+		if alert.GeneratorURL != "" {
+			summary = fmt.Sprintf("[%s](%s)", summary, alert.GeneratorURL)
+		}
+
+		fmt.Fprintf(&b, "- **%s** %s", strings.ToUpper(alert.Status), summary)
+		fmt.Fprintf(&b, " (started at %s", alert.StartsAt.Local().Format(time.RFC1123))
+		if alert.Status == "resolved" && !alert.EndsAt.IsZero() {
+			fmt.Fprintf(&b, ", ended at %s", alert.EndsAt.Local().Format(time.RFC1123))
+		}
+		b.WriteString(")\n")
+
+		if description := alert.Annotations["description"]; description != "" {
+			fmt.Fprintf(&b, "  %s\n", description)
+		}
+	}
+
+	if m.TruncatedAlerts > 0 {
+		fmt.Fprintf(&b, "\n_%d more alerts have been truncated_\n", m.TruncatedAlerts)
+	}
+
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// handleAlertmanager posts to Mattermost a Prometheus Alertmanager webhook notification.
+// A notification for an alert group that is already firing is posted as a reply
+// in the thread of the first firing notification.
+// The destination channel is set by the 'channel' query parameter or is selected by the
+// routing rules, using the common labels of the alerts as labels.
+func (s *relayServer) handleAlertmanager(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestBodySize)
+
+	var alert alertmanagerMessage
+	if err := json.NewDecoder(r.Body).Decode(&alert); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid Alertmanager notification: %v", err))
+		return
+	}
+	if alert.Version != "4" {
+		writeError(w, http.StatusBadRequest,
+			fmt.Errorf("unsupported Alertmanager notification version: \"%s\"", alert.Version))
+		return
+	}
+
+	var author = "Alertmanager"
+	var level = alert.level()
+
+	msg := message{
+		Author:      author,
+		Labels:      normalizeLabels(alert.CommonLabels),
+		Level:       level,
+		Text:        alert.text(),
+		Title:       alert.title(),
+		Options:     []mattermost.MsgOption{mattermost.WithFields(getMsgFields(alert.GroupLabels))},
+		Threads:     &s.alertThreads,
+		ThreadKey:   alert.GroupKey,
+		CloseThread: alert.Status == "resolved",
+	}
+
+	sent, err := postMessage(r.URL.Query().Get("channel"), msg, false, s.opts)
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+
+	var posts []relayPost
+	for _, post := range sent {
+		postID, _ := getKV(post.Response, "id")
+		posts = append(posts, relayPost{Channel: post.Destination, ID: postID})
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"posts": posts})
+}
@@ -0,0 +1,128 @@
+/*
+  Copyright 2026 Davide Madrisan <d.madrisan@proton.me>
+
+  Licensed under the Mozilla Public License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+      https://www.mozilla.org/en-US/MPL/2.0/
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+*/
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/madrisan/go-mattermost-notify/config"
+	mattermost "github.com/madrisan/go-mattermost-notify/mattermost"
+)
+
+const alertmanagerNotification = `{
+  "version": "4",
+  "groupKey": "{}:{alertname=\"HighLatency\"}",
+  "status": "%s",
+  "receiver": "mattermost",
+  "groupLabels": {"alertname": "HighLatency", "service": "api"},
+  "commonLabels": {"alertname": "HighLatency", "service": "api", "severity": "warning"},
+  "commonAnnotations": {},
+  "externalURL": "http://alertmanager:9093",
+  "alerts": [
+    {
+      "status": "%s",
+      "labels": {"alertname": "HighLatency", "instance": "api1"},
+      "annotations": {"summary": "High latency on api1"},
+      "startsAt": "2026-10-19T10:00:00Z",
+      "endsAt": "%s",
+      "generatorURL": "http://prometheus:9090/graph"
+    }
+  ]
+}`
+
+func TestAlertmanagerMessage(t *testing.T) {
+	t.Parallel()
+
+	var msg alertmanagerMessage
+	data := fmt.Sprintf(alertmanagerNotification, "firing", "firing", "0001-01-01T00:00:00Z")
+	if err := json.Unmarshal([]byte(data), &msg); err != nil {
+		t.Fatal(err)
+	}
+
+	if v := msg.level(); v != "warning" {
+		t.Error("expected level warning, got", v)
+	}
+	if v := msg.title(); v != "[FIRING:1] HighLatency (api)" {
+		t.Error("unexpected title", v)
+	}
+	if v := msg.text(); !strings.HasPrefix(v, "- **FIRING** [High latency on api1](http://prometheus:9090/graph) (started at") {
+		t.Error("unexpected text", v)
+	}
+
+	msg.Status = "resolved"
+	if v := msg.level(); v != "success" {
+		t.Error("expected level success, got", v)
+	}
+}
+
+func TestHandleAlertmanager(t *testing.T) {
+	oldMattermostPost := mattermostPost
+	defer func() {
+		mattermostPost = oldMattermostPost
+	}()
+
+	var posted []mattermost.MsgPayload
+	mattermostPost = func(endpoint string, payload io.Reader, opts config.Options) (interface{}, error) {
+		var data mattermost.MsgPayload
+		if err := json.NewDecoder(payload).Decode(&data); err != nil {
+			return nil, err
+		}
+		posted = append(posted, data)
+		return map[string]interface{}{"id": fmt.Sprintf("post%d", len(posted))}, nil
+	}
+
+	s := &relayServer{
+		apiKeys: map[string]string{"secret": "alertmanager"},
+		logger:  log.New(io.Discard, "", 0),
+	}
+	srv := httptest.NewServer(s.handler())
+	defer srv.Close()
+
+	notify := func(status, endsAt string) {
+		body := fmt.Sprintf(alertmanagerNotification, status, status, endsAt)
+		req, _ := http.NewRequest(http.MethodPost, srv.URL+"/alertmanager?channel=alerts", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer secret")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatal("expected status 200, got", resp.StatusCode)
+		}
+	}
+
+	notify("firing", "0001-01-01T00:00:00Z")
+	notify("resolved", "2026-10-19T10:30:00Z")
+
+	if len(posted) != 2 {
+		t.Fatal("expected two posts, got", len(posted))
+	}
+	if posted[0].RootID != "" || posted[0].Properties.Attachments[0].Color != colorWarning {
+		t.Error("unexpected firing post", posted[0])
+	}
+	if posted[1].RootID != "post1" || posted[1].Properties.Attachments[0].Color != colorSuccess {
+		t.Error("the resolved post should be a reply to the firing one", posted[1])
+	}
+}
@@ -0,0 +1,401 @@
+/*
+  Copyright 2026 Davide Madrisan <d.madrisan@proton.me>
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+      http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	mattermost "github.com/madrisan/go-mattermost-notify/mattermost"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/madrisan/go-mattermost-notify/config"
+)
+
+var (
+	// approvers contains the usernames of the users allowed to approve or deny the request.
+	approvers []string
+	// approverGroups contains the names of the groups whose members can approve or deny the request.
+	approverGroups []string
+	// approvalTimeout is the maximum time to wait for a decision.
+	approvalTimeout time.Duration
+	// approvalPollInterval is the time between two checks of the reactions and replies.
+	approvalPollInterval time.Duration
+)
+
+// The exit codes of the approve command, besides 0 on approval. They differ from the code 1
+// returned on error by the other commands, so that the scripts can tell a denial from a failure.
+const (
+	exitCodeDenied              = 1
+	exitCodeApprovalTimeout     = 2
+	exitCodeApprovalError       = 3
+	exitCodeApprovalInterrupted = 4
+)
+
+// usersPerPage is the number of users requested per page to Mattermost.
+const usersPerPage = 200
+
+// The emojis and replies used for approving or denying a request.
+var (
+	approveReactions = map[string]bool{"+1": true, "thumbsup": true}
+	denyReactions    = map[string]bool{"-1": true, "thumbsdown": true}
+	approveReplies   = map[string]bool{"approve": true, "approved": true}
+	denyReplies      = map[string]bool{"deny": true, "denied": true}
+)
+
+// approvalDecision is the approval or denial of a request by a user.
+type approvalDecision struct {
+	Approved bool
+	UserID   string
+	Time     time.Time
+}
+
+// getList returns the list contained in the JSON response data.
+// A null response is an empty list.
+func getList(response interface{}) ([]interface{}, error) {
+	switch data := response.(type) {
+	case nil:
+		return nil, nil
+	case []interface{}:
+		return data, nil
+	}
+	return nil, fmt.Errorf("unexpected response format from Mattermost (%T)", response)
+}
+
+// getMillis returns the time of the key in the JSON object, set by Mattermost in milliseconds.
+func getMillis(object map[string]interface{}, key string) time.Time {
+	ms, _ := object[key].(float64)
+	return time.UnixMilli(int64(ms))
+}
+
+// getGroupMemberIDs returns the IDs of the members of the Mattermost group with the given name.
+func getGroupMemberIDs(name string, opts config.Options) ([]string, error) {
+	response, err := mattermostGet("/groups?q="+url.QueryEscape(name)+"&per_page=200", opts)
+	if err != nil {
+		return nil, err
+	}
+	groups, err := getList(response)
+	if err != nil {
+		return nil, err
+	}
+
+	var groupID string
+	for _, g := range groups {
+		if groupName, _ := getKV(g, "name"); groupName == name {
+			groupID, _ = getKV(g, "id")
+			break
+		}
+	}
+	if groupID == "" {
+		return nil, fmt.Errorf("no such Mattermost group: %s", name)
+	}
+
+	var ids []string
+	for page := 0; ; page++ {
+		endpoint := fmt.Sprintf("/users?in_group=%s&page=%d&per_page=%d", groupID, page, usersPerPage)
+		response, err := mattermostGet(endpoint, opts)
+		if err != nil {
+			return nil, err
+		}
+		users, err := getList(response)
+		if err != nil {
+			return nil, err
+		}
+		for _, u := range users {
+			if id, err := getKV(u, "id"); err == nil {
+				ids = append(ids, id)
+			}
+		}
+		if len(users) < usersPerPage {
+			return ids, nil
+		}
+	}
+}
+
+// getApproverIDs returns the IDs of the given users and of the members of the given groups.
+func getApproverIDs(users, groups []string, opts config.Options) (map[string]bool, error) {
+	var ids = make(map[string]bool)
+	for _, username := range users {
+		id, err := getUserID(strings.TrimPrefix(username, "@"), opts)
+		if err != nil {
+			return nil, err
+		}
+		ids[id] = true
+	}
+	for _, group := range groups {
+		members, err := getGroupMemberIDs(strings.TrimPrefix(group, "@"), opts)
+		if err != nil {
+			return nil, err
+		}
+		for _, id := range members {
+			ids[id] = true
+		}
+	}
+	return ids, nil
+}
+
+// getApprovalDecision returns the first decision taken by one of the approvers on the given post,
+// by reacting to it or by replying in its thread, or nil if no decision has been taken yet.
+func getApprovalDecision(postID string, approverIDs map[string]bool, opts config.Options) (*approvalDecision, error) {
+	var decision *approvalDecision
+	consider := func(userID string, approved bool, at time.Time) {
+		if approverIDs[userID] && (decision == nil || at.Before(decision.Time)) {
+			decision = &approvalDecision{Approved: approved, UserID: userID, Time: at}
+		}
+	}
+
+	response, err := mattermostGet("/posts/"+postID+"/reactions", opts)
+	if err != nil {
+		return nil, err
+	}
+	reactions, err := getList(response)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range reactions {
+		reaction, _ := r.(map[string]interface{})
+		userID, _ := getKV(reaction, "user_id")
+		emoji, _ := getKV(reaction, "emoji_name")
+		switch {
+		case approveReactions[emoji]:
+			consider(userID, true, getMillis(reaction, "create_at"))
+		case denyReactions[emoji]:
+			consider(userID, false, getMillis(reaction, "create_at"))
+		}
+	}
+
+	response, err = mattermostGet("/posts/"+postID+"/thread", opts)
+	if err != nil {
+		return nil, err
+	}
+	thread, _ := response.(map[string]interface{})
+	posts, _ := thread["posts"].(map[string]interface{})
+	for _, p := range posts {
+		post, _ := p.(map[string]interface{})
+		if rootID, _ := getKV(post, "root_id"); rootID != postID {
+			continue
+		}
+		userID, _ := getKV(post, "user_id")
+		text, _ := getKV(post, "message")
+		text = strings.ToLower(strings.Trim(strings.TrimSpace(text), ".!"))
+		switch {
+		case approveReplies[text]:
+			consider(userID, true, getMillis(post, "create_at"))
+		case denyReplies[text]:
+			consider(userID, false, getMillis(post, "create_at"))
+		}
+	}
+
+	return decision, nil
+}
+
+// waitForApproval checks the given post for a decision every poll interval, until a decision
+// is taken or ctx is done. It returns nil if no decision has been taken.
+func waitForApproval(ctx context.Context, postID string, approverIDs map[string]bool,
+	pollInterval time.Duration, opts config.Options) *approvalDecision {
+
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		decision, err := getApprovalDecision(postID, approverIDs, opts)
+		if err != nil {
+			// Keep waiting: the next check may succeed.
+			fmt.Fprintln(os.Stderr, "Error:", err)
+		} else if decision != nil {
+			return decision
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// getDecisionReport returns the level and the text of the field added to the request post
+// once the decision has been taken, the timeout has expired, or the wait has been interrupted.
+func getDecisionReport(decision *approvalDecision, username string, interrupted bool) (string, string) {
+	switch {
+	case decision == nil && interrupted:
+		return "warning", "The request has been withdrawn"
+	case decision == nil:
+		return "warning", "No decision has been taken in time"
+	}
+	var verb, level = "Denied", "critical"
+	if decision.Approved {
+		verb, level = "Approved", "success"
+	}
+	return level, fmt.Sprintf("%s by @%s on %s", verb, username, decision.Time.Local().Format("2006-01-02 15:04:05"))
+}
+
+// approveCmd represents the approve CLI command.
+var approveCmd = &cobra.Command{
+	Use:   "approve",
+	Short: "Post an approval request and wait for a decision",
+	Long: `Post an approval request to a Mattermost channel or user and wait for one of the
+approvers to approve it, by reacting with :+1: or by replying "approve" in its thread,
+or to deny it, by reacting with :-1: or by replying "deny".
+
+The request post is then updated to show who decided and when, and the command exits with:
+  0  on approval
+  1  on denial
+  2  when no decision has been taken in time
+  3  on error, like an invalid flag or a Mattermost failure
+  4  when the wait has been interrupted (SIGINT or SIGTERM)`,
+	Annotations: map[string]string{exitCodeAnnotation: strconv.Itoa(exitCodeApprovalError)},
+	Example: `  approve -c rybfbdi9ojy8xxxjjxc88kh3me -A CI -t "Deploy to production" -m "Release v1.4.2" --approver alice --approver-group ops
+  approve -c @alice -A CI -t "Drop the staging database" -m "Confirm?" --approver alice --approval-timeout 10m`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var opts = newOptions()
+		opts.HTTPClient = mattermost.NewHTTPClient(opts)
+
+		if len(approvers) == 0 && len(approverGroups) == 0 {
+			return fmt.Errorf("at least one approver or approver group must be set")
+		}
+		if approvalPollInterval <= 0 {
+			return fmt.Errorf("the poll interval must be positive")
+		}
+
+		approverIDs, err := getApproverIDs(approvers, approverGroups, opts)
+		if err != nil {
+			return err
+		}
+
+		var approverNames []string
+		for _, username := range approvers {
+			approverNames = append(approverNames, "@"+strings.TrimPrefix(username, "@"))
+		}
+		for _, group := range approverGroups {
+			approverNames = append(approverNames, "@"+strings.TrimPrefix(group, "@")+" (group)")
+		}
+		fields := []mattermost.MsgField{
+			{Title: "Approvers", Value: strings.Join(approverNames, ", "), Short: true},
+			{Title: "Expires", Value: time.Now().Add(approvalTimeout).Format("2006-01-02 15:04:05"), Short: true},
+		}
+
+		msg := message{
+			Author: messageAuthor,
+			Level:  "info",
+			Text: strings.TrimSpace(messageContent +
+				"\n\nReact with :+1: or reply `approve` to approve, react with :-1: or reply `deny` to deny."),
+			Title:   messageTitle,
+			Options: []mattermost.MsgOption{mattermost.WithFields(fields)},
+		}
+
+		posts, err := postMessage(mattermostChannel, msg, false, opts)
+		if err != nil {
+			return err
+		}
+		postID, err := getKV(posts[0].Response, "id")
+		if err != nil {
+			return err
+		}
+		channelID, _ := getKV(posts[0].Response, "channel_id")
+
+		signalCtx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		ctx, cancel := context.WithTimeout(signalCtx, approvalTimeout)
+		defer cancel()
+
+		decision := waitForApproval(ctx, postID, approverIDs, approvalPollInterval, opts)
+
+		var username string
+		if decision != nil {
+			response, err := mattermostGet("/users/"+decision.UserID, opts)
+			if err == nil {
+				username, err = getKV(response, "username")
+			}
+			if err != nil {
+				username = decision.UserID
+			}
+		}
+
+		interrupted := signalCtx.Err() != nil
+		level, report := getDecisionReport(decision, username, interrupted)
+		payload, err := mattermost.CreateMsgPayload(
+			getAttachmentColor(level), channelID, messageAuthor, messageContent, messageTitle,
+			mattermost.WithFields(append(fields[:1:1], mattermost.MsgField{Title: "Decision", Value: report, Short: true})))
+		if err != nil {
+			return err
+		}
+		if _, err := mattermostPut("/posts/"+postID+"/patch", bytes.NewReader(payload), opts); err != nil {
+			fmt.Fprintln(os.Stderr, "Error: cannot update the request post:", err)
+		}
+
+		if !viper.GetBool("quiet") {
+			fmt.Println(report)
+		}
+
+		switch {
+		case decision == nil && interrupted:
+			return exitWithCode(cmd, exitCodeApprovalInterrupted)
+		case decision == nil:
+			return exitWithCode(cmd, exitCodeApprovalTimeout)
+		case !decision.Approved:
+			return exitWithCode(cmd, exitCodeDenied)
+		}
+		return nil
+	},
+}
+
+// init initializes the approve command flags.
+func init() {
+	rootCmd.AddCommand(approveCmd)
+
+	approveCmd.Flags().DurationVar(&approvalTimeout,
+		"approval-timeout", time.Hour, "the maximum time to wait for a decision")
+	approveCmd.Flags().StringArrayVar(&approvers,
+		"approver", nil, "username of a user allowed to approve or deny the request (can be repeated)")
+	approveCmd.Flags().StringArrayVar(&approverGroups,
+		"approver-group", nil, "name of a group whose members can approve or deny the request (can be repeated)")
+	approveCmd.Flags().StringVarP(&messageAuthor,
+		"author", "A", "", "author of the message")
+	approveCmd.Flags().StringVarP(&mattermostChannel,
+		"channel", "c", "", "Mattermost channel ID or username. Example: rybfbdi9ojy8xxxjjxc88kh3me or @alice")
+	approveCmd.Flags().BoolVarP(&mattermostSkipTLSVerify,
+		"insecure", "i", false, "ignore SSL/TLS certificate check")
+	approveCmd.Flags().StringVarP(&messageContent,
+		"message", "m", "", "the (markdown-formatted) description of the request")
+	approveCmd.Flags().DurationVar(&approvalPollInterval,
+		"poll-interval", 5*time.Second, "the time between two checks of the reactions and replies")
+	approveCmd.Flags().DurationVarP(&mattermostConnectionTimeout,
+		"timeout", "s", 10*time.Second, "the maximum time in seconds allowed for a Mattermost connection")
+	approveCmd.Flags().StringVarP(&messageTitle,
+		"title", "t", "", "the title of the request")
+
+	var requiredFlags = [...]string{
+		"author",
+		"channel",
+		"title",
+	}
+
+	for _, requiredFlag := range requiredFlags {
+		err := approveCmd.MarkFlagRequired(requiredFlag)
+		checkErr(err)
+	}
+}
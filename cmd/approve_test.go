@@ -0,0 +1,144 @@
+/*
+  Copyright 2026 Davide Madrisan <d.madrisan@proton.me>
+
+  Licensed under the Mozilla Public License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+      https://www.mozilla.org/en-US/MPL/2.0/
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+*/
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/go-test/deep"
+
+	"github.com/madrisan/go-mattermost-notify/config"
+)
+
+// mockApprovalServer returns a mock of mattermostGet serving the given reactions and thread.
+func mockApprovalServer(reactions, thread string) func(string, config.Options) (interface{}, error) {
+	return func(endpoint string, opts config.Options) (interface{}, error) {
+		var body string
+		switch endpoint {
+		case "/posts/post1/reactions":
+			body = reactions
+		case "/posts/post1/thread":
+			body = thread
+		case "/users/username/alice":
+			body = `{"id": "alice-id"}`
+		case "/groups?q=ops&per_page=200":
+			body = `[{"id": "opsers-id", "name": "opsers"}, {"id": "ops-id", "name": "ops"}]`
+		case "/users?in_group=ops-id&page=0&per_page=200":
+			body = `[{"id": "bob-id"}, {"id": "carol-id"}]`
+		default:
+			return nil, fmt.Errorf("unexpected endpoint %s", endpoint)
+		}
+		var data interface{}
+		err := json.Unmarshal([]byte(body), &data)
+		return data, err
+	}
+}
+
+func TestGetApproverIDs(t *testing.T) {
+	oldMattermostGet := mattermostGet
+	defer func() { mattermostGet = oldMattermostGet }()
+	mattermostGet = mockApprovalServer("", "")
+
+	ids, err := getApproverIDs([]string{"@alice"}, []string{"ops"}, config.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := deep.Equal(ids, map[string]bool{"alice-id": true, "bob-id": true, "carol-id": true}); diff != nil {
+		t.Error(diff)
+	}
+
+	if _, err := getApproverIDs(nil, []string{"devs"}, config.Options{}); err == nil {
+		t.Error("an unknown group should be reported")
+	}
+}
+
+func TestGetApprovalDecision(t *testing.T) {
+	oldMattermostGet := mattermostGet
+	defer func() { mattermostGet = oldMattermostGet }()
+
+	approverIDs := map[string]bool{"alice-id": true, "bob-id": true}
+	emptyThread := `{"order": ["post1"], "posts": {"post1": {"id": "post1", "message": "Deploy?"}}}`
+
+	cases := []struct {
+		name      string
+		reactions string
+		thread    string
+		shouldBe  *approvalDecision
+	}{
+		{
+			name:      "no decision",
+			reactions: `null`,
+			thread:    emptyThread,
+		},
+		{
+			name:      "not an approver",
+			reactions: `[{"user_id": "eve-id", "emoji_name": "+1", "create_at": 1000}]`,
+			thread:    emptyThread,
+		},
+		{
+			name:      "approved by reaction",
+			reactions: `[{"user_id": "eve-id", "emoji_name": "-1", "create_at": 1000}, {"user_id": "alice-id", "emoji_name": "+1", "create_at": 2000}]`,
+			thread:    emptyThread,
+			shouldBe:  &approvalDecision{Approved: true, UserID: "alice-id", Time: time.UnixMilli(2000)},
+		},
+		{
+			name:      "first decision wins",
+			reactions: `[{"user_id": "alice-id", "emoji_name": "thumbsup", "create_at": 3000}]`,
+			thread: `{"posts": {
+				"post1": {"id": "post1", "message": "Deploy?"},
+				"post2": {"id": "post2", "root_id": "post1", "user_id": "bob-id", "message": " Deny! ", "create_at": 2000},
+				"post3": {"id": "post3", "root_id": "post1", "user_id": "bob-id", "message": "Why?", "create_at": 1000}}}`,
+			shouldBe: &approvalDecision{Approved: false, UserID: "bob-id", Time: time.UnixMilli(2000)},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			mattermostGet = mockApprovalServer(tc.reactions, tc.thread)
+			decision, err := getApprovalDecision("post1", approverIDs, config.Options{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := deep.Equal(decision, tc.shouldBe); diff != nil {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func TestWaitForApprovalTimeout(t *testing.T) {
+	oldMattermostGet := mattermostGet
+	defer func() { mattermostGet = oldMattermostGet }()
+	mattermostGet = mockApprovalServer(`[]`, `{}`)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	if decision := waitForApproval(ctx, "post1", map[string]bool{"alice-id": true}, 10*time.Millisecond, config.Options{}); decision != nil {
+		t.Error("no decision expected, got", decision)
+	}
+	if level, text := getDecisionReport(nil, "", false); level != "warning" || text != "No decision has been taken in time" {
+		t.Error("unexpected timeout report", level, text)
+	}
+	if level, text := getDecisionReport(nil, "", true); level != "warning" || text != "The request has been withdrawn" {
+		t.Error("unexpected interruption report", level, text)
+	}
+}
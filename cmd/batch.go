@@ -0,0 +1,363 @@
+/*
+  Copyright 2026 Davide Madrisan <d.madrisan@proton.me>
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+      http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+*/
+
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	mattermost "github.com/madrisan/go-mattermost-notify/mattermost"
+	"github.com/spf13/viper"
+
+	"github.com/madrisan/go-mattermost-notify/config"
+)
+
+var (
+	// batchFile is the JSON Lines file (or - for the standard input) containing the messages to be posted.
+	batchFile string
+	// batchWorkers is the number of messages posted concurrently in batch mode.
+	batchWorkers int
+)
+
+const (
+	// batchMaxRetries is the number of times a post rejected by the Mattermost rate limiter is retried.
+	batchMaxRetries = 5
+	// batchMaxRetryDelay caps the time to wait before retrying a post rejected by the rate limiter.
+	batchMaxRetryDelay = time.Minute
+	// maxBatchLineSize is the maximum size of a line of the batch file.
+	maxBatchLineSize = 1024 * 1024
+)
+
+// batchRecord is a line of the batch file. The empty properties default to the command-line flags.
+type batchRecord struct {
+	Channel     string                `json:"channel"`
+	Author      string                `json:"author"`
+	Title       string                `json:"title"`
+	Message     string                `json:"message"`
+	Level       string                `json:"level"`
+	Labels      map[string]string     `json:"labels"`
+	Fields      []mattermost.MsgField `json:"fields"`
+	ThreadKey   string                `json:"thread_key"`
+	CloseThread bool                  `json:"close_thread"`
+}
+
+// batchResult is the outcome of the delivery of a batch record to one of its destinations.
+type batchResult struct {
+	Line        int    `json:"line"`
+	Destination string `json:"destination,omitempty"`
+	PostID      string `json:"post_id,omitempty"`
+	Error       string `json:"error,omitempty"`
+}
+
+// batchJob is a batch record waiting to be posted.
+type batchJob struct {
+	line   int
+	record batchRecord
+	err    error
+}
+
+// rateLimiter delays the queries when the Mattermost rate limit has been reached.
+type rateLimiter struct {
+	mu    sync.Mutex
+	until time.Time
+}
+
+// wait blocks until the queries are allowed again.
+func (l *rateLimiter) wait() {
+	l.mu.Lock()
+	delay := time.Until(l.until)
+	l.mu.Unlock()
+	if delay > 0 {
+		time.Sleep(delay)
+	}
+}
+
+// pause suspends the queries for the given delay.
+func (l *rateLimiter) pause(delay time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if until := time.Now().Add(delay); until.After(l.until) {
+		l.until = until
+	}
+}
+
+// update suspends the queries until the reset of the rate limit when no request is remaining.
+func (l *rateLimiter) update(header http.Header) {
+	if header.Get("X-Ratelimit-Remaining") == "0" {
+		l.pause(getRetryDelay(header))
+	}
+}
+
+// getRetryDelay returns the time to wait before the next query, read from the Retry-After
+// or X-Ratelimit-Reset header (in seconds), one second if none is set.
+func getRetryDelay(header http.Header) time.Duration {
+	for _, name := range []string{"Retry-After", "X-Ratelimit-Reset"} {
+		if seconds, err := strconv.Atoi(header.Get(name)); err == nil && seconds >= 0 {
+			return min(time.Duration(seconds)*time.Second, batchMaxRetryDelay)
+		}
+	}
+	return time.Second
+}
+
+// channelEntry is a destination resolved by a batchPoster. Its ID is empty until it has been resolved.
+type channelEntry struct {
+	mu sync.Mutex
+	id string
+}
+
+// batchPoster is the poster used in batch mode: it resolves each destination once,
+// and respects the Mattermost rate limit.
+type batchPoster struct {
+	opts     config.Options
+	limiter  rateLimiter
+	mu       sync.Mutex
+	channels map[string]*channelEntry
+}
+
+// channelID returns the Mattermost ID of the given channel, resolved at the first successful call.
+// The failures are not cached, so that a transient error does not fail the following records.
+func (p *batchPoster) channelID(destination string) (string, error) {
+	p.mu.Lock()
+	if p.channels == nil {
+		p.channels = make(map[string]*channelEntry)
+	}
+	entry, found := p.channels[destination]
+	if !found {
+		entry = &channelEntry{}
+		p.channels[destination] = entry
+	}
+	p.mu.Unlock()
+
+	entry.mu.Lock()
+	defer entry.mu.Unlock()
+	if entry.id == "" {
+		p.limiter.wait()
+		id, err := getChannelID(destination, p.opts)
+		if err != nil {
+			return "", err
+		}
+		entry.id = id
+	}
+	return entry.id, nil
+}
+
+// post sends the post payload to Mattermost, retrying when it is rejected by the rate limiter.
+func (p *batchPoster) post(payload []byte) (interface{}, error) {
+	for attempt := 0; ; attempt++ {
+		p.limiter.wait()
+		response, err := mattermostDo(http.MethodPost, "/posts", bytes.NewReader(payload), p.opts)
+
+		var apiErr *mattermost.APIError
+		if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusTooManyRequests && attempt < batchMaxRetries {
+			p.limiter.pause(getRetryDelay(apiErr.Header))
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		p.limiter.update(response.Header)
+		return response.Data, nil
+	}
+}
+
+// readBatch sends the records of the batch file to the jobs channel, which is closed at the end of the file.
+func readBatch(r io.Reader, jobs chan<- batchJob) error {
+	defer close(jobs)
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxBatchLineSize)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		var job = batchJob{line: line}
+		if err := json.Unmarshal([]byte(text), &job.record); err != nil {
+			job.err = fmt.Errorf("invalid JSON record: %v", err)
+		}
+		jobs <- job
+	}
+
+	return scanner.Err()
+}
+
+// getBatchMessage returns the message of a batch record, the command-line flags being the defaults.
+func getBatchMessage(r batchRecord, threads threadStore) (string, message, error) {
+	var channel = r.Channel
+	if channel == "" {
+		channel = mattermostChannel
+	}
+	msg := message{
+		Author:      r.Author,
+		Labels:      normalizeLabels(r.Labels),
+		Level:       r.Level,
+		Text:        r.Message,
+		Title:       r.Title,
+		Threads:     threads,
+		ThreadKey:   r.ThreadKey,
+		CloseThread: r.CloseThread,
+	}
+	if msg.Author == "" {
+		msg.Author = messageAuthor
+	}
+	if msg.Level == "" {
+		msg.Level = messageLevel
+	}
+	if len(r.Fields) > 0 {
+		msg.Options = append(msg.Options, mattermost.WithFields(r.Fields))
+	}
+
+	if msg.Author == "" || msg.Title == "" || msg.Text == "" {
+		return "", msg, fmt.Errorf("the record must have an author, a title and a message")
+	}
+	return channel, msg, nil
+}
+
+// getBatchShard returns the worker a batch job is sent to. The records with the same thread key
+// are posted in order by the same worker, so the first one creates the thread.
+func getBatchShard(job batchJob, workers int) int {
+	if job.record.ThreadKey == "" {
+		return job.line % workers
+	}
+	h := fnv.New32a()
+	h.Write([]byte(job.record.ThreadKey))
+	return int(h.Sum32() % uint32(workers))
+}
+
+// postBatch posts the messages read from the batch file with the given number of concurrent
+// workers, and writes a JSON result line per record and destination. It returns the number of
+// failed records.
+func postBatch(r io.Reader, w io.Writer, workers int, spool, quiet bool, opts config.Options) (int, error) {
+	stateFile, err := getStateFile("batch")
+	if err != nil {
+		return 0, err
+	}
+	threads := &stateThreads{file: stateFile}
+	p := &batchPoster{opts: opts}
+
+	if workers <= 0 {
+		workers = 1
+	}
+
+	var (
+		mu       sync.Mutex
+		failures int
+		encoder  = json.NewEncoder(w)
+		wg       sync.WaitGroup
+	)
+	report := func(results ...batchResult) {
+		mu.Lock()
+		defer mu.Unlock()
+		for _, result := range results {
+			if result.Error != "" {
+				failures++
+			} else if quiet {
+				continue
+			}
+			encoder.Encode(result)
+		}
+	}
+
+	var queues = make([]chan batchJob, workers)
+	for i := range queues {
+		queues[i] = make(chan batchJob)
+		wg.Add(1)
+		go func(queue <-chan batchJob) {
+			defer wg.Done()
+			for job := range queue {
+				report(postBatchJob(job, threads, spool, p)...)
+			}
+		}(queues[i])
+	}
+
+	var jobs = make(chan batchJob)
+	var readErr = make(chan error, 1)
+	go func() { readErr <- readBatch(r, jobs) }()
+
+	for job := range jobs {
+		queues[getBatchShard(job, workers)] <- job
+	}
+	for _, queue := range queues {
+		close(queue)
+	}
+	wg.Wait()
+
+	if err := <-readErr; err != nil {
+		return failures, fmt.Errorf("cannot read the batch file: %v", err)
+	}
+	return failures, nil
+}
+
+// postBatchJob posts a batch record and returns the results of its delivery.
+func postBatchJob(job batchJob, threads threadStore, spool bool, p poster) []batchResult {
+	failed := func(err error) []batchResult {
+		return []batchResult{{Line: job.line, Error: err.Error()}}
+	}
+	if job.err != nil {
+		return failed(job.err)
+	}
+
+	channel, msg, err := getBatchMessage(job.record, threads)
+	if err != nil {
+		return failed(err)
+	}
+	posts, err := sendMessage(channel, msg, spool, p)
+
+	var results []batchResult
+	for _, post := range posts {
+		postID, _ := getKV(post.Response, "id")
+		results = append(results, batchResult{Line: job.line, Destination: post.Destination, PostID: postID})
+	}
+	if err != nil {
+		results = append(results, failed(err)...)
+	}
+	return results
+}
+
+// runBatch posts the messages of the batch file (or the standard input for -).
+func runBatch(file string, opts config.Options) error {
+	var r io.Reader = os.Stdin
+	if file != "-" {
+		f, err := os.Open(file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	failures, err := postBatch(r, os.Stdout, batchWorkers, spoolOnFailure, viper.GetBool("quiet"), opts)
+	if err != nil {
+		return err
+	}
+	if failures > 0 {
+		return fmt.Errorf("%d message(s) could not be posted", failures)
+	}
+	return nil
+}
@@ -0,0 +1,211 @@
+/*
+  Copyright 2026 Davide Madrisan <d.madrisan@proton.me>
+
+  Licensed under the Mozilla Public License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+      https://www.mozilla.org/en-US/MPL/2.0/
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-test/deep"
+	"github.com/madrisan/go-mattermost-notify/config"
+	mattermost "github.com/madrisan/go-mattermost-notify/mattermost"
+	"github.com/madrisan/go-mattermost-notify/mattermost/mattermosttest"
+	"github.com/spf13/viper"
+)
+
+func TestGetRetryDelay(t *testing.T) {
+	var testCases = []struct {
+		header   map[string]string
+		shouldBe time.Duration
+	}{
+		{map[string]string{"Retry-After": "3"}, 3 * time.Second},
+		{map[string]string{"X-Ratelimit-Reset": "2"}, 2 * time.Second},
+		{map[string]string{"Retry-After": "3600"}, batchMaxRetryDelay},
+		{map[string]string{}, time.Second},
+	}
+
+	for _, tc := range testCases {
+		header := http.Header{}
+		for k, v := range tc.header {
+			header.Set(k, v)
+		}
+		if v := getRetryDelay(header); v != tc.shouldBe {
+			t.Errorf("%v: expected %v, got %v", tc.header, tc.shouldBe, v)
+		}
+	}
+}
+
+func TestBatchPosterRateLimit(t *testing.T) {
+	defer func() { mattermostDo = mattermost.Do }()
+
+	var calls int
+	mattermostDo = func(method, endpoint string, payload io.Reader, opts config.Options) (*mattermost.Response, error) {
+		calls++
+		if calls <= 2 {
+			return nil, &mattermost.APIError{
+				StatusCode: http.StatusTooManyRequests,
+				Header:     http.Header{"Retry-After": []string{"0"}},
+			}
+		}
+		return &mattermost.Response{
+			StatusCode: http.StatusCreated,
+			Header:     http.Header{},
+			Data:       map[string]interface{}{"id": "post1"},
+		}, nil
+	}
+
+	p := &batchPoster{}
+	response, err := p.post([]byte("{}"))
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	if id, _ := getKV(response, "id"); id != "post1" || calls != 3 {
+		t.Errorf("unexpected response %v after %d calls", response, calls)
+	}
+}
+
+func TestBatchPosterChannelID(t *testing.T) {
+	srv := mattermosttest.NewServer()
+	defer srv.Close()
+	srv.AddUser("alice")
+
+	defer saveSettings("url", "access-token")()
+	viper.Set("url", srv.URL)
+	viper.Set("access-token", srv.Token)
+
+	p := &batchPoster{}
+	srv.SetError(http.MethodGet, "/api/v4/users/username/alice", http.StatusServiceUnavailable)
+	if _, err := p.channelID("@alice"); err == nil {
+		t.Fatal("the resolution of @alice should fail")
+	}
+	srv.SetError(http.MethodGet, "/api/v4/users/username/alice", 0)
+	id, err := p.channelID("@alice")
+	if err != nil || id == "" {
+		t.Fatalf("a failed resolution should not be cached, got %q (%v)", id, err)
+	}
+
+	lookups := len(srv.Requests())
+	if again, err := p.channelID("@alice"); err != nil || again != id || len(srv.Requests()) != lookups {
+		t.Errorf("a successful resolution should be cached, got %q (%v)", again, err)
+	}
+}
+
+func TestGetBatchMessageLabels(t *testing.T) {
+	_, msg, err := getBatchMessage(batchRecord{
+		Author:  "CI",
+		Title:   "Build",
+		Message: "failed",
+		Labels:  map[string]string{"Service": "db"},
+	}, nil)
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	if diff := deep.Equal(msg.Labels, map[string]string{"service": "db"}); diff != nil {
+		t.Error(diff)
+	}
+}
+
+func TestPostBatch(t *testing.T) {
+	srv := mattermosttest.NewServer()
+	defer srv.Close()
+	srv.AddUser("alice")
+	channel := srv.AddChannel("ci")
+
+	oldURL, oldToken, oldStateDir := viper.Get("url"), viper.Get("access-token"), viper.Get("state.dir")
+	defer func() {
+		viper.Set("url", oldURL)
+		viper.Set("access-token", oldToken)
+		viper.Set("state.dir", oldStateDir)
+	}()
+	viper.Set("url", srv.URL)
+	viper.Set("access-token", srv.Token)
+	viper.Set("state.dir", t.TempDir())
+
+	oldAuthor := messageAuthor
+	defer func() { messageAuthor = oldAuthor }()
+	messageAuthor = "CI"
+
+	input := strings.Join([]string{
+		`{"channel": "@alice", "title": "Suite A", "message": "failed", "level": "critical"}`,
+		`{"channel": "@alice", "title": "Suite B", "message": "failed", "fields": [{"title": "Tests", "value": "3"}]}`,
+		``,
+		`{"channel": "` + channel.ID + `", "title": "Build", "message": "started", "thread_key": "build-42"}`,
+		`not a JSON record`,
+		`{"channel": "` + channel.ID + `", "title": "Build", "message": "done", "thread_key": "build-42"}`,
+		`{"channel": "@alice", "message": "no title"}`,
+	}, "\n")
+
+	var out bytes.Buffer
+	failures, err := postBatch(strings.NewReader(input), &out, 3, false, false, config.Options{})
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	if failures != 2 {
+		t.Errorf("expected 2 failures, got %d", failures)
+	}
+
+	var results []batchResult
+	decoder := json.NewDecoder(&out)
+	for decoder.More() {
+		var result batchResult
+		if err := decoder.Decode(&result); err != nil {
+			t.Fatal(err)
+		}
+		results = append(results, result)
+	}
+	sort.Slice(results, func(i, j int) bool { return results[i].Line < results[j].Line })
+
+	var lines []int
+	for _, result := range results {
+		lines = append(lines, result.Line)
+		if (result.Error == "") == (result.PostID == "") {
+			t.Errorf("unexpected result %+v", result)
+		}
+	}
+	if v := fmt.Sprint(lines); v != "[1 2 4 5 6 7]" {
+		t.Errorf("unexpected results %+v", results)
+	}
+
+	var lookups int
+	for _, r := range srv.Requests() {
+		if r.Path == "/api/v4/users/username/alice" {
+			lookups++
+		}
+	}
+	if lookups != 1 {
+		t.Errorf("@alice should be resolved once, got %d lookups", lookups)
+	}
+
+	var rootID string
+	for _, post := range srv.Posts() {
+		if post.ChannelID != channel.ID {
+			continue
+		}
+		if rootID == "" {
+			rootID = post.ID
+		} else if post.RootID != rootID {
+			t.Errorf("the second build post should reply to %s, got %+v", rootID, post)
+		}
+	}
+}
@@ -0,0 +1,223 @@
+/*
+  Copyright 2026 Davide Madrisan <d.madrisan@proton.me>
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+      http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/madrisan/go-mattermost-notify/config"
+)
+
+var (
+	// botDisplayName is the display name of the bot to create.
+	botDisplayName string
+	// botDescription is the description of the bot to create.
+	botDescription string
+	// botListAll tells if the disabled bots must be listed too.
+	botListAll bool
+)
+
+// idRegexp matches the Mattermost IDs.
+var idRegexp = regexp.MustCompile(`^[a-z0-9]{26}$`)
+
+// resolveUserID returns the ID of the given user, which can be a Mattermost ID or a username,
+// with or without the leading '@'. The ID of the logged user is returned if user is empty.
+func resolveUserID(user string, opts config.Options) (string, error) {
+	switch {
+	case user == "":
+		return getLoggedUserID(opts)
+	case idRegexp.MatchString(user):
+		return user, nil
+	}
+	return getUserID(strings.TrimPrefix(user, "@"), opts)
+}
+
+// formatMillis returns the given Mattermost timestamp in the local time, or "-" if zero.
+func formatMillis(ms float64) string {
+	if ms == 0 {
+		return "-"
+	}
+	return time.UnixMilli(int64(ms)).Local().Format("2006-01-02 15:04")
+}
+
+// listBots writes the bots of Mattermost in a table, including the disabled ones if all is set.
+func listBots(w io.Writer, all bool, opts config.Options) error {
+	var owners = make(map[string]string)
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "USERNAME\tUSER ID\tDISPLAY NAME\tOWNER\tCREATED\tSTATUS")
+
+	for page := 0; ; page++ {
+		endpoint := fmt.Sprintf("/bots?include_deleted=%t&page=%d&per_page=%d", all, page, usersPerPage)
+		response, err := mattermostGet(endpoint, opts)
+		if err != nil {
+			return err
+		}
+		bots, err := getList(response)
+		if err != nil {
+			return err
+		}
+
+		for _, b := range bots {
+			bot, _ := b.(map[string]interface{})
+			username, _ := getKV(bot, "username")
+			userID, _ := getKV(bot, "user_id")
+			displayName, _ := getKV(bot, "display_name")
+			ownerID, _ := getKV(bot, "owner_id")
+			createAt, _ := bot["create_at"].(float64)
+			deleteAt, _ := bot["delete_at"].(float64)
+
+			// The owner is usually the same user, whose name is looked up once.
+			owner, found := owners[ownerID]
+			if !found {
+				owner = ownerID
+				if response, err := mattermostGet("/users/"+ownerID, opts); err == nil {
+					if name, err := getKV(response, "username"); err == nil {
+						owner = "@" + name
+					}
+				}
+				owners[ownerID] = owner
+			}
+
+			status := "active"
+			if deleteAt != 0 {
+				status = "disabled since " + formatMillis(deleteAt)
+			}
+			fmt.Fprintf(tw, "@%s\t%s\t%s\t%s\t%s\t%s\n",
+				username, userID, displayName, owner, formatMillis(createAt), status)
+		}
+		if len(bots) < usersPerPage {
+			break
+		}
+	}
+	return tw.Flush()
+}
+
+// botCmd represents the bot CLI command.
+var botCmd = &cobra.Command{
+	Use:   "bot",
+	Short: "Manage the Mattermost bot accounts",
+	Long: `Create, list and disable the Mattermost bot accounts.
+
+The bot accounts must be enabled in Mattermost, and the logged user must be allowed
+to manage them. The access tokens of the bots are managed by the token command.`,
+}
+
+// botCreateCmd represents the bot create CLI command.
+var botCreateCmd = &cobra.Command{
+	Use:   "create USERNAME",
+	Short: "Create a bot account",
+	Example: `  bot create ci-pipeline --display-name "CI Pipeline" --description "Posts the CI results"
+  bot create ci-pipeline && token create --user ci-pipeline --description pipeline --to-profile ci`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var opts = newOptions()
+
+		payload, err := json.Marshal(map[string]string{
+			"username":     strings.TrimPrefix(args[0], "@"),
+			"display_name": botDisplayName,
+			"description":  botDescription,
+		})
+		if err != nil {
+			return err
+		}
+		response, err := mattermostPost("/bots", bytes.NewReader(payload), opts)
+		if err != nil {
+			return fmt.Errorf("cannot create the bot %s: %v", args[0], err)
+		}
+
+		userID, err := getKV(response, "user_id")
+		if err != nil {
+			return err
+		}
+		if !viper.GetBool("quiet") {
+			fmt.Fprintf(cmd.OutOrStdout(), "Bot @%s created with the user ID %s\n", strings.TrimPrefix(args[0], "@"), userID)
+		}
+		return nil
+	},
+}
+
+// botListCmd represents the bot list CLI command.
+var botListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the bot accounts",
+	Example: `  bot list
+  bot list --all`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var opts = newOptions()
+
+		return listBots(cmd.OutOrStdout(), botListAll, opts)
+	},
+}
+
+// botDisableCmd represents the bot disable CLI command.
+var botDisableCmd = &cobra.Command{
+	Use:   "disable BOT...",
+	Short: "Disable bot accounts",
+	Long: `Disable the given bot accounts, identified by username or user ID.
+The access tokens of a disabled bot stop working.`,
+	Example: `  bot disable ci-pipeline
+  bot disable @ci-old 7trmbhd8xg9tmiagqfx1fzhhjo`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var opts = newOptions()
+
+		for _, bot := range args {
+			userID, err := resolveUserID(bot, opts)
+			if err != nil {
+				return fmt.Errorf("cannot find the bot %s: %v", bot, err)
+			}
+			if _, err := mattermostPost("/bots/"+userID+"/disable", nil, opts); err != nil {
+				return fmt.Errorf("cannot disable the bot %s: %v", bot, err)
+			}
+			if !viper.GetBool("quiet") {
+				fmt.Fprintf(cmd.OutOrStdout(), "Bot %s disabled\n", bot)
+			}
+		}
+		return nil
+	},
+}
+
+// init initializes the bot command flags.
+func init() {
+	rootCmd.AddCommand(botCmd)
+	botCmd.AddCommand(botCreateCmd)
+	botCmd.AddCommand(botListCmd)
+	botCmd.AddCommand(botDisableCmd)
+
+	botCmd.PersistentFlags().BoolVarP(&mattermostSkipTLSVerify,
+		"insecure", "i", false, "ignore SSL/TLS certificate check")
+	botCmd.PersistentFlags().DurationVarP(&mattermostConnectionTimeout,
+		"timeout", "s", 10*time.Second, "the maximum time in seconds allowed for a Mattermost connection")
+
+	botCreateCmd.Flags().StringVar(&botDescription,
+		"description", "", "the description of the bot")
+	botCreateCmd.Flags().StringVar(&botDisplayName,
+		"display-name", "", "the display name of the bot")
+	botListCmd.Flags().BoolVar(&botListAll,
+		"all", false, "also list the disabled bots")
+}
@@ -0,0 +1,109 @@
+/*
+  Copyright 2026 Davide Madrisan <d.madrisan@proton.me>
+
+  Licensed under the Mozilla Public License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+      https://www.mozilla.org/en-US/MPL/2.0/
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/madrisan/go-mattermost-notify/config"
+	"github.com/madrisan/go-mattermost-notify/mattermost/mattermosttest"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func TestResolveUserID(t *testing.T) {
+	srv := mattermosttest.NewServer()
+	defer srv.Close()
+	alice := srv.AddUser("alice")
+
+	defer saveSettings("url", "access-token")()
+	viper.Set("url", srv.URL)
+	viper.Set("access-token", srv.Token)
+
+	var testCases = []struct {
+		user     string
+		shouldBe string
+	}{
+		{"", srv.Me().ID},
+		{"alice", alice.ID},
+		{"@alice", alice.ID},
+		{alice.ID, alice.ID},
+	}
+
+	for _, tc := range testCases {
+		if id, err := resolveUserID(tc.user, config.Options{}); err != nil || id != tc.shouldBe {
+			t.Errorf("%q: expected %s, got %s (%v)", tc.user, tc.shouldBe, id, err)
+		}
+	}
+	if _, err := resolveUserID("@nobody", config.Options{}); err == nil {
+		t.Error("an unknown user should not be resolved")
+	}
+}
+
+func TestBotCommands(t *testing.T) {
+	srv := mattermosttest.NewServer()
+	defer srv.Close()
+
+	defer saveSettings("url", "access-token", "quiet")()
+	viper.Set("url", srv.URL)
+	viper.Set("access-token", srv.Token)
+	viper.Set("quiet", false)
+
+	var out bytes.Buffer
+	for _, c := range []*cobra.Command{botCreateCmd, botListCmd, botDisableCmd} {
+		c.SetOut(&out)
+		defer c.SetOut(nil)
+	}
+
+	oldDisplayName, oldAll := botDisplayName, botListAll
+	defer func() { botDisplayName, botListAll = oldDisplayName, oldAll }()
+
+	for _, name := range []string{"ci-pipeline", "backup"} {
+		botDisplayName = strings.ToUpper(name)
+		if err := botCreateCmd.RunE(botCreateCmd, []string{name}); err != nil {
+			t.Fatal("cannot create the bot:", err)
+		}
+	}
+	if err := botCreateCmd.RunE(botCreateCmd, []string{"backup"}); err == nil {
+		t.Error("a bot with an existing username should not be created")
+	}
+	if err := botDisableCmd.RunE(botDisableCmd, []string{"@backup"}); err != nil {
+		t.Fatal("cannot disable the bot:", err)
+	}
+
+	out.Reset()
+	botListAll = false
+	if err := botListCmd.RunE(botListCmd, nil); err != nil {
+		t.Fatal("cannot list the bots:", err)
+	}
+	if lines := strings.Split(strings.TrimSpace(out.String()), "\n"); len(lines) != 2 ||
+		!strings.Contains(lines[1], "@ci-pipeline") || !strings.Contains(lines[1], "CI-PIPELINE") ||
+		!strings.Contains(lines[1], "@bot") || !strings.HasSuffix(lines[1], "active") {
+		t.Errorf("unexpected bot list:\n%s", out.String())
+	}
+
+	out.Reset()
+	botListAll = true
+	if err := botListCmd.RunE(botListCmd, nil); err != nil {
+		t.Fatal("cannot list the bots:", err)
+	}
+	if !strings.Contains(out.String(), "@backup") || !strings.Contains(out.String(), "disabled since") {
+		t.Errorf("the disabled bot should be listed:\n%s", out.String())
+	}
+}
@@ -0,0 +1,534 @@
+/*
+  Copyright 2026 Davide Madrisan <d.madrisan@proton.me>
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+      http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/madrisan/go-mattermost-notify/config"
+)
+
+var (
+	// channelDisplayName is the display name of the channel to create or rename.
+	channelDisplayName string
+	// channelHeader is the header of the channel to create.
+	channelHeader string
+	// channelPrivate tells if the channel to create is private.
+	channelPrivate bool
+	// channelPurpose is the purpose of the channel to create.
+	channelPurpose string
+)
+
+// getTeamID returns the Mattermost ID of the given team, which can be an ID or a team name.
+// When no team is given, the only team of the logged user is returned.
+func getTeamID(team string, opts config.Options) (string, error) {
+	switch {
+	case team == "":
+		response, err := mattermostGet("/users/me/teams", opts)
+		if err != nil {
+			return "", err
+		}
+		teams, err := getList(response)
+		if err != nil {
+			return "", err
+		}
+		var names []string
+		for _, t := range teams {
+			name, _ := getKV(t, "name")
+			names = append(names, name)
+		}
+		switch len(teams) {
+		case 0:
+			return "", fmt.Errorf("the logged user does not belong to any team")
+		case 1:
+			return getKV(teams[0], "id")
+		}
+		return "", fmt.Errorf("the logged user belongs to several teams (%s): please set --team", strings.Join(names, ", "))
+	case idRegexp.MatchString(team):
+		return team, nil
+	}
+
+	response, err := mattermostGet("/teams/name/"+url.PathEscape(team), opts)
+	if err != nil {
+		return "", fmt.Errorf("cannot find the team %s: %v", team, err)
+	}
+	return getKV(response, "id")
+}
+
+// resolveChannelID returns the Mattermost ID of the given channel, which can be an ID, a channel
+// name of the given team (with or without the leading '~'), or a @username for a direct channel.
+// The archived channels are found only when includeArchived is set.
+func resolveChannelID(channel, team string, includeArchived bool, opts config.Options) (string, error) {
+	switch {
+	case strings.HasPrefix(channel, "@"):
+		return getChannelID(channel, opts)
+	case idRegexp.MatchString(channel):
+		return channel, nil
+	}
+
+	teamID, err := getTeamID(team, opts)
+	if err != nil {
+		return "", err
+	}
+	endpoint := fmt.Sprintf("/teams/%s/channels/name/%s?include_deleted=%t",
+		teamID, url.PathEscape(strings.TrimPrefix(channel, "~")), includeArchived)
+	response, err := mattermostGet(endpoint, opts)
+	if err != nil {
+		return "", fmt.Errorf("cannot find the channel %s: %v", channel, err)
+	}
+	return getKV(response, "id")
+}
+
+// teamChannel is a channel of a team, as sent to Mattermost when it's created.
+type teamChannel struct {
+	TeamID      string `json:"team_id"`
+	Name        string `json:"name"`
+	DisplayName string `json:"display_name"`
+	// Type is "O" for the open channels and "P" for the private ones.
+	Type    string `json:"type"`
+	Header  string `json:"header,omitempty"`
+	Purpose string `json:"purpose,omitempty"`
+}
+
+// createTeamChannel creates the given channel and returns its ID.
+// The display name defaults to the channel name.
+func createTeamChannel(channel teamChannel, opts config.Options) (string, error) {
+	if channel.DisplayName == "" {
+		channel.DisplayName = channel.Name
+	}
+	if channel.Type == "" {
+		channel.Type = "O"
+	}
+	payload, err := json.Marshal(channel)
+	if err != nil {
+		return "", err
+	}
+	response, err := mattermostPost("/channels", bytes.NewReader(payload), opts)
+	if err != nil {
+		return "", fmt.Errorf("cannot create the channel %s: %v", channel.Name, err)
+	}
+	return getKV(response, "id")
+}
+
+// patchChannel changes the given properties (name, display_name, header, purpose) of a channel.
+func patchChannel(channelID string, patch map[string]string, opts config.Options) error {
+	payload, err := json.Marshal(patch)
+	if err != nil {
+		return err
+	}
+	_, err = mattermostPut("/channels/"+channelID+"/patch", bytes.NewReader(payload), opts)
+	return err
+}
+
+// addChannelMember adds the user with the given ID to a channel.
+func addChannelMember(channelID, userID string, opts config.Options) error {
+	payload, err := json.Marshal(map[string]string{"user_id": userID})
+	if err != nil {
+		return err
+	}
+	_, err = mattermostPost("/channels/"+channelID+"/members", bytes.NewReader(payload), opts)
+	return err
+}
+
+// removeChannelMember removes the user with the given ID from a channel.
+func removeChannelMember(channelID, userID string, opts config.Options) error {
+	_, err := mattermostDelete("/channels/"+channelID+"/members/"+userID, opts)
+	return err
+}
+
+// getUsernames adds to names the usernames of the users with the given IDs, indexed by user ID.
+// The users are looked up at once, by pages, and the unknown ones are left out.
+func getUsernames(names map[string]string, ids []string, opts config.Options) error {
+	for len(ids) > 0 {
+		page := ids[:min(len(ids), usersPerPage)]
+		ids = ids[len(page):]
+
+		payload, err := json.Marshal(page)
+		if err != nil {
+			return err
+		}
+		response, err := mattermostPost("/users/ids", bytes.NewReader(payload), opts)
+		if err != nil {
+			return err
+		}
+		users, err := getList(response)
+		if err != nil {
+			return err
+		}
+		for _, user := range users {
+			id, _ := getKV(user, "id")
+			if username, err := getKV(user, "username"); err == nil && id != "" {
+				names[id] = username
+			}
+		}
+	}
+	return nil
+}
+
+// getMembers returns the usernames of the members listed by the given endpoint, like
+// /channels/{channel_id}/members or /teams/{team_id}/members, indexed by user ID.
+func getMembers(membersEndpoint string, opts config.Options) (map[string]string, error) {
+	var members = make(map[string]string)
+	for page := 0; ; page++ {
+		endpoint := fmt.Sprintf("%s?page=%d&per_page=%d", membersEndpoint, page, usersPerPage)
+		response, err := mattermostGet(endpoint, opts)
+		if err != nil {
+			return nil, err
+		}
+		list, err := getList(response)
+		if err != nil {
+			return nil, err
+		}
+
+		var ids []string
+		for _, member := range list {
+			if userID, err := getKV(member, "user_id"); err == nil {
+				ids = append(ids, userID)
+				members[userID] = userID
+			}
+		}
+		if err := getUsernames(members, ids, opts); err != nil {
+			return nil, err
+		}
+
+		if len(list) < usersPerPage {
+			return members, nil
+		}
+	}
+}
+
+// listChannelMembers writes the members of a channel in a table, sorted by username.
+func listChannelMembers(w io.Writer, channelID string, opts config.Options) error {
+	members, err := getMembers("/channels/"+channelID+"/members", opts)
+	if err != nil {
+		return err
+	}
+
+	var rows []string
+	for id, username := range members {
+		rows = append(rows, fmt.Sprintf("@%s\t%s", username, id))
+	}
+	sort.Strings(rows)
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "USERNAME\tUSER ID")
+	for _, row := range rows {
+		fmt.Fprintln(tw, row)
+	}
+	return tw.Flush()
+}
+
+// channelCmd represents the channel CLI command.
+var channelCmd = &cobra.Command{
+	Use:   "channel",
+	Short: "Manage the Mattermost channels and their members",
+	Long: `Create, archive, unarchive and rename the Mattermost channels, set their header
+and purpose, and manage their members.
+
+The channels are identified by ID or by name (with or without the leading '~').
+The names are looked up in the team set by --team (a team name or ID), which
+can be omitted when the logged user belongs to a single team.`,
+}
+
+// channelCreateCmd represents the channel create CLI command.
+var channelCreateCmd = &cobra.Command{
+	Use:   "create NAME",
+	Short: "Create a channel",
+	Example: `  channel create --team ops incident-db --display-name "Incident: database" --private
+  channel create --team ops deploys --header "Deploy notifications" --purpose "The CI deploys"`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var opts = newOptions()
+
+		teamID, err := getTeamID(mattermostTeam, opts)
+		if err != nil {
+			return err
+		}
+		var channel = teamChannel{
+			TeamID:      teamID,
+			Name:        strings.TrimPrefix(args[0], "~"),
+			DisplayName: channelDisplayName,
+			Type:        "O",
+			Header:      channelHeader,
+			Purpose:     channelPurpose,
+		}
+		if channelPrivate {
+			channel.Type = "P"
+		}
+		channelID, err := createTeamChannel(channel, opts)
+		if err != nil {
+			return err
+		}
+		if !viper.GetBool("quiet") {
+			fmt.Fprintf(cmd.OutOrStdout(), "Channel ~%s created with the ID %s\n", channel.Name, channelID)
+		}
+		return nil
+	},
+}
+
+// channelArchiveCmd represents the channel archive CLI command.
+var channelArchiveCmd = &cobra.Command{
+	Use:   "archive CHANNEL...",
+	Short: "Archive channels",
+	Example: `  channel archive --team ops incident-db
+  channel archive ~incident-db ~incident-dns`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var opts = newOptions()
+
+		for _, channel := range args {
+			channelID, err := resolveChannelID(channel, mattermostTeam, false, opts)
+			if err != nil {
+				return err
+			}
+			if _, err := mattermostDelete("/channels/"+channelID, opts); err != nil {
+				return fmt.Errorf("cannot archive the channel %s: %v", channel, err)
+			}
+			if !viper.GetBool("quiet") {
+				fmt.Fprintf(cmd.OutOrStdout(), "Channel %s archived\n", channel)
+			}
+		}
+		return nil
+	},
+}
+
+// channelUnarchiveCmd represents the channel unarchive CLI command.
+var channelUnarchiveCmd = &cobra.Command{
+	Use:     "unarchive CHANNEL...",
+	Short:   "Unarchive channels",
+	Example: `  channel unarchive --team ops incident-db`,
+	Args:    cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var opts = newOptions()
+
+		for _, channel := range args {
+			channelID, err := resolveChannelID(channel, mattermostTeam, true, opts)
+			if err != nil {
+				return err
+			}
+			if _, err := mattermostPost("/channels/"+channelID+"/restore", nil, opts); err != nil {
+				return fmt.Errorf("cannot unarchive the channel %s: %v", channel, err)
+			}
+			if !viper.GetBool("quiet") {
+				fmt.Fprintf(cmd.OutOrStdout(), "Channel %s unarchived\n", channel)
+			}
+		}
+		return nil
+	},
+}
+
+// channelRenameCmd represents the channel rename CLI command.
+var channelRenameCmd = &cobra.Command{
+	Use:   "rename CHANNEL NEW_NAME",
+	Short: "Rename a channel",
+	Long: `Change the name of a channel, which appears in its URL, and optionally its display name.
+Use an empty NEW_NAME to change the display name only.`,
+	Example: `  channel rename --team ops incident-db incident-2026-10-19-db
+  channel rename --team ops deploys "" --display-name "Production deploys"`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var opts = newOptions()
+
+		var patch = make(map[string]string)
+		if name := strings.TrimPrefix(args[1], "~"); name != "" {
+			patch["name"] = name
+		}
+		if cmd.Flags().Changed("display-name") {
+			patch["display_name"] = channelDisplayName
+		}
+		if len(patch) == 0 {
+			return fmt.Errorf("a new name or --display-name is required")
+		}
+
+		channelID, err := resolveChannelID(args[0], mattermostTeam, false, opts)
+		if err != nil {
+			return err
+		}
+		if err := patchChannel(channelID, patch, opts); err != nil {
+			return fmt.Errorf("cannot rename the channel %s: %v", args[0], err)
+		}
+		if !viper.GetBool("quiet") {
+			fmt.Fprintf(cmd.OutOrStdout(), "Channel %s renamed\n", args[0])
+		}
+		return nil
+	},
+}
+
+// newChannelPropertyCmd returns the command grouping the subcommands of the given channel property.
+func newChannelPropertyCmd(property string) *cobra.Command {
+	return &cobra.Command{
+		Use:   property,
+		Short: "Manage the " + property + " of a channel",
+	}
+}
+
+// newChannelPropertySetCmd returns the command setting the given property (header or purpose) of a channel.
+func newChannelPropertySetCmd(property, example string) *cobra.Command {
+	return &cobra.Command{
+		Use:     "set CHANNEL " + strings.ToUpper(property),
+		Short:   "Set the " + property + " of a channel",
+		Long:    "Set the (markdown-formatted) " + property + " of a channel. An empty " + property + " clears it.",
+		Example: example,
+		Args:    cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var opts = newOptions()
+
+			channelID, err := resolveChannelID(args[0], mattermostTeam, false, opts)
+			if err != nil {
+				return err
+			}
+			if err := patchChannel(channelID, map[string]string{property: args[1]}, opts); err != nil {
+				return fmt.Errorf("cannot set the %s of the channel %s: %v", property, args[0], err)
+			}
+			if !viper.GetBool("quiet") {
+				fmt.Fprintf(cmd.OutOrStdout(), "The %s of the channel %s has been set\n", property, args[0])
+			}
+			return nil
+		},
+	}
+}
+
+// channelMembersCmd represents the channel members CLI command.
+var channelMembersCmd = &cobra.Command{
+	Use:   "members",
+	Short: "Manage the members of a channel",
+}
+
+// channelMembersAddCmd represents the channel members add CLI command.
+var channelMembersAddCmd = &cobra.Command{
+	Use:     "add CHANNEL USER...",
+	Short:   "Add users to a channel",
+	Example: `  channel members add --team ops incident-db @alice @bob`,
+	Args:    cobra.MinimumNArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var opts = newOptions()
+
+		channelID, err := resolveChannelID(args[0], mattermostTeam, false, opts)
+		if err != nil {
+			return err
+		}
+		for _, user := range args[1:] {
+			userID, err := resolveUserID(user, opts)
+			if err != nil {
+				return fmt.Errorf("cannot find the user %s: %v", user, err)
+			}
+			if err := addChannelMember(channelID, userID, opts); err != nil {
+				return fmt.Errorf("cannot add %s to the channel %s: %v", user, args[0], err)
+			}
+			if !viper.GetBool("quiet") {
+				fmt.Fprintf(cmd.OutOrStdout(), "User %s added to the channel %s\n", user, args[0])
+			}
+		}
+		return nil
+	},
+}
+
+// channelMembersRemoveCmd represents the channel members remove CLI command.
+var channelMembersRemoveCmd = &cobra.Command{
+	Use:     "remove CHANNEL USER...",
+	Short:   "Remove users from a channel",
+	Example: `  channel members remove --team ops incident-db @bob`,
+	Args:    cobra.MinimumNArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var opts = newOptions()
+
+		channelID, err := resolveChannelID(args[0], mattermostTeam, false, opts)
+		if err != nil {
+			return err
+		}
+		for _, user := range args[1:] {
+			userID, err := resolveUserID(user, opts)
+			if err != nil {
+				return fmt.Errorf("cannot find the user %s: %v", user, err)
+			}
+			if err := removeChannelMember(channelID, userID, opts); err != nil {
+				return fmt.Errorf("cannot remove %s from the channel %s: %v", user, args[0], err)
+			}
+			if !viper.GetBool("quiet") {
+				fmt.Fprintf(cmd.OutOrStdout(), "User %s removed from the channel %s\n", user, args[0])
+			}
+		}
+		return nil
+	},
+}
+
+// channelMembersListCmd represents the channel members list CLI command.
+var channelMembersListCmd = &cobra.Command{
+	Use:     "list CHANNEL",
+	Short:   "List the members of a channel",
+	Example: `  channel members list --team ops incident-db`,
+	Args:    cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var opts = newOptions()
+
+		channelID, err := resolveChannelID(args[0], mattermostTeam, false, opts)
+		if err != nil {
+			return err
+		}
+		return listChannelMembers(cmd.OutOrStdout(), channelID, opts)
+	},
+}
+
+// init initializes the channel command flags.
+func init() {
+	rootCmd.AddCommand(channelCmd)
+	channelCmd.AddCommand(channelCreateCmd)
+	channelCmd.AddCommand(channelArchiveCmd)
+	channelCmd.AddCommand(channelUnarchiveCmd)
+	channelCmd.AddCommand(channelRenameCmd)
+	for _, property := range []struct{ name, example string }{
+		{"header", `  channel header set --team ops incident-db "Status: investigating | Lead: @alice"`},
+		{"purpose", `  channel purpose set --team ops incident-db "Coordination of the database incident"`},
+	} {
+		propertyCmd := newChannelPropertyCmd(property.name)
+		propertyCmd.AddCommand(newChannelPropertySetCmd(property.name, property.example))
+		channelCmd.AddCommand(propertyCmd)
+	}
+	channelCmd.AddCommand(channelMembersCmd)
+	channelMembersCmd.AddCommand(channelMembersAddCmd)
+	channelMembersCmd.AddCommand(channelMembersRemoveCmd)
+	channelMembersCmd.AddCommand(channelMembersListCmd)
+
+	channelCmd.PersistentFlags().BoolVarP(&mattermostSkipTLSVerify,
+		"insecure", "i", false, "ignore SSL/TLS certificate check")
+	channelCmd.PersistentFlags().StringVarP(&mattermostTeam,
+		"team", "T", "", "the Mattermost team name or ID (default is the only team of the logged user)")
+	channelCmd.PersistentFlags().DurationVarP(&mattermostConnectionTimeout,
+		"timeout", "s", 10*time.Second, "the maximum time in seconds allowed for a Mattermost connection")
+
+	channelCreateCmd.Flags().StringVar(&channelDisplayName,
+		"display-name", "", "the display name of the channel (default is the channel name)")
+	channelCreateCmd.Flags().StringVar(&channelHeader,
+		"header", "", "the (markdown-formatted) header of the channel")
+	channelCreateCmd.Flags().BoolVar(&channelPrivate,
+		"private", false, "create a private channel")
+	channelCreateCmd.Flags().StringVar(&channelPurpose,
+		"purpose", "", "the purpose of the channel")
+	channelRenameCmd.Flags().StringVar(&channelDisplayName,
+		"display-name", "", "the new display name of the channel")
+}
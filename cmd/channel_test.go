@@ -0,0 +1,172 @@
+/*
+  Copyright 2026 Davide Madrisan <d.madrisan@proton.me>
+
+  Licensed under the Mozilla Public License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+      https://www.mozilla.org/en-US/MPL/2.0/
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/madrisan/go-mattermost-notify/config"
+	"github.com/madrisan/go-mattermost-notify/mattermost/mattermosttest"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func TestGetTeamID(t *testing.T) {
+	srv := mattermosttest.NewServer()
+	defer srv.Close()
+
+	defer saveSettings("url", "access-token")()
+	viper.Set("url", srv.URL)
+	viper.Set("access-token", srv.Token)
+
+	if _, err := getTeamID("", config.Options{}); err == nil {
+		t.Error("no team should be found for a user without teams")
+	}
+	ops := srv.AddTeam("ops")
+	if id, err := getTeamID("", config.Options{}); err != nil || id != ops.ID {
+		t.Errorf("expected the only team %s, got %s (%v)", ops.ID, id, err)
+	}
+	dev := srv.AddTeam("dev")
+	if _, err := getTeamID("", config.Options{}); err == nil || !strings.Contains(err.Error(), "--team") {
+		t.Errorf("the team should be required for a user in several teams, got %v", err)
+	}
+
+	var testCases = []struct {
+		team     string
+		shouldBe string
+	}{
+		{"ops", ops.ID},
+		{"dev", dev.ID},
+		{dev.ID, dev.ID},
+	}
+	for _, tc := range testCases {
+		if id, err := getTeamID(tc.team, config.Options{}); err != nil || id != tc.shouldBe {
+			t.Errorf("%q: expected %s, got %s (%v)", tc.team, tc.shouldBe, id, err)
+		}
+	}
+	if _, err := getTeamID("nowhere", config.Options{}); err == nil {
+		t.Error("an unknown team should not be found")
+	}
+}
+
+func TestChannelCommands(t *testing.T) {
+	srv := mattermosttest.NewServer()
+	defer srv.Close()
+	srv.AddTeam("ops")
+	alice := srv.AddUser("alice")
+	bob := srv.AddUser("bob")
+
+	defer saveSettings("url", "access-token", "quiet")()
+	viper.Set("url", srv.URL)
+	viper.Set("access-token", srv.Token)
+	viper.Set("quiet", false)
+
+	headerSetCmd, _, err := channelCmd.Find([]string{"header", "set"})
+	if err != nil {
+		t.Fatal("cannot find the header set command:", err)
+	}
+	purposeSetCmd, _, err := channelCmd.Find([]string{"purpose", "set"})
+	if err != nil {
+		t.Fatal("cannot find the purpose set command:", err)
+	}
+
+	var out bytes.Buffer
+	for _, c := range []*cobra.Command{channelCreateCmd, channelArchiveCmd, channelUnarchiveCmd, channelRenameCmd,
+		headerSetCmd, purposeSetCmd, channelMembersAddCmd, channelMembersRemoveCmd, channelMembersListCmd} {
+		c.SetOut(&out)
+		defer c.SetOut(nil)
+	}
+
+	oldTeam, oldDisplayName, oldPrivate := mattermostTeam, channelDisplayName, channelPrivate
+	defer func() { mattermostTeam, channelDisplayName, channelPrivate = oldTeam, oldDisplayName, oldPrivate }()
+	mattermostTeam, channelDisplayName, channelPrivate = "ops", "Incident: database", true
+
+	if err := channelCreateCmd.RunE(channelCreateCmd, []string{"incident-db"}); err != nil {
+		t.Fatal("cannot create the channel:", err)
+	}
+	if err := channelCreateCmd.RunE(channelCreateCmd, []string{"incident-db"}); err == nil {
+		t.Error("a channel with an existing name should not be created")
+	}
+	channelID, err := resolveChannelID("~incident-db", "ops", false, config.Options{})
+	if err != nil {
+		t.Fatal("cannot find the created channel:", err)
+	}
+	if c := srv.Channel(channelID); c.DisplayName != "Incident: database" || c.Type != "P" {
+		t.Errorf("unexpected channel: %+v", c)
+	}
+
+	if err := headerSetCmd.RunE(headerSetCmd, []string{"incident-db", "Lead: @alice"}); err != nil {
+		t.Fatal("cannot set the header:", err)
+	}
+	if err := purposeSetCmd.RunE(purposeSetCmd, []string{"incident-db", "Database outage"}); err != nil {
+		t.Fatal("cannot set the purpose:", err)
+	}
+	if c := srv.Channel(channelID); c.Header != "Lead: @alice" || c.Purpose != "Database outage" {
+		t.Errorf("unexpected header and purpose: %+v", c)
+	}
+
+	if err := channelMembersAddCmd.RunE(channelMembersAddCmd, []string{"incident-db", "@alice", "bob"}); err != nil {
+		t.Fatal("cannot add the members:", err)
+	}
+	if err := channelMembersRemoveCmd.RunE(channelMembersRemoveCmd, []string{"incident-db", "@bob"}); err != nil {
+		t.Fatal("cannot remove the member:", err)
+	}
+	if members := srv.ChannelMembers(channelID); len(members) != 2 || members[0] != srv.Me().ID || members[1] != alice.ID {
+		t.Errorf("unexpected members: %v", members)
+	}
+	if err := channelMembersRemoveCmd.RunE(channelMembersRemoveCmd, []string{"incident-db", bob.ID}); err == nil {
+		t.Error("removing a user who is not a member should fail")
+	}
+
+	out.Reset()
+	if err := channelMembersListCmd.RunE(channelMembersListCmd, []string{channelID}); err != nil {
+		t.Fatal("cannot list the members:", err)
+	}
+	if lines := strings.Split(strings.TrimSpace(out.String()), "\n"); len(lines) != 3 ||
+		!strings.HasPrefix(lines[1], "@alice") || !strings.HasPrefix(lines[2], "@bot") {
+		t.Errorf("unexpected member list:\n%s", out.String())
+	}
+
+	channelDisplayName = "Incident: DB"
+	if err := channelRenameCmd.ParseFlags([]string{"--display-name", channelDisplayName}); err != nil {
+		t.Fatal(err)
+	}
+	if err := channelRenameCmd.RunE(channelRenameCmd, []string{"incident-db", "incident-2026-db"}); err != nil {
+		t.Fatal("cannot rename the channel:", err)
+	}
+	if c := srv.Channel(channelID); c.Name != "incident-2026-db" || c.DisplayName != "Incident: DB" {
+		t.Errorf("unexpected renamed channel: %+v", c)
+	}
+
+	if err := channelArchiveCmd.RunE(channelArchiveCmd, []string{"incident-2026-db"}); err != nil {
+		t.Fatal("cannot archive the channel:", err)
+	}
+	if c := srv.Channel(channelID); c.DeleteAt == 0 {
+		t.Error("the channel should be archived")
+	}
+	if err := channelArchiveCmd.RunE(channelArchiveCmd, []string{"incident-2026-db"}); err == nil {
+		t.Error("an archived channel should not be found by name")
+	}
+	if err := channelUnarchiveCmd.RunE(channelUnarchiveCmd, []string{"incident-2026-db"}); err != nil {
+		t.Fatal("cannot unarchive the channel:", err)
+	}
+	if c := srv.Channel(channelID); c.DeleteAt != 0 {
+		t.Error("the channel should be unarchived")
+	}
+}
@@ -0,0 +1,381 @@
+/*
+  Copyright 2026 Davide Madrisan <d.madrisan@proton.me>
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+      http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"time"
+
+	mattermost "github.com/madrisan/go-mattermost-notify/mattermost"
+	"github.com/spf13/viper"
+)
+
+// defaultChatopsWorkers is the number of action and ChatOps commands run concurrently when not configured.
+const defaultChatopsWorkers = 4
+
+// chatopsReplyDelay is the maximum time Mattermost is kept waiting for the result of a command.
+// The result of the longer commands is sent later as a delayed response.
+var chatopsReplyDelay = 2 * time.Second
+
+// defaultCommandTimeout is the maximum time allowed to the commands run by the serve
+// command when no timeout is configured.
+const defaultCommandTimeout = 5 * time.Minute
+
+// commandWaitDelay is the time given to the commands run by runCommandWithTimeout to close their
+// output once they have exited or been killed, for instance when a child process keeps it open.
+const commandWaitDelay = 5 * time.Second
+
+// maxCommandOutputSize is the maximum size in bytes of the output kept by runCommandWithTimeout.
+const maxCommandOutputSize = 4000
+
+// chatopsSubcommand is an executable run by a ChatOps command, selected by the first word
+// of the command text.
+type chatopsSubcommand struct {
+	Command []string `mapstructure:"command"`
+	// PassArgs tells whether the words following the subcommand are passed to the executable.
+	PassArgs bool `mapstructure:"pass-args"`
+}
+
+// chatopsCommand is a slash command or an outgoing webhook trigger word set in the
+// 'chatops.commands' section of the configuration file.
+type chatopsCommand struct {
+	// Token is the token generated by Mattermost for the slash command or the outgoing webhook.
+	Token string `mapstructure:"token"`
+	// ResponseType is either "ephemeral" (the default) or "in_channel".
+	ResponseType string        `mapstructure:"response-type"`
+	Timeout      time.Duration `mapstructure:"timeout"`
+	// Command and PassArgs are used when there is no subcommand.
+	Command     []string                     `mapstructure:"command"`
+	PassArgs    bool                         `mapstructure:"pass-args"`
+	Subcommands map[string]chatopsSubcommand `mapstructure:"subcommands"`
+}
+
+// chatopsConfig is the 'chatops' section of the configuration file.
+type chatopsConfig struct {
+	Workers  int                       `mapstructure:"workers"`
+	Commands map[string]chatopsCommand `mapstructure:"commands"`
+}
+
+// chatopsRequest is a slash command or an outgoing webhook request sent by Mattermost.
+type chatopsRequest struct {
+	Token       string `json:"token"`
+	Command     string `json:"command"`
+	TriggerWord string `json:"trigger_word"`
+	Text        string `json:"text"`
+	ResponseURL string `json:"response_url"`
+	UserName    string `json:"user_name"`
+	ChannelID   string `json:"channel_id"`
+	ChannelName string `json:"channel_name"`
+	TeamDomain  string `json:"team_domain"`
+	PostID      string `json:"post_id"`
+}
+
+// chatopsResponse is the response to a slash command or an outgoing webhook.
+type chatopsResponse struct {
+	ResponseType string `json:"response_type,omitempty"`
+	Text         string `json:"text"`
+}
+
+// getChatopsConfig returns the ChatOps commands set in the configuration file.
+func getChatopsConfig() (chatopsConfig, error) {
+	var cfg chatopsConfig
+	if err := viper.UnmarshalKey("chatops", &cfg); err != nil {
+		return cfg, fmt.Errorf("invalid 'chatops' section in the configuration file: %v", err)
+	}
+
+	for name, c := range cfg.Commands {
+		if c.Token == "" {
+			return cfg, fmt.Errorf("no token has been set for the ChatOps command %s", name)
+		}
+		if c.ResponseType != "" && c.ResponseType != "ephemeral" && c.ResponseType != "in_channel" {
+			return cfg, fmt.Errorf("invalid response type \"%s\" for the ChatOps command %s", c.ResponseType, name)
+		}
+	}
+	if cfg.Workers <= 0 {
+		cfg.Workers = defaultChatopsWorkers
+	}
+
+	return cfg, nil
+}
+
+// parseChatopsRequest decodes the request sent by Mattermost either as a JSON document or as a form.
+func parseChatopsRequest(r *http.Request) (*chatopsRequest, error) {
+	var req chatopsRequest
+
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			return nil, fmt.Errorf("invalid JSON request: %v", err)
+		}
+	} else {
+		if err := r.ParseForm(); err != nil {
+			return nil, fmt.Errorf("invalid form request: %v", err)
+		}
+		req = chatopsRequest{
+			Token:       r.FormValue("token"),
+			Command:     r.FormValue("command"),
+			TriggerWord: r.FormValue("trigger_word"),
+			Text:        r.FormValue("text"),
+			ResponseURL: r.FormValue("response_url"),
+			UserName:    r.FormValue("user_name"),
+			ChannelID:   r.FormValue("channel_id"),
+			ChannelName: r.FormValue("channel_name"),
+			TeamDomain:  r.FormValue("team_domain"),
+			PostID:      r.FormValue("post_id"),
+		}
+	}
+
+	// The slash commands also send their token in the header 'Authorization: Token <token>'.
+	if token, found := strings.CutPrefix(r.Header.Get("Authorization"), "Token "); found && req.Token == "" {
+		req.Token = token
+	}
+
+	return &req, nil
+}
+
+// getChatopsArgs returns the executable and the arguments to be run for the given command text,
+// or an error message describing the command usage.
+func getChatopsArgs(name string, c chatopsCommand, text string) ([]string, error) {
+	words := strings.Fields(text)
+
+	command, passArgs := c.Command, c.PassArgs
+	if len(c.Subcommands) > 0 {
+		var subcommands []string
+		for subcommand := range c.Subcommands {
+			subcommands = append(subcommands, subcommand)
+		}
+		sort.Strings(subcommands)
+		usage := fmt.Errorf("usage: %s %s", name, strings.Join(subcommands, "|"))
+
+		if len(words) == 0 {
+			return nil, usage
+		}
+		sub, found := c.Subcommands[strings.ToLower(words[0])]
+		if !found {
+			return nil, usage
+		}
+		command, passArgs, words = sub.Command, sub.PassArgs, words[1:]
+	}
+
+	if len(command) == 0 {
+		return nil, fmt.Errorf("no executable has been configured for %s", name)
+	}
+	if len(words) > 0 && !passArgs {
+		return nil, fmt.Errorf("%s does not accept any argument", name)
+	}
+
+	return append(append([]string(nil), command...), words...), nil
+}
+
+// runCommandWithTimeout runs the given command with the given additional environment variables,
+// killing it when the timeout expires (defaultCommandTimeout if not positive). It returns the exit
+// code of the command, whether it has been killed because of the timeout (the exit code is then
+// not significant), and the end of its combined output, ready to be put in a markdown code block.
+func runCommandWithTimeout(ctx context.Context, args, env []string, timeout time.Duration) (int, bool, string) {
+	if timeout <= 0 {
+		timeout = defaultCommandTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	c := exec.CommandContext(ctx, args[0], args[1:]...)
+	c.Env = append(os.Environ(), env...)
+	c.WaitDelay = commandWaitDelay
+	output, err := c.CombinedOutput()
+
+	var exitCode int
+	var timedOut bool
+	var exitErr *exec.ExitError
+	switch {
+	case ctx.Err() == context.DeadlineExceeded:
+		exitCode, timedOut = -1, true
+		output = append(output, fmt.Sprintf("\nthe command has been killed after %s", timeout)...)
+	case ctx.Err() == context.Canceled:
+		exitCode = 1
+		output = append(output, "\nthe command has been killed at shutdown"...)
+	case errors.As(err, &exitErr):
+		exitCode = exitErr.ExitCode()
+	case errors.Is(err, exec.ErrWaitDelay):
+		// The command has exited successfully, but a child process has kept its output open.
+	case err != nil:
+		exitCode = exitCodeCannotRun
+		output = append(output, err.Error()...)
+	}
+
+	if len(output) > maxCommandOutputSize {
+		output = output[len(output)-maxCommandOutputSize:]
+	}
+	return exitCode, timedOut, escapeCodeBlock(strings.TrimSpace(string(output)))
+}
+
+// runJobCommand runs the given command with runCommandWithTimeout, once a worker is available.
+// The command is killed, or not started, when the jobs are canceled at shutdown.
+func (s *relayServer) runJobCommand(args, env []string, timeout time.Duration) (int, bool, string) {
+	select {
+	case s.workers <- struct{}{}:
+		defer func() { <-s.workers }()
+	case <-s.jobsCtx.Done():
+	}
+	return runCommandWithTimeout(s.jobsCtx, args, env, timeout)
+}
+
+// getChatopsReply returns the markdown text reporting the outcome of a ChatOps command.
+func getChatopsReply(commandLine string, exitCode int, timedOut bool, output string) string {
+	var text string
+	switch {
+	case timedOut:
+		text = fmt.Sprintf("`%s` has timed out.", commandLine)
+	case exitCode == 0:
+		text = fmt.Sprintf("`%s` has completed successfully.", commandLine)
+	default:
+		text = fmt.Sprintf("`%s` has failed with exit code %d.", commandLine, exitCode)
+	}
+	if output != "" {
+		text += "\n```\n" + output + "\n```"
+	}
+	return text
+}
+
+// sendDelayedChatopsReply sends the result of a command that took too long to Mattermost: to the
+// response URL of the slash command or, for the outgoing webhooks, as a reply to the trigger post.
+func (s *relayServer) sendDelayedChatopsReply(req *chatopsRequest, response chatopsResponse) error {
+	if req.ResponseURL == "" {
+		payload, err := json.Marshal(map[string]string{
+			"channel_id": req.ChannelID,
+			"message":    response.Text,
+			"root_id":    req.PostID,
+		})
+		if err != nil {
+			return err
+		}
+		_, err = mattermostPost("/posts", bytes.NewReader(payload), s.opts)
+		return err
+	}
+
+	payload, err := json.Marshal(response)
+	if err != nil {
+		return err
+	}
+
+	client := s.opts.HTTPClient
+	if client == nil {
+		client = mattermost.NewHTTPClient(s.opts)
+	}
+	resp, err := client.Post(req.ResponseURL, "application/json", bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("the delayed response has ended with a %d (\"%s\") code",
+			resp.StatusCode, http.StatusText(resp.StatusCode))
+	}
+
+	return nil
+}
+
+// handleChatops runs the executable mapped to a slash command or an outgoing webhook and replies
+// with its result. The commands run in a worker pool; when a command takes more than
+// chatopsReplyDelay, Mattermost is answered at once and the result is sent later.
+func (s *relayServer) handleChatops(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestBodySize)
+
+	req, err := parseChatopsRequest(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	// Slash commands are identified by the command, outgoing webhooks by the trigger word.
+	name, text := strings.TrimPrefix(req.Command, "/"), req.Text
+	if req.Command == "" {
+		name, text = req.TriggerWord, strings.TrimPrefix(strings.TrimSpace(req.Text), req.TriggerWord)
+	}
+
+	c, found := s.chatops.Commands[strings.ToLower(name)]
+	if !found || subtle.ConstantTimeCompare([]byte(c.Token), []byte(req.Token)) != 1 {
+		writeError(w, http.StatusUnauthorized, fmt.Errorf("invalid or missing token"))
+		return
+	}
+	if p, ok := r.Context().Value(relayClientKey{}).(*string); ok {
+		*p = "@" + req.UserName
+	}
+
+	var responseType = c.ResponseType
+	if req.Command == "" {
+		// The outgoing webhooks cannot reply ephemerally.
+		responseType = ""
+	} else if responseType == "" {
+		responseType = "ephemeral"
+	}
+	commandLine := strings.Join(strings.Fields(req.Command+" "+req.TriggerWord+" "+text), " ")
+
+	args, err := getChatopsArgs(strings.TrimSpace(req.Command+" "+req.TriggerWord), c, text)
+	if err != nil {
+		writeJSON(w, http.StatusOK, chatopsResponse{ResponseType: responseType, Text: err.Error()})
+		return
+	}
+
+	var done = make(chan chatopsResponse, 1)
+	s.jobs.Add(1)
+	go func() {
+		defer s.jobs.Done()
+
+		exitCode, timedOut, output := s.runJobCommand(args, []string{
+			"MM_COMMAND=" + name,
+			"MM_TEXT=" + strings.TrimSpace(text),
+			"MM_USER_NAME=" + req.UserName,
+			"MM_CHANNEL_ID=" + req.ChannelID,
+			"MM_CHANNEL_NAME=" + req.ChannelName,
+			"MM_TEAM_DOMAIN=" + req.TeamDomain,
+		}, c.Timeout)
+
+		done <- chatopsResponse{
+			ResponseType: responseType,
+			Text:         getChatopsReply(commandLine, exitCode, timedOut, output),
+		}
+	}()
+
+	select {
+	case response := <-done:
+		writeJSON(w, http.StatusOK, response)
+		return
+	case <-time.After(chatopsReplyDelay):
+	}
+
+	s.jobs.Add(1)
+	go func() {
+		defer s.jobs.Done()
+		if err := s.sendDelayedChatopsReply(req, <-done); err != nil {
+			s.logger.Printf("%s: cannot send the delayed response: %v", name, err)
+		}
+	}()
+
+	writeJSON(w, http.StatusOK, chatopsResponse{
+		ResponseType: responseType,
+		Text:         fmt.Sprintf("`%s` is running, the result will be posted when it completes.", commandLine),
+	})
+}
@@ -0,0 +1,210 @@
+/*
+  Copyright 2026 Davide Madrisan <d.madrisan@proton.me>
+
+  Licensed under the Mozilla Public License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+      https://www.mozilla.org/en-US/MPL/2.0/
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+*/
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-test/deep"
+)
+
+func TestChatopsHelperProcess(t *testing.T) {
+	if os.Getenv("GO_WANT_CHATOPS_HELPER_PROCESS") != "1" {
+		return
+	}
+	switch os.Args[len(os.Args)-1] {
+	case "slow":
+		// Wait until the test has received the first answer.
+		for release := os.Getenv("CHATOPS_HELPER_RELEASE"); ; time.Sleep(10 * time.Millisecond) {
+			if _, err := os.Stat(release); err == nil {
+				break
+			}
+		}
+	case "hang":
+		time.Sleep(time.Minute)
+	}
+	fmt.Printf("%s asked for %s\n", os.Getenv("MM_USER_NAME"), os.Getenv("MM_TEXT"))
+	os.Exit(0)
+}
+
+func TestGetChatopsArgs(t *testing.T) {
+	t.Parallel()
+
+	deploy := chatopsCommand{
+		Subcommands: map[string]chatopsSubcommand{
+			"status":   {Command: []string{"/usr/local/bin/deploy-status"}},
+			"rollback": {Command: []string{"/usr/local/bin/rollback", "--force"}, PassArgs: true},
+		},
+	}
+	uptime := chatopsCommand{Command: []string{"uptime"}}
+
+	cases := []struct {
+		name     string
+		command  chatopsCommand
+		text     string
+		shouldBe []string
+		err      string
+	}{
+		{"subcommand", deploy, "status", []string{"/usr/local/bin/deploy-status"}, ""},
+		{"subcommand arguments", deploy, " Rollback  web v1.2 ", []string{"/usr/local/bin/rollback", "--force", "web", "v1.2"}, ""},
+		{"unexpected arguments", deploy, "status web", nil, "/deploy does not accept any argument"},
+		{"unknown subcommand", deploy, "destroy", nil, "usage: /deploy rollback|status"},
+		{"no subcommand", deploy, "", nil, "usage: /deploy rollback|status"},
+		{"command", uptime, "", []string{"uptime"}, ""},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			args, err := getChatopsArgs("/deploy", tc.command, tc.text)
+			if err != nil {
+				if err.Error() != tc.err {
+					t.Error("expected error", tc.err, "got", err)
+				}
+				return
+			}
+			if diff := deep.Equal(args, tc.shouldBe); diff != nil {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func TestHandleChatops(t *testing.T) {
+	oldChatopsReplyDelay := chatopsReplyDelay
+	defer func() { chatopsReplyDelay = oldChatopsReplyDelay }()
+	// The commands answering at once are given a wide margin, whatever the start-up time of the helper.
+	chatopsReplyDelay = time.Minute
+
+	release := filepath.Join(t.TempDir(), "release")
+	t.Setenv("GO_WANT_CHATOPS_HELPER_PROCESS", "1")
+	t.Setenv("CHATOPS_HELPER_RELEASE", release)
+	helper := []string{os.Args[0], "-test.run=TestChatopsHelperProcess", "--"}
+
+	s := &relayServer{
+		logger: log.New(io.Discard, "", 0),
+		chatops: chatopsConfig{
+			Commands: map[string]chatopsCommand{
+				"deploy": {
+					Token:        "slash-token",
+					ResponseType: "in_channel",
+					Subcommands: map[string]chatopsSubcommand{
+						"status": {Command: append(helper, "status")},
+						"slow":   {Command: append(helper, "slow")},
+					},
+				},
+				"hang":   {Token: "hang-token", Timeout: 100 * time.Millisecond, Command: append(helper, "hang")},
+				"uptime": {Token: "webhook-token", Command: helper},
+			},
+		},
+		workers: make(chan struct{}, 1),
+		jobsCtx: context.Background(),
+	}
+	srv := httptest.NewServer(s.handler())
+	defer srv.Close()
+
+	var delayed = make(chan chatopsResponse, 1)
+	responseURL := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var response chatopsResponse
+		json.NewDecoder(r.Body).Decode(&response)
+		delayed <- response
+	}))
+	defer responseURL.Close()
+
+	request := func(form url.Values) (int, chatopsResponse) {
+		resp, err := http.PostForm(srv.URL+"/chatops", form)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		var response chatopsResponse
+		json.NewDecoder(resp.Body).Decode(&response)
+		return resp.StatusCode, response
+	}
+
+	t.Run("invalid token", func(t *testing.T) {
+		status, _ := request(url.Values{"command": {"/deploy"}, "text": {"status"}, "token": {"webhook-token"}})
+		if status != http.StatusUnauthorized {
+			t.Error("expected status 401, got", status)
+		}
+	})
+
+	t.Run("slash command", func(t *testing.T) {
+		status, response := request(url.Values{"command": {"/deploy"}, "text": {"status"},
+			"token": {"slash-token"}, "user_name": {"alice"}})
+		if status != http.StatusOK || response.ResponseType != "in_channel" ||
+			!strings.HasPrefix(response.Text, "`/deploy status` has completed successfully.") ||
+			!strings.Contains(response.Text, "alice asked for status") {
+			t.Error("unexpected response", status, response)
+		}
+	})
+
+	t.Run("usage", func(t *testing.T) {
+		_, response := request(url.Values{"command": {"/deploy"}, "text": {"destroy"}, "token": {"slash-token"}})
+		if response.Text != "usage: /deploy slow|status" {
+			t.Error("unexpected response", response)
+		}
+	})
+
+	t.Run("outgoing webhook", func(t *testing.T) {
+		_, response := request(url.Values{"trigger_word": {"uptime"}, "text": {"uptime"},
+			"token": {"webhook-token"}, "user_name": {"bob"}})
+		if response.ResponseType != "" || !strings.HasPrefix(response.Text, "`uptime` has completed successfully.") {
+			t.Error("unexpected response", response)
+		}
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		_, response := request(url.Values{"command": {"/hang"}, "token": {"hang-token"}})
+		if response.ResponseType != "ephemeral" || !strings.HasPrefix(response.Text, "`/hang` has timed out.") {
+			t.Error("unexpected response", response)
+		}
+	})
+
+	t.Run("delayed response", func(t *testing.T) {
+		// The slow command completes only once the first answer has been received.
+		chatopsReplyDelay = 10 * time.Millisecond
+		_, response := request(url.Values{"command": {"/deploy"}, "text": {"slow"},
+			"token": {"slash-token"}, "user_name": {"alice"}, "response_url": {responseURL.URL}})
+		if response.ResponseType != "in_channel" || response.Text != "`/deploy slow` is running, the result will be posted when it completes." {
+			t.Error("unexpected response", response)
+		}
+		if err := os.WriteFile(release, nil, 0o600); err != nil {
+			t.Fatal(err)
+		}
+		select {
+		case response := <-delayed:
+			if response.ResponseType != "in_channel" || !strings.Contains(response.Text, "alice asked for slow") {
+				t.Error("unexpected delayed response", response)
+			}
+		case <-time.After(5 * time.Second):
+			t.Error("no delayed response has been received")
+		}
+		s.jobs.Wait()
+	})
+}
@@ -0,0 +1,241 @@
+/*
+  Copyright 2026 Davide Madrisan <d.madrisan@proton.me>
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+      http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+*/
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	mattermost "github.com/madrisan/go-mattermost-notify/mattermost"
+	"github.com/spf13/cobra"
+
+	"github.com/madrisan/go-mattermost-notify/config"
+)
+
+var (
+	// checkServerStatus tells if the status of the Mattermost database and file store must be checked.
+	checkServerStatus bool
+	// checkWarning is the latency above which the check returns a warning.
+	checkWarning time.Duration
+	// checkCritical is the latency above which the check returns a critical status.
+	checkCritical time.Duration
+	// checkPerfdata tells if the performance data must be appended to the check output.
+	checkPerfdata bool
+)
+
+// The Nagios plugin exit codes.
+const (
+	checkOK       = 0
+	checkWarn     = 1
+	checkCrit     = 2
+	checkUnknown  = 3
+	checkStatuses = "OK WARNING CRITICAL UNKNOWN"
+)
+
+// checkSeverity orders the check statuses from the least to the most severe.
+var checkSeverity = map[int]int{
+	checkOK:      0,
+	checkUnknown: 1,
+	checkWarn:    2,
+	checkCrit:    3,
+}
+
+// checkResult is the outcome of the Mattermost health check.
+type checkResult struct {
+	Status   int
+	Messages []string
+	Latency  time.Duration
+	Version  string
+}
+
+// report records a check message, raising the check status if the given one is more severe.
+func (r *checkResult) report(status int, format string, a ...interface{}) {
+	if checkSeverity[status] > checkSeverity[r.Status] {
+		r.Status = status
+	}
+	r.Messages = append(r.Messages, fmt.Sprintf(format, a...))
+}
+
+// output returns the check output line in the Nagios plugin format, with the optional perfdata.
+func (r *checkResult) output(perfdata bool, warning, critical time.Duration) string {
+	line := fmt.Sprintf("MATTERMOST %s - %s",
+		strings.Fields(checkStatuses)[r.Status], strings.Join(r.Messages, ", "))
+	if perfdata && r.Latency > 0 {
+		line += fmt.Sprintf(" | time=%.6fs;%.6f;%.6f;0.000000",
+			r.Latency.Seconds(), warning.Seconds(), critical.Seconds())
+	}
+	return line
+}
+
+// getServerVersion returns the Mattermost version from the X-Version-Id header,
+// like 9.11.0 for 9.11.0.9.11.0.a1b2c3.false.
+func getServerVersion(header http.Header) string {
+	parts := strings.Split(header.Get("X-Version-Id"), ".")
+	if len(parts) < 3 {
+		return "unknown"
+	}
+	return strings.Join(parts[:3], ".")
+}
+
+// runCheck checks that Mattermost is reachable, that the access token is valid and,
+// if a channel is given, that the logged user is a member of it. The check only reads
+// from Mattermost: the direct channel with a @username is not created when missing.
+func runCheck(channel string, serverStatus bool, warning, critical time.Duration, opts config.Options) checkResult {
+	var r checkResult
+
+	if err := mattermost.CheckSettings(); err != nil {
+		r.report(checkUnknown, "%v", err)
+		return r
+	}
+	var localMode = mattermost.IsLocalMode()
+
+	endpoint := "/system/ping"
+	if serverStatus {
+		endpoint += "?get_server_status=true"
+	}
+	start := time.Now()
+	response, err := mattermostDo(http.MethodGet, endpoint, nil, opts)
+	if err != nil {
+		r.report(checkCrit, "Mattermost is unreachable: %v", err)
+		return r
+	}
+	r.Latency = time.Since(start)
+	r.Version = getServerVersion(response.Header)
+
+	var latencyStatus = checkOK
+	switch {
+	case critical > 0 && r.Latency >= critical:
+		latencyStatus = checkCrit
+	case warning > 0 && r.Latency >= warning:
+		latencyStatus = checkWarn
+	}
+	r.report(latencyStatus, "Mattermost %s replied in %s", r.Version, r.Latency.Round(time.Millisecond))
+
+	status, _ := response.Data.(map[string]interface{})
+	if s, _ := status["status"].(string); s != "OK" {
+		r.report(checkCrit, "server status is %s", s)
+	}
+	var keys []string
+	for key := range status {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if s, ok := status[key].(string); ok && key != "status" && strings.HasSuffix(key, "_status") && s != "OK" {
+			r.report(checkWarn, "%s is %s", key, s)
+		}
+	}
+
+	// There is no session, hence no logged user, in local mode.
+	if localMode {
+		r.report(checkOK, "connected through the local mode socket")
+		if channel != "" {
+			if _, err := mattermostGet("/channels/"+channel, opts); err != nil {
+				r.report(checkCrit, "cannot get the channel %s: %v", channel, err)
+			} else {
+				r.report(checkOK, "the channel %s exists", channel)
+			}
+		}
+		return r
+	}
+
+	username, err := getLoggedUsername(opts)
+	if err != nil {
+		var apiErr *mattermost.APIError
+		if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusUnauthorized {
+			r.report(checkCrit, "the access token is invalid")
+		} else {
+			r.report(checkCrit, "cannot check the access token: %v", err)
+		}
+		return r
+	}
+	r.report(checkOK, "token of @%s is valid", username)
+
+	if channel == "" {
+		return r
+	}
+	var channelID = channel
+	if strings.HasPrefix(channel, "@") {
+		if channelID, err = findDirectChannelID(channel, opts); err != nil {
+			r.report(checkCrit, "cannot check the direct channel with %s: %v", channel, err)
+			return r
+		}
+		if channelID == "" {
+			// The direct channel is created by the first post.
+			r.report(checkOK, "%s exists, no direct channel with @%s yet", channel, username)
+			return r
+		}
+	}
+	// The membership is checked, not the permission to post.
+	if _, err := mattermostGet("/channels/"+channelID+"/members/me", opts); err != nil {
+		r.report(checkCrit, "@%s is not a member of %s: %v", username, channel, err)
+	} else {
+		r.report(checkOK, "@%s is a member of %s", username, channel)
+	}
+
+	return r
+}
+
+// checkCmd represents the check CLI command.
+var checkCmd = &cobra.Command{
+	Use:     "check",
+	Aliases: []string{"ping"},
+	Short:   "Check Mattermost reachability and token validity",
+	Long: `Check that Mattermost is reachable, that the access token is valid and, if a
+channel is given, that the logged user is a member of it (the permission to post is
+not checked). The direct channel with a @username is not created when missing.
+The latency and the server version are reported.
+
+The command can be used as a Nagios plugin: it exits with code 0 (OK), 1 (WARNING),
+2 (CRITICAL) or 3 (UNKNOWN), and the performance data can be added to its output.`,
+	Example: `  check
+  check -c rybfbdi9ojy8xxxjjxc88kh3me --server-status --warning 500ms --critical 2s --perfdata`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var opts = newOptions()
+
+		r := runCheck(mattermostChannel, checkServerStatus, checkWarning, checkCritical, opts)
+		fmt.Println(r.output(checkPerfdata, checkWarning, checkCritical))
+		if r.Status != checkOK {
+			return exitWithCode(cmd, r.Status)
+		}
+		return nil
+	},
+}
+
+// init initializes the check command flags.
+func init() {
+	rootCmd.AddCommand(checkCmd)
+
+	checkCmd.Flags().StringVarP(&mattermostChannel,
+		"channel", "c", "", "Mattermost channel ID or @username the logged user must be a member of")
+	checkCmd.Flags().DurationVar(&checkCritical,
+		"critical", 5*time.Second, "the latency above which the status is critical")
+	checkCmd.Flags().BoolVarP(&mattermostSkipTLSVerify,
+		"insecure", "i", false, "ignore SSL/TLS certificate check")
+	checkCmd.Flags().BoolVar(&checkPerfdata,
+		"perfdata", false, "append the performance data to the output")
+	checkCmd.Flags().BoolVar(&checkServerStatus,
+		"server-status", false, "also check the status of the Mattermost database and file store")
+	checkCmd.Flags().DurationVarP(&mattermostConnectionTimeout,
+		"timeout", "s", 10*time.Second, "the maximum time in seconds allowed for a Mattermost connection")
+	checkCmd.Flags().DurationVar(&checkWarning,
+		"warning", time.Second, "the latency above which the status is warning")
+}
@@ -0,0 +1,179 @@
+/*
+  Copyright 2026 Davide Madrisan <d.madrisan@proton.me>
+
+  Licensed under the Mozilla Public License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+      https://www.mozilla.org/en-US/MPL/2.0/
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+*/
+
+package cmd
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/madrisan/go-mattermost-notify/config"
+	mattermost "github.com/madrisan/go-mattermost-notify/mattermost"
+	"github.com/madrisan/go-mattermost-notify/mattermost/mattermosttest"
+	"github.com/spf13/viper"
+)
+
+func TestGetServerVersion(t *testing.T) {
+	var testCases = map[string]string{
+		"9.11.0.9.11.0.a1b2c3.false": "9.11.0",
+		"10.5.1":                     "10.5.1",
+		"":                           "unknown",
+	}
+	for header, shouldBe := range testCases {
+		h := http.Header{}
+		h.Set("X-Version-Id", header)
+		if v := getServerVersion(h); v != shouldBe {
+			t.Errorf("%q: expected %s, got %s", header, shouldBe, v)
+		}
+	}
+}
+
+func TestCheckResultOutput(t *testing.T) {
+	var r checkResult
+	r.report(checkOK, "first")
+	r.report(checkWarn, "second")
+	r.report(checkUnknown, "third")
+	if r.Status != checkWarn {
+		t.Errorf("expected status %d, got %d", checkWarn, r.Status)
+	}
+
+	r.Latency = 250 * time.Millisecond
+	shouldBe := "MATTERMOST WARNING - first, second, third"
+	if v := r.output(false, time.Second, 5*time.Second); v != shouldBe {
+		t.Errorf("expected %q, got %q", shouldBe, v)
+	}
+	shouldBe += " | time=0.250000s;1.000000;5.000000;0.000000"
+	if v := r.output(true, time.Second, 5*time.Second); v != shouldBe {
+		t.Errorf("expected %q, got %q", shouldBe, v)
+	}
+}
+
+func TestRunCheck(t *testing.T) {
+	srv := mattermosttest.NewServer()
+	defer srv.Close()
+	member := srv.AddChannel("town-square")
+	other := srv.AddChannel("off-topic")
+	srv.SetChannelMember(other.ID, srv.Me().ID, false)
+
+	oldURL, oldToken := viper.Get("url"), viper.Get("access-token")
+	defer func() {
+		viper.Set("url", oldURL)
+		viper.Set("access-token", oldToken)
+	}()
+	viper.Set("url", srv.URL)
+	viper.Set("access-token", srv.Token)
+
+	var opts config.Options
+
+	t.Run("ok", func(t *testing.T) {
+		r := runCheck(member.ID, true, time.Minute, 2*time.Minute, opts)
+		if r.Status != checkOK || r.Version != mattermosttest.DefaultVersion {
+			t.Fatalf("unexpected result %+v", r)
+		}
+		if v := strings.Join(r.Messages, ", "); !strings.Contains(v, "token of @bot is valid, @bot is a member of "+member.ID) {
+			t.Error("unexpected messages:", v)
+		}
+	})
+
+	t.Run("not a member", func(t *testing.T) {
+		r := runCheck(other.ID, false, time.Minute, 2*time.Minute, opts)
+		if r.Status != checkCrit {
+			t.Errorf("unexpected result %+v", r)
+		}
+	})
+
+	t.Run("direct channel", func(t *testing.T) {
+		srv.AddUser("alice")
+		r := runCheck("@alice", false, time.Minute, 2*time.Minute, opts)
+		if r.Status != checkOK || !strings.Contains(strings.Join(r.Messages, ", "), "@alice exists, no direct channel with @bot yet") {
+			t.Errorf("unexpected result %+v", r)
+		}
+		for _, req := range srv.Requests() {
+			if req.Method != http.MethodGet {
+				t.Errorf("unexpected request %s %s", req.Method, req.Path)
+			}
+		}
+
+		if _, err := getChannelID("@alice", opts); err != nil {
+			t.Fatal(err)
+		}
+		r = runCheck("@alice", false, time.Minute, 2*time.Minute, opts)
+		if r.Status != checkOK || !strings.Contains(strings.Join(r.Messages, ", "), "@bot is a member of @alice") {
+			t.Errorf("unexpected result %+v", r)
+		}
+
+		r = runCheck("@nobody", false, time.Minute, 2*time.Minute, opts)
+		if r.Status != checkCrit {
+			t.Errorf("unexpected result %+v", r)
+		}
+	})
+
+	t.Run("latency", func(t *testing.T) {
+		r := runCheck("", false, time.Nanosecond, time.Minute, opts)
+		if r.Status != checkWarn {
+			t.Errorf("unexpected result %+v", r)
+		}
+		r = runCheck("", false, time.Nanosecond, time.Nanosecond, opts)
+		if r.Status != checkCrit {
+			t.Errorf("unexpected result %+v", r)
+		}
+	})
+
+	t.Run("degraded", func(t *testing.T) {
+		defer func() { mattermostDo = mattermost.Do }()
+		mattermostDo = func(method, endpoint string, payload io.Reader, opts config.Options) (*mattermost.Response, error) {
+			return &mattermost.Response{
+				StatusCode: http.StatusOK,
+				Header:     http.Header{},
+				Data:       map[string]interface{}{"status": "OK", "database_status": "UNHEALTHY"},
+			}, nil
+		}
+		r := runCheck("", true, time.Minute, 2*time.Minute, opts)
+		if r.Status != checkWarn || !strings.Contains(strings.Join(r.Messages, ", "), "database_status is UNHEALTHY") {
+			t.Errorf("unexpected result %+v", r)
+		}
+	})
+
+	t.Run("invalid token", func(t *testing.T) {
+		viper.Set("access-token", "wrong")
+		defer viper.Set("access-token", srv.Token)
+		r := runCheck("", false, time.Minute, 2*time.Minute, opts)
+		if r.Status != checkCrit || r.Messages[len(r.Messages)-1] != "the access token is invalid" {
+			t.Errorf("unexpected result %+v", r)
+		}
+	})
+
+	t.Run("unreachable", func(t *testing.T) {
+		srv.SetError("GET", "/api/v4/system/ping", http.StatusServiceUnavailable)
+		defer srv.SetError("GET", "/api/v4/system/ping", 0)
+		r := runCheck("", false, time.Minute, 2*time.Minute, opts)
+		if r.Status != checkCrit {
+			t.Errorf("unexpected result %+v", r)
+		}
+	})
+
+	t.Run("unknown", func(t *testing.T) {
+		viper.Set("url", "")
+		defer viper.Set("url", srv.URL)
+		r := runCheck("", false, time.Minute, 2*time.Minute, opts)
+		if r.Status != checkUnknown {
+			t.Errorf("unexpected result %+v", r)
+		}
+	})
+}
@@ -0,0 +1,118 @@
+/*
+  Copyright 2026 Davide Madrisan <d.madrisan@proton.me>
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+      http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/madrisan/go-mattermost-notify/state"
+)
+
+// dedupEntry records the last message sent with a deduplication key.
+type dedupEntry struct {
+	// Sent is the time the last message has been sent.
+	Sent time.Time `json:"sent"`
+	// Window is the period following Sent during which the duplicates are suppressed.
+	Window time.Duration `json:"window"`
+	// Suppressed is the number of duplicates suppressed since the last message has been sent.
+	Suppressed int `json:"suppressed"`
+}
+
+// dedupFilter suppresses the messages sent with the same key during a time window.
+// Its state is saved in a state file, so that it is shared by concurrent processes.
+type dedupFilter struct {
+	file *state.File
+	now  func() time.Time
+}
+
+// newDedupFilter returns a dedupFilter saving its state in the given file.
+func newDedupFilter(file *state.File) *dedupFilter {
+	return &dedupFilter{file: file, now: time.Now}
+}
+
+// check tells whether the message with the given key must be suppressed because another one
+// has been sent during the last window. When the message is not suppressed, it is recorded as
+// sent and the number of duplicates suppressed since the previous message is returned, along
+// with the previous entry needed by restore.
+func (d *dedupFilter) check(key string, window time.Duration) (bool, int, dedupEntry, error) {
+	var entries map[string]dedupEntry
+	var suppress bool
+	var previous dedupEntry
+
+	err := d.file.Update(&entries, func() error {
+		now := d.now()
+		if entries == nil {
+			entries = make(map[string]dedupEntry)
+		}
+
+		// Forget the keys not seen for a while.
+		for k, e := range entries {
+			if e.Suppressed == 0 && now.Sub(e.Sent) > e.Window {
+				delete(entries, k)
+			}
+		}
+
+		entry, found := entries[key]
+		if found && now.Sub(entry.Sent) < window {
+			entry.Suppressed++
+			entries[key] = entry
+			suppress = true
+			return nil
+		}
+
+		previous = entry
+		entries[key] = dedupEntry{Sent: now, Window: window}
+		return nil
+	})
+	if err != nil {
+		return false, 0, previous, fmt.Errorf("deduplication failure: %v", err)
+	}
+
+	return suppress, previous.Suppressed, previous, nil
+}
+
+// restore reverts the effect of check on a message that has not been sent after all,
+// keeping the duplicates suppressed in the meantime by the concurrent processes.
+func (d *dedupFilter) restore(key string, previous dedupEntry) error {
+	var entries map[string]dedupEntry
+
+	return d.file.Update(&entries, func() error {
+		if entries == nil {
+			entries = make(map[string]dedupEntry)
+		}
+		entry := entries[key]
+		if previous.Sent.IsZero() && entry.Suppressed == 0 {
+			delete(entries, key)
+			return nil
+		}
+		entries[key] = dedupEntry{
+			Sent:       previous.Sent,
+			Window:     previous.Window,
+			Suppressed: previous.Suppressed + entry.Suppressed,
+		}
+		return nil
+	})
+}
+
+// getRepeatedNote returns the note added to a message following n suppressed duplicates.
+func getRepeatedNote(n int) string {
+	if n == 1 {
+		return "_(repeated 1 time)_"
+	}
+	return fmt.Sprintf("_(repeated %d times)_", n)
+}
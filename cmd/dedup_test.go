@@ -0,0 +1,82 @@
+/*
+  Copyright 2026 Davide Madrisan <d.madrisan@proton.me>
+
+  Licensed under the Mozilla Public License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+      https://www.mozilla.org/en-US/MPL/2.0/
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+*/
+
+package cmd
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/madrisan/go-mattermost-notify/state"
+)
+
+func TestDedupFilter(t *testing.T) {
+	var now = time.Date(2026, 10, 19, 10, 0, 0, 0, time.UTC)
+
+	d := newDedupFilter(state.New(filepath.Join(t.TempDir(), "dedup.json")))
+	d.now = func() time.Time { return now }
+
+	steps := []struct {
+		elapsed          time.Duration
+		suppressShouldBe bool
+		repeatedShouldBe int
+	}{
+		{0, false, 0},
+		{time.Minute, true, 0},
+		{2 * time.Minute, true, 0},
+		{11 * time.Minute, false, 2},
+		{12 * time.Minute, true, 0},
+		{30 * time.Minute, false, 1},
+		{50 * time.Minute, false, 0},
+	}
+
+	for i, step := range steps {
+		now = now.Add(step.elapsed - steps[max(i-1, 0)].elapsed)
+		suppress, repeated, _, err := d.check("disk-var", 10*time.Minute)
+		if err != nil {
+			t.Fatal("check has failed:", err)
+		}
+		if suppress != step.suppressShouldBe || repeated != step.repeatedShouldBe {
+			t.Errorf("step %d: expected (%v, %d), got (%v, %d)",
+				i, step.suppressShouldBe, step.repeatedShouldBe, suppress, repeated)
+		}
+	}
+
+	// A message that cannot be sent must not start a new window.
+	now = now.Add(time.Hour)
+	_, _, previous, err := d.check("disk-var", 10*time.Minute)
+	if err != nil {
+		t.Fatal("check has failed:", err)
+	}
+	if err := d.restore("disk-var", previous); err != nil {
+		t.Fatal("restore has failed:", err)
+	}
+	if suppress, _, _, _ := d.check("disk-var", 10*time.Minute); suppress {
+		t.Error("the message should not be suppressed after a restore")
+	}
+}
+
+func TestGetRepeatedNote(t *testing.T) {
+	t.Parallel()
+
+	if v := getRepeatedNote(1); v != "_(repeated 1 time)_" {
+		t.Error("unexpected note", v)
+	}
+	if v := getRepeatedNote(5); v != "_(repeated 5 times)_" {
+		t.Error("unexpected note", v)
+	}
+}
@@ -0,0 +1,375 @@
+/*
+  Copyright 2026 Davide Madrisan <d.madrisan@proton.me>
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+      http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+*/
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	mattermost "github.com/madrisan/go-mattermost-notify/mattermost"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/madrisan/go-mattermost-notify/config"
+	"github.com/madrisan/go-mattermost-notify/state"
+)
+
+var (
+	// digestName is the name of the digest the post must be queued in.
+	digestName string
+	// digestInterval is the time between two flushes of the digests by 'digest run'.
+	digestInterval time.Duration
+	// digestMaxEvents is the maximum number of events listed in a digest post.
+	digestMaxEvents int
+)
+
+// digestNameRegexp matches the valid digest names.
+var digestNameRegexp = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+// digestFilePrefix is the prefix of the state files containing the digest queues.
+const digestFilePrefix = "digest-"
+
+// digestEvent is a message queued in a digest.
+type digestEvent struct {
+	Time         time.Time `json:"time"`
+	Destinations []string  `json:"destinations"`
+	Author       string    `json:"author"`
+	Level        string    `json:"level"`
+	Title        string    `json:"title"`
+	Text         string    `json:"text"`
+}
+
+// levelPriority orders the message levels from the most to the least important.
+var levelPriority = map[string]int{
+	"critical": 0,
+	"warning":  1,
+	"info":     2,
+	"success":  3,
+}
+
+// getLevelPriority returns the priority of the given level, unknown levels being the least important.
+func getLevelPriority(level string) int {
+	if p, found := levelPriority[level]; found {
+		return p
+	}
+	return len(levelPriority)
+}
+
+// getDigestQueue returns the state file containing the queue of the digest with the given name.
+func getDigestQueue(name string) (*digestQueue, error) {
+	if !digestNameRegexp.MatchString(name) {
+		return nil, fmt.Errorf("invalid digest name \"%s\"", name)
+	}
+	file, err := getStateFile(digestFilePrefix + name)
+	if err != nil {
+		return nil, err
+	}
+	return &digestQueue{name: name, file: file}, nil
+}
+
+// getDigestNames returns the names of the digests having a queue in the state directory.
+func getDigestNames() ([]string, error) {
+	dir, err := getStateDir()
+	if err != nil {
+		return nil, err
+	}
+
+	paths, err := filepath.Glob(filepath.Join(dir, digestFilePrefix+"*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, path := range paths {
+		name := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(path), digestFilePrefix), ".json")
+		if digestNameRegexp.MatchString(name) {
+			names = append(names, name)
+		}
+	}
+	return names, nil
+}
+
+// escapeTableCell makes the given text fit in a cell of a markdown table.
+func escapeTableCell(text string) string {
+	text = strings.ReplaceAll(text, "|", "\\|")
+	text = strings.ReplaceAll(text, "\r", "")
+	return strings.ReplaceAll(strings.TrimSpace(text), "\n", "<br>")
+}
+
+// renderDigest returns the level and the markdown text of the digest of the given events:
+// a table of the events grouped by level, most important first.
+func renderDigest(events []digestEvent, maxEvents int) (string, string) {
+	sorted := append([]digestEvent(nil), events...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		pi, pj := getLevelPriority(sorted[i].Level), getLevelPriority(sorted[j].Level)
+		if pi != pj {
+			return pi < pj
+		}
+		return sorted[i].Time.Before(sorted[j].Time)
+	})
+
+	var level = "info"
+	if len(sorted) > 0 && getLevelPriority(sorted[0].Level) < getLevelPriority(level) {
+		level = sorted[0].Level
+	}
+
+	var b strings.Builder
+	b.WriteString("| Time | Level | Author | Title | Message |\n")
+	b.WriteString("|:-----|:------|:-------|:------|:--------|\n")
+	for i, e := range sorted {
+		if maxEvents > 0 && i == maxEvents {
+			fmt.Fprintf(&b, "\n_%d more events have been omitted_", len(sorted)-maxEvents)
+			break
+		}
+		fmt.Fprintf(&b, "| %s | %s | %s | %s | %s |\n",
+			e.Time.Local().Format("2006-01-02 15:04:05"),
+			escapeTableCell(e.Level),
+			escapeTableCell(e.Author),
+			escapeTableCell(e.Title),
+			escapeTableCell(e.Text))
+	}
+
+	return level, strings.TrimRight(b.String(), "\n")
+}
+
+// digestQueue is the queue of the events of a digest, saved in a state file.
+type digestQueue struct {
+	name string
+	file *state.File
+}
+
+// push appends an event to the queue.
+func (q *digestQueue) push(e digestEvent) error {
+	var events []digestEvent
+	return q.file.Update(&events, func() error {
+		events = append(events, e)
+		return nil
+	})
+}
+
+// take removes all the events from the queue and returns them.
+func (q *digestQueue) take() ([]digestEvent, error) {
+	var events, taken []digestEvent
+	err := q.file.Update(&events, func() error {
+		taken, events = events, nil
+		return nil
+	})
+	return taken, err
+}
+
+// requeue puts back at the head of the queue the events that could not be posted.
+func (q *digestQueue) requeue(failed []digestEvent) error {
+	var events []digestEvent
+	return q.file.Update(&events, func() error {
+		events = append(append([]digestEvent(nil), failed...), events...)
+		return nil
+	})
+}
+
+// flush posts a digest of the queued events to each destination and clears the queue.
+// The events that cannot be posted are put back in the queue.
+func (q *digestQueue) flush(maxEvents int, opts config.Options) (int, error) {
+	events, err := q.take()
+	if err != nil || len(events) == 0 {
+		return 0, err
+	}
+
+	var byDestination = make(map[string][]digestEvent)
+	var destinations []string
+	for _, e := range events {
+		for _, dest := range e.Destinations {
+			if _, found := byDestination[dest]; !found {
+				destinations = append(destinations, dest)
+			}
+			byDestination[dest] = append(byDestination[dest], e)
+		}
+	}
+
+	var failedDestinations = make(map[string]bool)
+	var errs []string
+	for _, dest := range destinations {
+		level, text := renderDigest(byDestination[dest], maxEvents)
+		msg := message{
+			Author: "digest",
+			Level:  level,
+			Text:   text,
+			Title:  fmt.Sprintf("Digest %s: %d events", q.name, len(byDestination[dest])),
+		}
+		if _, err := postMessage(dest, msg, false, opts); err != nil {
+			failedDestinations[dest] = true
+			errs = append(errs, fmt.Sprintf("%s: %v", dest, err))
+		}
+	}
+
+	if len(errs) == 0 {
+		return len(events), nil
+	}
+
+	// Put back the events, but only for the destinations they could not be posted to.
+	var requeued []digestEvent
+	for _, e := range events {
+		var pending []string
+		for _, dest := range e.Destinations {
+			if failedDestinations[dest] {
+				pending = append(pending, dest)
+			}
+		}
+		if len(pending) > 0 {
+			e.Destinations = pending
+			requeued = append(requeued, e)
+		}
+	}
+	if err := q.requeue(requeued); err != nil {
+		errs = append(errs, err.Error())
+	}
+
+	return len(events) - len(requeued),
+		fmt.Errorf("cannot post the digest %s to %s", q.name, strings.Join(errs, "; "))
+}
+
+// queueDigestEvent queues the message in the digest with the given name.
+func queueDigestEvent(name, channel string, msg message) error {
+	destinations, err := getDestinations(channel, msg.Level, msg.Labels, msg.Author)
+	if err != nil {
+		return err
+	}
+
+	queue, err := getDigestQueue(name)
+	if err != nil {
+		return err
+	}
+
+	return queue.push(digestEvent{
+		Time:         time.Now(),
+		Destinations: destinations,
+		Author:       msg.Author,
+		Level:        msg.Level,
+		Title:        msg.Title,
+		Text:         msg.Text,
+	})
+}
+
+// flushDigests flushes the digests with the given names, or all the digests if none is given.
+func flushDigests(names []string, opts config.Options) error {
+	if len(names) == 0 {
+		var err error
+		if names, err = getDigestNames(); err != nil {
+			return err
+		}
+	}
+
+	var errs []string
+	for _, name := range names {
+		queue, err := getDigestQueue(name)
+		if err != nil {
+			return err
+		}
+		n, err := queue.flush(digestMaxEvents, opts)
+		if err != nil {
+			errs = append(errs, err.Error())
+		}
+		if n > 0 && !viper.GetBool("quiet") {
+			fmt.Printf("digest %s: %d events posted\n", name, n)
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("%s", strings.Join(errs, "\n"))
+	}
+	return nil
+}
+
+// digestCmd represents the digest CLI command.
+var digestCmd = &cobra.Command{
+	Use:   "digest",
+	Short: "Post the digests of the queued messages",
+	Long: `Post the digests of the messages queued by 'post --digest NAME'.
+
+A digest is a single post summarizing the queued messages in a table grouped by level.
+It is posted to each destination of the queued messages, which are then removed from the queue.`,
+}
+
+// digestFlushCmd represents the digest flush CLI command.
+var digestFlushCmd = &cobra.Command{
+	Use:   "flush [NAME...]",
+	Short: "Post the digests and clear their queues",
+	Long:  `Post the digests with the given names, or all the digests if no name is given, and clear their queues.`,
+	Example: `  digest flush builds
+  digest flush`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return flushDigests(args, newOptions())
+	},
+}
+
+// digestRunCmd represents the digest run CLI command.
+var digestRunCmd = &cobra.Command{
+	Use:   "run [NAME...]",
+	Short: "Periodically post the digests",
+	Long: `Periodically post the digests with the given names, or all the digests if no name is given.
+The digests are posted a last time on exit.`,
+	Example: `  digest run --interval 15m
+  digest run builds backups`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var opts = newOptions()
+		opts.HTTPClient = mattermost.NewHTTPClient(opts)
+
+		if digestInterval <= 0 {
+			return fmt.Errorf("the interval must be positive")
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		ticker := time.NewTicker(digestInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				if err := flushDigests(args, opts); err != nil {
+					fmt.Fprintln(os.Stderr, "Error:", err)
+				}
+			case <-ctx.Done():
+				return flushDigests(args, opts)
+			}
+		}
+	},
+}
+
+// init initializes the digest command flags.
+func init() {
+	rootCmd.AddCommand(digestCmd)
+	digestCmd.AddCommand(digestFlushCmd)
+	digestCmd.AddCommand(digestRunCmd)
+
+	digestCmd.PersistentFlags().BoolVarP(&mattermostSkipTLSVerify,
+		"insecure", "i", false, "ignore SSL/TLS certificate check")
+	digestCmd.PersistentFlags().IntVar(&digestMaxEvents,
+		"max-events", 100, "the maximum number of events listed in a digest post")
+	digestCmd.PersistentFlags().DurationVarP(&mattermostConnectionTimeout,
+		"timeout", "s", 10*time.Second, "the maximum time in seconds allowed for a Mattermost connection")
+	digestRunCmd.Flags().DurationVar(&digestInterval,
+		"interval", 15*time.Minute, "the time between two digest posts")
+}
@@ -0,0 +1,116 @@
+/*
+  Copyright 2026 Davide Madrisan <d.madrisan@proton.me>
+
+  Licensed under the Mozilla Public License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+      https://www.mozilla.org/en-US/MPL/2.0/
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+*/
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/madrisan/go-mattermost-notify/config"
+	mattermost "github.com/madrisan/go-mattermost-notify/mattermost"
+	"github.com/spf13/viper"
+)
+
+func TestRenderDigest(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	events := []digestEvent{
+		{Time: now, Level: "info", Author: "CI", Title: "Build", Text: "Build #1 | ok"},
+		{Time: now.Add(time.Second), Level: "warning", Author: "CI", Title: "Tests", Text: "flaky\ntest"},
+		{Time: now.Add(2 * time.Second), Level: "success", Author: "CI", Title: "Deploy", Text: "done"},
+	}
+
+	level, text := renderDigest(events, 0)
+	if level != "warning" {
+		t.Error("expected level warning, got", level)
+	}
+
+	lines := strings.Split(text, "\n")
+	if len(lines) != 5 {
+		t.Fatalf("expected 5 lines, got %d: %q", len(lines), text)
+	}
+	if !strings.HasSuffix(lines[2], "| warning | CI | Tests | flaky<br>test |") ||
+		!strings.HasSuffix(lines[3], "| info | CI | Build | Build #1 \\| ok |") ||
+		!strings.HasSuffix(lines[4], "| success | CI | Deploy | done |") {
+		t.Errorf("unexpected digest table %q", text)
+	}
+
+	_, text = renderDigest(events, 2)
+	if !strings.HasSuffix(text, "_1 more events have been omitted_") {
+		t.Errorf("unexpected truncated digest table %q", text)
+	}
+}
+
+func TestDigestFlush(t *testing.T) {
+	oldMattermostPost := mattermostPost
+	oldStateDir := viper.Get("state.dir")
+	defer func() {
+		mattermostPost = oldMattermostPost
+		viper.Set("state.dir", oldStateDir)
+	}()
+	viper.Set("state.dir", t.TempDir())
+
+	var posted []mattermost.MsgPayload
+	var serverDown = true
+	mattermostPost = func(endpoint string, payload io.Reader, opts config.Options) (interface{}, error) {
+		if serverDown {
+			return nil, fmt.Errorf("connection refused")
+		}
+		var data mattermost.MsgPayload
+		if err := json.NewDecoder(payload).Decode(&data); err != nil {
+			return nil, err
+		}
+		posted = append(posted, data)
+		return map[string]interface{}{"id": "post"}, nil
+	}
+
+	for i := 1; i <= 3; i++ {
+		msg := message{Author: "CI", Level: "info", Title: "Build", Text: fmt.Sprintf("Build #%d", i)}
+		if err := queueDigestEvent("builds", "channel1", msg); err != nil {
+			t.Fatal("queueDigestEvent has failed:", err)
+		}
+	}
+
+	queue, err := getDigestQueue("builds")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, err := queue.flush(0, config.Options{}); err == nil || n != 0 {
+		t.Fatal("flush should fail when Mattermost is not reachable", n, err)
+	}
+
+	serverDown = false
+	if n, err := queue.flush(0, config.Options{}); err != nil || n != 3 {
+		t.Fatal("flush has failed:", n, err)
+	}
+	if len(posted) != 1 || posted[0].ID != "channel1" {
+		t.Fatal("expected one digest post to channel1, got", posted)
+	}
+	attachment := posted[0].Properties.Attachments[0]
+	if attachment.Title != "Digest builds: 3 events" || strings.Count(attachment.Text, "| Build |") != 3 {
+		t.Error("unexpected digest post", attachment)
+	}
+
+	if n, err := queue.flush(0, config.Options{}); err != nil || n != 0 {
+		t.Error("the queue should be empty after a flush", n, err)
+	}
+}
@@ -0,0 +1,354 @@
+/*
+  Copyright 2026 Davide Madrisan <d.madrisan@proton.me>
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+      http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	mattermost "github.com/madrisan/go-mattermost-notify/mattermost"
+	"github.com/spf13/cobra"
+)
+
+var (
+	// execNotifyOnStart tells if a message must be posted when the command starts.
+	execNotifyOnStart bool
+	// execNotifyOnlyOnFailure tells if the message must be posted only when the command fails.
+	execNotifyOnlyOnFailure bool
+	// execTailLines is the number of lines of the command output sent in the message.
+	execTailLines int
+	// execStatus is the status of the logged user while the command runs.
+	execStatus string
+	// execStatusEmoji is the emoji of the custom status of the logged user while the command runs.
+	execStatusEmoji string
+	// execStatusText is the text of the custom status of the logged user while the command runs.
+	execStatusText string
+)
+
+// exitCodeCannotRun is the exit code used when the command cannot be run,
+// like the shells do when a command is not found.
+const exitCodeCannotRun = 127
+
+// maxPartialLine is the maximum size in bytes of an output line kept by tailWriter.
+const maxPartialLine = 4096
+
+// tailWriter is an io.Writer keeping the last lines written to it.
+// It is safe for concurrent use, so the standard output and error of a command can share it.
+type tailWriter struct {
+	mu      sync.Mutex
+	max     int
+	lines   []string
+	partial []byte
+}
+
+// newTailWriter returns a tailWriter keeping the last max lines.
+func newTailWriter(max int) *tailWriter {
+	return &tailWriter{max: max}
+}
+
+// Write splits p in lines and keeps the last ones.
+func (t *tailWriter) Write(p []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	data := append(t.partial, p...)
+	for {
+		i := bytes.IndexByte(data, '\n')
+		if i < 0 {
+			break
+		}
+		t.addLine(string(data[:i]))
+		data = data[i+1:]
+	}
+
+	if len(data) > maxPartialLine {
+		data = data[len(data)-maxPartialLine:]
+	}
+	t.partial = append([]byte(nil), data...)
+
+	return len(p), nil
+}
+
+// addLine adds a line to the kept ones, discarding the oldest one if needed.
+func (t *tailWriter) addLine(line string) {
+	if t.max <= 0 {
+		return
+	}
+	if len(t.lines) == t.max {
+		t.lines = t.lines[1:]
+	}
+	t.lines = append(t.lines, strings.TrimRight(line, "\r"))
+}
+
+// String returns the last lines written.
+func (t *tailWriter) String() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	lines := t.lines
+	if len(t.partial) > 0 && t.max > 0 {
+		lines = append(append([]string(nil), lines...), string(t.partial))
+		if len(lines) > t.max {
+			lines = lines[1:]
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// runCommand runs the given command, forwarding to it the interrupt and termination signals.
+// It returns the exit code of the command, 128 plus the signal number when it has been killed
+// by a signal, and its duration. An error is returned only when the command cannot be run.
+func runCommand(args []string, stdout, stderr io.Writer) (int, time.Duration, error) {
+	c := exec.Command(args[0], args[1:]...)
+	c.Stdin = os.Stdin
+	c.Stdout = stdout
+	c.Stderr = stderr
+
+	start := time.Now()
+	if err := c.Start(); err != nil {
+		return exitCodeCannotRun, 0, err
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		for {
+			select {
+			case sig := <-signals:
+				c.Process.Signal(sig)
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	err := c.Wait()
+	duration := time.Since(start)
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		code := exitErr.ExitCode()
+		if ws, ok := exitErr.Sys().(syscall.WaitStatus); ok && ws.Signaled() {
+			// The command has been killed by a signal: report it like the shells do.
+			code = 128 + int(ws.Signal())
+		} else if code < 0 {
+			code = 1
+		}
+		return code, duration, nil
+	}
+	if err != nil {
+		return 1, duration, err
+	}
+
+	return 0, duration, nil
+}
+
+// getCommandLine returns the given command and its arguments quoted if needed.
+func getCommandLine(args []string) string {
+	var quoted = make([]string, len(args))
+	for i, arg := range args {
+		if arg == "" || strings.ContainsAny(arg, " \t\n\"'`$\\|&;<>()*?[]#~") {
+			arg = strconv.Quote(arg)
+		}
+		quoted[i] = arg
+	}
+	return strings.Join(quoted, " ")
+}
+
+// escapeCodeBlock returns the given text ready to be put in a markdown code block,
+// preventing it from closing the block.
+func escapeCodeBlock(text string) string {
+	return strings.ReplaceAll(text, "```", "` ` `")
+}
+
+// getExecReport returns the level and the markdown text of the message reporting the outcome of a command.
+func getExecReport(commandLine string, exitCode int, runErr error, output string) (string, string) {
+	var level = "success"
+	var text string
+
+	switch {
+	case runErr != nil:
+		level = "critical"
+		text = fmt.Sprintf("The command `%s` cannot be run: %v", commandLine, runErr)
+	case exitCode != 0:
+		level = "critical"
+		text = fmt.Sprintf("The command `%s` has failed with exit code %d.", commandLine, exitCode)
+	default:
+		text = fmt.Sprintf("The command `%s` has completed successfully.", commandLine)
+	}
+
+	if output != "" {
+		text += "\n```\n" + escapeCodeBlock(output) + "\n```"
+	}
+
+	return level, text
+}
+
+// execCmd represents the exec CLI command.
+var execCmd = &cobra.Command{
+	Use:   "exec [flags] -- COMMAND [ARGS...]",
+	Short: "Run a command and post its outcome to Mattermost",
+	Long: `Run a command and post to Mattermost a success or critical message, according to
+its exit code, with the host, the exit code, the duration, and the last lines of the
+command output.
+
+The exit code of the command is passed through, so that exec can wrap cron jobs and CI
+steps without changing their behavior.
+
+The status and the custom status of the logged user can be set while the command runs
+(--status, --status-emoji, --status-text): the previous ones are restored at the end.`,
+	Example: `  exec -c rybfbdi9ojy8xxxjjxc88kh3me -t "Nightly backup" -- /usr/local/bin/backup.sh --full
+  exec -c @alice --notify-only-on-failure --tail 50 -- make test
+  exec -c @alice --status dnd --status-emoji rocket --status-text "Deploying v1.2" -- ./deploy.sh v1.2`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var opts = newOptions()
+
+		labels, err := parseLabels(messageLabels)
+		if err != nil {
+			return err
+		}
+
+		hostname, err := os.Hostname()
+		if err != nil {
+			hostname = "unknown"
+		}
+
+		commandLine := getCommandLine(args)
+		msg := message{
+			Author: messageAuthor,
+			Labels: labels,
+			Title:  messageTitle,
+		}
+		if msg.Author == "" {
+			msg.Author = hostname
+		}
+		if msg.Title == "" {
+			msg.Title = commandLine
+		}
+
+		if execNotifyOnStart {
+			start := msg
+			start.Level = "info"
+			start.Text = fmt.Sprintf("The command `%s` has started.", commandLine)
+			start.Options = []mattermost.MsgOption{
+				mattermost.WithFields([]mattermost.MsgField{{Title: "Host", Value: hostname, Short: true}}),
+			}
+			if _, err := postMessage(mattermostChannel, start, spoolOnFailure, opts); err != nil {
+				fmt.Fprintln(os.Stderr, "Error:", err)
+			}
+		}
+
+		var restoreStatus func() error
+		if execStatus != "" || execStatusEmoji != "" || execStatusText != "" {
+			// The command must run even if the status cannot be set.
+			if restoreStatus, err = setStatusWhileRunning(execStatus, execStatusEmoji, execStatusText, opts); err != nil {
+				fmt.Fprintln(os.Stderr, "Error: cannot set the status:", err)
+			}
+		}
+
+		tail := newTailWriter(execTailLines)
+		exitCode, duration, runErr := runCommand(args,
+			io.MultiWriter(os.Stdout, tail),
+			io.MultiWriter(os.Stderr, tail))
+		if runErr != nil {
+			fmt.Fprintln(os.Stderr, "Error:", runErr)
+		}
+
+		if restoreStatus != nil {
+			if err := restoreStatus(); err != nil {
+				fmt.Fprintln(os.Stderr, "Error:", err)
+			}
+		}
+
+		if exitCode != 0 || !execNotifyOnlyOnFailure {
+			msg.Level, msg.Text = getExecReport(commandLine, exitCode, runErr, tail.String())
+			if messageContent != "" {
+				msg.Text = messageContent + "\n\n" + msg.Text
+			}
+			msg.Options = []mattermost.MsgOption{
+				mattermost.WithFields([]mattermost.MsgField{
+					{Title: "Host", Value: hostname, Short: true},
+					{Title: "Exit code", Value: strconv.Itoa(exitCode), Short: true},
+					{Title: "Duration", Value: duration.Round(time.Millisecond).String(), Short: true},
+				}),
+			}
+
+			// A notification failure must not hide the exit code of the command.
+			if _, err := postMessage(mattermostChannel, msg, spoolOnFailure, opts); err != nil {
+				fmt.Fprintln(os.Stderr, "Error:", err)
+			}
+		}
+
+		if exitCode != 0 {
+			return exitWithCode(cmd, exitCode)
+		}
+		return nil
+	},
+}
+
+// init initializes the exec command flags.
+func init() {
+	rootCmd.AddCommand(execCmd)
+
+	// The flags following the command belong to the command.
+	execCmd.Flags().SetInterspersed(false)
+
+	execCmd.Flags().StringVarP(&messageAuthor,
+		"author", "A", "", "author of the message (default is the host name)")
+	execCmd.Flags().StringVarP(&mattermostChannel,
+		"channel", "c", "", "Mattermost channel ID or username. Example: rybfbdi9ojy8xxxjjxc88kh3me or @alice")
+	execCmd.Flags().BoolVarP(&mattermostSkipTLSVerify,
+		"insecure", "i", false, "ignore SSL/TLS certificate check")
+	execCmd.Flags().StringArrayVar(&messageLabels,
+		"label", nil, "label in the form key=value used for routing the message (can be repeated)")
+	execCmd.Flags().StringVarP(&messageContent,
+		"message", "m", "", "a (markdown-formatted) text preceding the command report")
+	execCmd.Flags().BoolVar(&execNotifyOnStart,
+		"notify-on-start", false, "post a message when the command starts")
+	execCmd.Flags().BoolVar(&execNotifyOnlyOnFailure,
+		"notify-only-on-failure", false, "post a message only when the command fails")
+	execCmd.Flags().StringVar(&execStatus,
+		"status", "", "the status of the logged user while the command runs: online, away, dnd or offline")
+	execCmd.Flags().StringVar(&execStatusEmoji,
+		"status-emoji", "", "the emoji of the custom status of the logged user while the command runs")
+	execCmd.Flags().StringVar(&execStatusText,
+		"status-text", "", "the text of the custom status of the logged user while the command runs")
+	execCmd.Flags().BoolVar(&spoolOnFailure,
+		"spool-on-failure", false, "save the message in the spool directory when it cannot be posted (see the flush command)")
+	execCmd.Flags().IntVar(&execTailLines,
+		"tail", 20, "the number of lines of the command output sent in the message")
+	execCmd.Flags().DurationVarP(&mattermostConnectionTimeout,
+		"timeout", "s", 10*time.Second, "the maximum time in seconds allowed for a Mattermost connection")
+	execCmd.Flags().StringVarP(&messageTitle,
+		"title", "t", "", "the title of the message (default is the command line)")
+}
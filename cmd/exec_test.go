@@ -0,0 +1,108 @@
+/*
+  Copyright 2026 Davide Madrisan <d.madrisan@proton.me>
+
+  Licensed under the Mozilla Public License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+      https://www.mozilla.org/en-US/MPL/2.0/
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+func TestExecHelperProcess(t *testing.T) {
+	if os.Getenv("GO_WANT_EXEC_HELPER_PROCESS") != "1" {
+		return
+	}
+	if os.Getenv("EXEC_HELPER_KILL") == "1" {
+		p, _ := os.FindProcess(os.Getpid())
+		p.Kill()
+	}
+	for i := 1; i <= 5; i++ {
+		fmt.Printf("line %d\n", i)
+	}
+	fmt.Fprint(os.Stderr, "fatal error")
+	os.Exit(3)
+}
+
+func TestRunCommand(t *testing.T) {
+	t.Setenv("GO_WANT_EXEC_HELPER_PROCESS", "1")
+
+	tail := newTailWriter(3)
+	args := []string{os.Args[0], "-test.run=TestExecHelperProcess"}
+	exitCode, _, err := runCommand(args, tail, tail)
+	if err != nil {
+		t.Fatal("runCommand has failed:", err)
+	}
+	if exitCode != 3 {
+		t.Error("expected exit code 3, got", exitCode)
+	}
+	if v := tail.String(); v != "line 4\nline 5\nfatal error" {
+		t.Errorf("unexpected output tail %q", v)
+	}
+
+	// The commands killed by a signal exit like in the shells.
+	t.Setenv("EXEC_HELPER_KILL", "1")
+	killed := 128 + int(syscall.SIGKILL)
+	exitCode, _, err = runCommand(args, io.Discard, io.Discard)
+	if runtime.GOOS != "windows" && (err != nil || exitCode != killed) {
+		t.Error("expected exit code", killed, "for a killed command, got", exitCode, err)
+	}
+
+	exitCode, _, err = runCommand([]string{"/nonexistent/command"}, io.Discard, io.Discard)
+	if err == nil || exitCode != exitCodeCannotRun {
+		t.Error("expected an error and exit code", exitCodeCannotRun, "got", err, exitCode)
+	}
+}
+
+func TestTailWriter(t *testing.T) {
+	t.Parallel()
+
+	tail := newTailWriter(2)
+	fmt.Fprint(tail, "one\ntwo\r\nth")
+	fmt.Fprint(tail, "ree\nfour\n")
+	if v := tail.String(); v != "three\nfour" {
+		t.Errorf("unexpected output tail %q", v)
+	}
+
+	none := newTailWriter(0)
+	fmt.Fprint(none, "one\ntwo")
+	if v := none.String(); v != "" {
+		t.Errorf("expected an empty output tail, got %q", v)
+	}
+}
+
+func TestGetExecReport(t *testing.T) {
+	t.Parallel()
+
+	commandLine := getCommandLine([]string{"backup.sh", "--path", "/var/lib/my data"})
+	if commandLine != `backup.sh --path "/var/lib/my data"` {
+		t.Error("unexpected command line", commandLine)
+	}
+
+	level, text := getExecReport(commandLine, 0, nil, "")
+	if level != "success" || strings.Contains(text, "```") {
+		t.Error("unexpected report", level, text)
+	}
+
+	level, text = getExecReport(commandLine, 2, nil, "disk full")
+	if level != "critical" || !strings.HasSuffix(text, "exit code 2.\n```\ndisk full\n```") {
+		t.Error("unexpected report", level, text)
+	}
+}
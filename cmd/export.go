@@ -0,0 +1,510 @@
+/*
+  Copyright 2026 Davide Madrisan <d.madrisan@proton.me>
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+      http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+*/
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/madrisan/go-mattermost-notify/config"
+)
+
+var (
+	// exportAttachments tells if the message attachments and the file names must be exported.
+	exportAttachments bool
+	// exportFormat is the format of the export: jsonl, markdown or html.
+	exportFormat string
+	// exportOutput is the file the export is written to, the standard output if empty.
+	exportOutput string
+	// exportSince is the time of the oldest posts exported.
+	exportSince string
+	// exportThreads tells if the replies to the posts must be exported.
+	exportThreads bool
+	// exportUntil is the time of the most recent posts exported.
+	exportUntil string
+)
+
+// postsPerPage is the number of posts requested per page to Mattermost.
+const postsPerPage = 200
+
+// exportedPost is a post as written by the export and search commands.
+type exportedPost struct {
+	ID        string `json:"id"`
+	RootID    string `json:"root_id,omitempty"`
+	ChannelID string `json:"channel_id"`
+	Channel   string `json:"channel,omitempty"`
+	// Time is the creation time of the post, in the local time zone.
+	Time        time.Time            `json:"time"`
+	UserID      string               `json:"user_id"`
+	Username    string               `json:"username"`
+	Message     string               `json:"message"`
+	Attachments []exportedAttachment `json:"attachments,omitempty"`
+	Files       []string             `json:"files,omitempty"`
+}
+
+// exportedAttachment is a message attachment of an exported post.
+type exportedAttachment struct {
+	Title  string          `json:"title,omitempty"`
+	Text   string          `json:"text,omitempty"`
+	Fields []exportedField `json:"fields,omitempty"`
+}
+
+// exportedField is a field of a message attachment.
+type exportedField struct {
+	Title string `json:"title"`
+	Value string `json:"value"`
+}
+
+// parseDay returns the time described by s, an RFC3339 time or a local date (2006-01-02).
+// A date is the start of the day, or the start of the next day when endOfDay is set, so that
+// the whole day is included in a range ending at it.
+func parseDay(s string, endOfDay bool) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	t, err := time.ParseInLocation("2006-01-02", s, time.Local)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %q: expected a date (2006-01-02) or an RFC3339 time", s)
+	}
+	if endOfDay {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t, nil
+}
+
+// getTimeRange returns the times parsed from the given since and until flags of the export and
+// search commands, zero when not set. The range includes since and excludes until.
+func getTimeRange(since, until string) (time.Time, time.Time, error) {
+	var from, to time.Time
+	var err error
+	if since != "" {
+		if from, err = parseDay(since, false); err != nil {
+			return from, to, err
+		}
+	}
+	if until != "" {
+		if to, err = parseDay(until, true); err != nil {
+			return from, to, err
+		}
+	}
+	if !from.IsZero() && !to.IsZero() && !from.Before(to) {
+		return from, to, fmt.Errorf("the time range from %s to %s is empty", since, until)
+	}
+	return from, to, nil
+}
+
+// getPostList returns the posts of a Mattermost post list, in the order of the list.
+func getPostList(response interface{}) []map[string]interface{} {
+	list, _ := response.(map[string]interface{})
+	order, _ := list["order"].([]interface{})
+	posts, _ := list["posts"].(map[string]interface{})
+
+	var result []map[string]interface{}
+	for _, id := range order {
+		postID, _ := id.(string)
+		if post, ok := posts[postID].(map[string]interface{}); ok {
+			result = append(result, post)
+		}
+	}
+	return result
+}
+
+// isSystemPost tells if the given post is a system message, like a user joining the channel.
+func isSystemPost(post map[string]interface{}) bool {
+	postType, _ := getKV(post, "type")
+	return strings.HasPrefix(postType, "system_")
+}
+
+// newExportedPost returns the given Mattermost post ready to be exported. The usernames are set later.
+// The message attachments and the names of the attached files are added when attachments is set.
+func newExportedPost(post map[string]interface{}, attachments bool, opts config.Options) exportedPost {
+	var p exportedPost
+	p.ID, _ = getKV(post, "id")
+	p.RootID, _ = getKV(post, "root_id")
+	p.ChannelID, _ = getKV(post, "channel_id")
+	p.UserID, _ = getKV(post, "user_id")
+	p.Message, _ = getKV(post, "message")
+	p.Time = getMillis(post, "create_at").Local()
+
+	if !attachments {
+		return p
+	}
+
+	props, _ := post["props"].(map[string]interface{})
+	list, _ := props["attachments"].([]interface{})
+	for _, a := range list {
+		attachment, _ := a.(map[string]interface{})
+		var exported exportedAttachment
+		exported.Title, _ = getKV(attachment, "title")
+		exported.Text, _ = getKV(attachment, "text")
+		fields, _ := attachment["fields"].([]interface{})
+		for _, f := range fields {
+			field, _ := f.(map[string]interface{})
+			title, _ := getKV(field, "title")
+			value, _ := field["value"].(string)
+			exported.Fields = append(exported.Fields, exportedField{Title: title, Value: value})
+		}
+		p.Attachments = append(p.Attachments, exported)
+	}
+
+	fileIDs, _ := post["file_ids"].([]interface{})
+	for _, f := range fileIDs {
+		fileID, _ := f.(string)
+		name := fileID
+		if response, err := mattermostGet("/files/"+fileID+"/info", opts); err == nil {
+			if n, err := getKV(response, "name"); err == nil {
+				name = n
+			}
+		}
+		p.Files = append(p.Files, name)
+	}
+	return p
+}
+
+// setUsernames sets the usernames of the given posts, looking up the users at once.
+func setUsernames(posts []exportedPost, opts config.Options) error {
+	var names = make(map[string]string)
+	var ids []string
+	for _, p := range posts {
+		if _, found := names[p.UserID]; !found {
+			names[p.UserID] = p.UserID
+			ids = append(ids, p.UserID)
+		}
+	}
+	if err := getUsernames(names, ids, opts); err != nil {
+		return err
+	}
+	for i := range posts {
+		posts[i].Username = names[posts[i].UserID]
+	}
+	return nil
+}
+
+// isPostBefore tells if the post of a channel at the given offset, counted from the newest post,
+// has been created before the given time. The offsets past the oldest post are before any time.
+func isPostBefore(channelID string, offset int, t time.Time, opts config.Options) (bool, error) {
+	endpoint := fmt.Sprintf("/channels/%s/posts?page=%d&per_page=1", channelID, offset)
+	response, err := mattermostGet(endpoint, opts)
+	if err != nil {
+		return false, err
+	}
+	posts := getPostList(response)
+	return len(posts) == 0 || getMillis(posts[0], "create_at").Before(t), nil
+}
+
+// findFirstPageBefore returns the first page of the posts of a channel, listed newest first,
+// containing a post created before the given time. The offset of this post is found by an
+// exponential search followed by a binary search, so that the recent posts are not all listed.
+func findFirstPageBefore(channelID string, t time.Time, opts config.Options) (int, error) {
+	// The post at the offset low is not before t, the one at the offset high is.
+	var low, high = -1, 0
+	for {
+		before, err := isPostBefore(channelID, high, t, opts)
+		if err != nil {
+			return 0, err
+		}
+		if before {
+			break
+		}
+		low, high = high, 2*high+1
+	}
+	for high-low > 1 {
+		middle := low + (high-low)/2
+		before, err := isPostBefore(channelID, middle, t, opts)
+		if err != nil {
+			return 0, err
+		}
+		if before {
+			high = middle
+		} else {
+			low = middle
+		}
+	}
+	return high / postsPerPage, nil
+}
+
+// getChannelHistory returns the posts of a channel created in the given time range (zero times
+// for no limits), oldest first. The replies are included only when threads is set, after their
+// root post, even if created after the end of the time range.
+func getChannelHistory(channelID string, since, until time.Time, threads, attachments bool,
+	opts config.Options) ([]exportedPost, error) {
+	var roots []map[string]interface{}
+
+	// The posts are listed newest first, from the first page before the end of the time range.
+	var firstPage int
+	if !until.IsZero() {
+		var err error
+		if firstPage, err = findFirstPageBefore(channelID, until, opts); err != nil {
+			return nil, err
+		}
+	}
+	for page, done := firstPage, false; !done; page++ {
+		endpoint := fmt.Sprintf("/channels/%s/posts?page=%d&per_page=%d", channelID, page, postsPerPage)
+		response, err := mattermostGet(endpoint, opts)
+		if err != nil {
+			return nil, err
+		}
+		posts := getPostList(response)
+		done = len(posts) < postsPerPage
+
+		for _, post := range posts {
+			createAt := getMillis(post, "create_at")
+			if !since.IsZero() && createAt.Before(since) {
+				done = true
+				break
+			}
+			rootID, _ := getKV(post, "root_id")
+			if rootID != "" || isSystemPost(post) || (!until.IsZero() && !createAt.Before(until)) {
+				continue
+			}
+			roots = append(roots, post)
+		}
+	}
+
+	var history []exportedPost
+	for i := len(roots) - 1; i >= 0; i-- {
+		root := newExportedPost(roots[i], attachments, opts)
+		history = append(history, root)
+		if !threads {
+			continue
+		}
+
+		response, err := mattermostGet("/posts/"+root.ID+"/thread", opts)
+		if err != nil {
+			return nil, fmt.Errorf("cannot get the thread of the post %s: %v", root.ID, err)
+		}
+		var replies []exportedPost
+		for _, post := range getPostList(response) {
+			if rootID, _ := getKV(post, "root_id"); rootID == root.ID && !isSystemPost(post) {
+				replies = append(replies, newExportedPost(post, attachments, opts))
+			}
+		}
+		sort.Slice(replies, func(i, j int) bool { return replies[i].Time.Before(replies[j].Time) })
+		history = append(history, replies...)
+	}
+
+	if err := setUsernames(history, opts); err != nil {
+		return nil, err
+	}
+	return history, nil
+}
+
+// writeJSONLines writes the given posts in the JSON Lines format.
+func writeJSONLines(w io.Writer, posts []exportedPost) error {
+	encoder := json.NewEncoder(w)
+	for _, p := range posts {
+		if err := encoder.Encode(p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// exportTimeFormat is the format of the times in the Markdown and HTML exports.
+const exportTimeFormat = "2006-01-02 15:04"
+
+// writeMarkdown writes the given posts of the channel with the given title as a Markdown document.
+// The replies are headed by an arrow.
+func writeMarkdown(w io.Writer, title string, posts []exportedPost) error {
+	fmt.Fprintf(w, "# %s\n\nExported on %s: %d post(s).\n", title, time.Now().Format(exportTimeFormat), len(posts))
+	for _, p := range posts {
+		heading := "##"
+		if p.RootID != "" {
+			heading = "### ↳"
+		}
+		fmt.Fprintf(w, "\n%s @%s, %s\n", heading, p.Username, p.Time.Format(exportTimeFormat))
+		if p.Message != "" {
+			fmt.Fprintf(w, "\n%s\n", p.Message)
+		}
+		for _, a := range p.Attachments {
+			fmt.Fprintln(w)
+			if a.Title != "" {
+				fmt.Fprintf(w, "> **%s**\n", a.Title)
+			}
+			for _, line := range strings.Split(a.Text, "\n") {
+				if line != "" || a.Text != "" {
+					fmt.Fprintf(w, "> %s\n", line)
+				}
+			}
+			for _, f := range a.Fields {
+				fmt.Fprintf(w, "> *%s*: %s\n", f.Title, f.Value)
+			}
+		}
+		if len(p.Files) > 0 {
+			fmt.Fprintf(w, "\nFiles: %s\n", strings.Join(p.Files, ", "))
+		}
+	}
+	return nil
+}
+
+// transcriptTemplate is the template of the standalone HTML transcript.
+var transcriptTemplate = template.Must(template.New("transcript").Funcs(template.FuncMap{
+	"format": func(t time.Time) string { return t.Format(exportTimeFormat) },
+	"join":   strings.Join,
+	"rfc3339": func(t time.Time) string {
+		return t.Format(time.RFC3339)
+	},
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: sans-serif; max-width: 60em; margin: 2em auto; color: #3d3c40; }
+.meta { color: #888; }
+.post { border-top: 1px solid #ddd; padding: 0.5em 0; }
+.reply { margin-left: 2em; border-left: 3px solid #ddd; padding-left: 1em; }
+.user { font-weight: bold; }
+time { color: #888; margin-left: 0.5em; }
+.message { white-space: pre-wrap; margin-top: 0.3em; }
+.attachment { border-left: 4px solid #2389d7; margin: 0.5em 0; padding-left: 0.8em; white-space: pre-wrap; }
+.files { color: #555; font-size: 0.9em; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<p class="meta">Exported on {{format .Exported}}: {{len .Posts}} post(s).</p>
+{{range .Posts}}<div class="post{{if .RootID}} reply{{end}}" id="{{.ID}}">
+<span class="user">@{{.Username}}</span><time datetime="{{rfc3339 .Time}}">{{format .Time}}</time>
+{{if .Message}}<div class="message">{{.Message}}</div>
+{{end}}{{range .Attachments}}<div class="attachment">{{if .Title}}<strong>{{.Title}}</strong>
+{{end}}{{.Text}}{{range .Fields}}
+<em>{{.Title}}</em>: {{.Value}}{{end}}</div>
+{{end}}{{if .Files}}<div class="files">Files: {{join .Files ", "}}</div>
+{{end}}</div>
+{{end}}</body>
+</html>
+`))
+
+// writeHTML writes the given posts of the channel with the given title as a standalone HTML transcript.
+func writeHTML(w io.Writer, title string, posts []exportedPost) error {
+	return transcriptTemplate.Execute(w, struct {
+		Title    string
+		Exported time.Time
+		Posts    []exportedPost
+	}{title, time.Now(), posts})
+}
+
+// exportCmd represents the export CLI command.
+var exportCmd = &cobra.Command{
+	Use:   "export CHANNEL",
+	Short: "Export the history of a channel",
+	Long: `Export the posts of a channel, oldest first, in the JSON Lines format, as a Markdown
+document, or as a standalone HTML transcript. The usernames are resolved and the times
+are in the local time zone.
+
+The channel is an ID, a channel name (with or without the leading '~') of the team set
+by --team, or a @username for an existing direct channel. The replies are exported only
+with --threads, and the message attachments and the names of the attached files only with
+--attachments. The --since and --until times are dates (2006-01-02, whole days included)
+or RFC3339 times.`,
+	Example: `  export --team ops deploys --since 2026-09-01 --until 2026-09-30 --format markdown -o deploys.md
+  export --team ops incident-db --threads --attachments --format html -o incident-db.html
+  export @alice --format jsonl`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var opts = newOptions()
+
+		var write func(w io.Writer, title string, posts []exportedPost) error
+		switch exportFormat {
+		case "jsonl":
+			write = func(w io.Writer, _ string, posts []exportedPost) error { return writeJSONLines(w, posts) }
+		case "markdown":
+			write = writeMarkdown
+		case "html":
+			write = writeHTML
+		default:
+			return fmt.Errorf("invalid format %q (must be jsonl, markdown or html)", exportFormat)
+		}
+		since, until, err := getTimeRange(exportSince, exportUntil)
+		if err != nil {
+			return err
+		}
+
+		// The direct channel is not created when exporting the history of a user.
+		var channelID string
+		if strings.HasPrefix(args[0], "@") {
+			channelID, err = findDirectChannelID(args[0], opts)
+			if err == nil && channelID == "" {
+				err = fmt.Errorf("no direct channel with %s", args[0])
+			}
+		} else {
+			channelID, err = resolveChannelID(args[0], mattermostTeam, true, opts)
+		}
+		if err != nil {
+			return err
+		}
+		response, err := mattermostGet("/channels/"+channelID, opts)
+		if err != nil {
+			return fmt.Errorf("cannot get the channel %s: %v", args[0], err)
+		}
+		title, _ := getKV(response, "display_name")
+		if name, _ := getKV(response, "name"); title == "" {
+			title = "~" + name
+		}
+
+		posts, err := getChannelHistory(channelID, since, until, exportThreads, exportAttachments, opts)
+		if err != nil {
+			return fmt.Errorf("cannot export the channel %s: %v", args[0], err)
+		}
+
+		if exportOutput == "" {
+			return write(cmd.OutOrStdout(), title, posts)
+		}
+		f, err := os.Create(exportOutput)
+		if err != nil {
+			return err
+		}
+		if err := write(f, title, posts); err != nil {
+			f.Close()
+			return err
+		}
+		return f.Close()
+	},
+}
+
+// init initializes the export command flags.
+func init() {
+	rootCmd.AddCommand(exportCmd)
+
+	exportCmd.Flags().BoolVar(&exportAttachments,
+		"attachments", false, "export the message attachments and the names of the attached files")
+	exportCmd.Flags().StringVar(&exportFormat,
+		"format", "jsonl", "the format of the export: jsonl, markdown or html")
+	exportCmd.Flags().BoolVarP(&mattermostSkipTLSVerify,
+		"insecure", "i", false, "ignore SSL/TLS certificate check")
+	exportCmd.Flags().StringVarP(&exportOutput,
+		"output", "o", "", "the file the export is written to (default is the standard output)")
+	exportCmd.Flags().StringVar(&exportSince,
+		"since", "", "export the posts created since this date (2006-01-02) or RFC3339 time")
+	exportCmd.Flags().StringVarP(&mattermostTeam,
+		"team", "T", "", "the Mattermost team name or ID (default is the only team of the logged user)")
+	exportCmd.Flags().BoolVar(&exportThreads,
+		"threads", false, "export the replies to the posts")
+	exportCmd.Flags().DurationVarP(&mattermostConnectionTimeout,
+		"timeout", "s", 10*time.Second, "the maximum time in seconds allowed for a Mattermost connection")
+	exportCmd.Flags().StringVar(&exportUntil,
+		"until", "", "export the posts created until this date (2006-01-02, included) or RFC3339 time")
+}
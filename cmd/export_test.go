@@ -0,0 +1,242 @@
+/*
+  Copyright 2026 Davide Madrisan <d.madrisan@proton.me>
+
+  Licensed under the Mozilla Public License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+      https://www.mozilla.org/en-US/MPL/2.0/
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/madrisan/go-mattermost-notify/config"
+	"github.com/madrisan/go-mattermost-notify/mattermost/mattermosttest"
+	"github.com/spf13/viper"
+)
+
+func TestGetTimeRange(t *testing.T) {
+	day := func(y int, m time.Month, d int) time.Time { return time.Date(y, m, d, 0, 0, 0, 0, time.Local) }
+
+	var testCases = []struct {
+		since, until string
+		from, to     time.Time
+		fail         bool
+	}{
+		{"", "", time.Time{}, time.Time{}, false},
+		{"2026-09-01", "", day(2026, 9, 1), time.Time{}, false},
+		{"", "2026-09-30", time.Time{}, day(2026, 10, 1), false},
+		{"2026-09-01", "2026-09-01", day(2026, 9, 1), day(2026, 9, 2), false},
+		{"2026-09-01T10:00:00Z", "", time.Date(2026, 9, 1, 10, 0, 0, 0, time.UTC), time.Time{}, false},
+		{"2026-09-02", "2026-09-01", time.Time{}, time.Time{}, true},
+		{"yesterday", "", time.Time{}, time.Time{}, true},
+		{"", "2026-13-01", time.Time{}, time.Time{}, true},
+	}
+	for _, tc := range testCases {
+		from, to, err := getTimeRange(tc.since, tc.until)
+		if tc.fail {
+			if err == nil {
+				t.Errorf("%q-%q: an error was expected", tc.since, tc.until)
+			}
+			continue
+		}
+		if err != nil || !from.Equal(tc.from) || !to.Equal(tc.to) {
+			t.Errorf("%q-%q: expected %v-%v, got %v-%v (%v)", tc.since, tc.until, tc.from, tc.to, from, to, err)
+		}
+	}
+}
+
+func TestNewExportedPost(t *testing.T) {
+	srv := mattermosttest.NewServer()
+	defer srv.Close()
+
+	defer saveSettings("url", "access-token")()
+	viper.Set("url", srv.URL)
+	viper.Set("access-token", srv.Token)
+
+	post := map[string]interface{}{
+		"id":         "p1",
+		"channel_id": "c1",
+		"user_id":    "u1",
+		"message":    "Disk full",
+		"create_at":  float64(time.Date(2026, 9, 1, 10, 0, 0, 0, time.UTC).UnixMilli()),
+		"props": map[string]interface{}{
+			"attachments": []interface{}{
+				map[string]interface{}{
+					"title": "host1",
+					"text":  "/var is 100% full",
+					"fields": []interface{}{
+						map[string]interface{}{"title": "Level", "value": "critical"},
+					},
+				},
+			},
+		},
+		"file_ids": []interface{}{"missing"},
+	}
+
+	p := newExportedPost(post, false, config.Options{})
+	if p.ID != "p1" || p.Message != "Disk full" || p.Time.Location() != time.Local || p.Attachments != nil || p.Files != nil {
+		t.Errorf("unexpected post without attachments: %+v", p)
+	}
+	p = newExportedPost(post, true, config.Options{})
+	if len(p.Attachments) != 1 || p.Attachments[0].Title != "host1" ||
+		len(p.Attachments[0].Fields) != 1 || p.Attachments[0].Fields[0].Value != "critical" {
+		t.Errorf("unexpected attachments: %+v", p.Attachments)
+	}
+	if len(p.Files) != 1 || p.Files[0] != "missing" {
+		t.Errorf("the ID of an unknown file should be exported, got %v", p.Files)
+	}
+}
+
+func TestExportCommand(t *testing.T) {
+	srv := mattermosttest.NewServer()
+	defer srv.Close()
+	ops := srv.AddTeam("ops")
+	alice := srv.AddUser("alice")
+	bob := srv.AddUser("bob")
+
+	defer saveSettings("url", "access-token")()
+	viper.Set("url", srv.URL)
+	viper.Set("access-token", srv.Token)
+
+	channelID, err := createTeamChannel(teamChannel{TeamID: ops.ID, Name: "deploys", DisplayName: "Deploys"},
+		config.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	at := func(d, h int) time.Time { return time.Date(2026, 9, d, h, 0, 0, 0, time.Local) }
+	srv.AddPost(channelID, alice.ID, "", "too old", at(1, 10))
+	root := srv.AddPost(channelID, alice.ID, "", "deploying <v2>", at(2, 10))
+	srv.AddPost(channelID, bob.ID, root.ID, "looks good", at(4, 9))
+	srv.AddPost(channelID, bob.ID, "", "rollback", at(3, 23))
+	srv.AddPost(channelID, alice.ID, "", "too recent", at(4, 0))
+
+	saved := []interface{}{exportFormat, exportOutput, exportSince, exportUntil, exportThreads, mattermostTeam}
+	defer func() {
+		exportFormat, exportOutput = saved[0].(string), saved[1].(string)
+		exportSince, exportUntil = saved[2].(string), saved[3].(string)
+		exportThreads, mattermostTeam = saved[4].(bool), saved[5].(string)
+	}()
+	mattermostTeam, exportSince, exportUntil = "ops", "2026-09-02", "2026-09-03"
+
+	var out bytes.Buffer
+	exportCmd.SetOut(&out)
+	defer exportCmd.SetOut(nil)
+
+	exportFormat, exportThreads = "jsonl", false
+	if err := exportCmd.RunE(exportCmd, []string{"deploys"}); err != nil {
+		t.Fatal("cannot export the channel:", err)
+	}
+	var posts []exportedPost
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		var p exportedPost
+		if err := json.Unmarshal([]byte(line), &p); err != nil {
+			t.Fatalf("invalid JSON line %q: %v", line, err)
+		}
+		posts = append(posts, p)
+	}
+	if len(posts) != 2 || posts[0].Message != "deploying <v2>" || posts[0].Username != "alice" ||
+		posts[1].Message != "rollback" || posts[1].Username != "bob" {
+		t.Errorf("unexpected export without threads: %+v", posts)
+	}
+
+	out.Reset()
+	exportFormat, exportThreads = "markdown", true
+	if err := exportCmd.RunE(exportCmd, []string{"~deploys"}); err != nil {
+		t.Fatal("cannot export the channel:", err)
+	}
+	markdown := out.String()
+	for _, expected := range []string{"# Deploys\n", "## @alice, 2026-09-02 10:00\n\ndeploying <v2>\n",
+		"### ↳ @bob, 2026-09-04 09:00\n\nlooks good\n", "## @bob, 2026-09-03 23:00\n"} {
+		if !strings.Contains(markdown, expected) {
+			t.Errorf("the Markdown export should contain %q:\n%s", expected, markdown)
+		}
+	}
+	if strings.Index(markdown, "looks good") > strings.Index(markdown, "rollback") {
+		t.Errorf("the replies should follow their root post:\n%s", markdown)
+	}
+
+	exportFormat = "html"
+	exportOutput = filepath.Join(t.TempDir(), "deploys.html")
+	if err := exportCmd.RunE(exportCmd, []string{"deploys"}); err != nil {
+		t.Fatal("cannot export the channel:", err)
+	}
+	content, err := os.ReadFile(exportOutput)
+	if err != nil {
+		t.Fatal(err)
+	}
+	html := string(content)
+	if !strings.HasPrefix(html, "<!DOCTYPE html>") || !strings.Contains(html, "deploying &lt;v2&gt;") ||
+		!strings.Contains(html, `class="post reply"`) || strings.Contains(html, "too old") {
+		t.Errorf("unexpected HTML transcript:\n%s", html)
+	}
+
+	exportOutput, exportFormat = "", "pdf"
+	if err := exportCmd.RunE(exportCmd, []string{"deploys"}); err == nil {
+		t.Error("an invalid format should be rejected")
+	}
+
+	exportFormat, exportSince, exportUntil = "jsonl", "", ""
+	requests := len(srv.Requests())
+	if err := exportCmd.RunE(exportCmd, []string{"@bob"}); err == nil || !strings.Contains(err.Error(), "no direct channel") {
+		t.Error("the export of a missing direct channel should fail, got", err)
+	}
+	for _, req := range srv.Requests()[requests:] {
+		if req.Method != http.MethodGet {
+			t.Errorf("unexpected request %s %s", req.Method, req.Path)
+		}
+	}
+}
+
+func TestGetChannelHistoryUntil(t *testing.T) {
+	srv := mattermosttest.NewServer()
+	defer srv.Close()
+	alice := srv.AddUser("alice")
+
+	defer saveSettings("url", "access-token")()
+	viper.Set("url", srv.URL)
+	viper.Set("access-token", srv.Token)
+
+	channel := srv.AddChannel("deploys")
+	start := time.Date(2026, 9, 1, 0, 0, 0, 0, time.Local)
+	for i := 0; i < 5*postsPerPage; i++ {
+		srv.AddPost(channel.ID, alice.ID, "", fmt.Sprintf("post %d", i), start.Add(time.Duration(i)*time.Minute))
+	}
+
+	since, until := start.Add(100*time.Minute), start.Add(110*time.Minute)
+	posts, err := getChannelHistory(channel.ID, since, until, false, false, config.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(posts) != 10 || posts[0].Message != "post 100" || posts[9].Message != "post 109" {
+		t.Errorf("unexpected history: %+v", posts)
+	}
+
+	// The pages of the posts created after the end of the time range are not listed.
+	for _, req := range srv.Requests() {
+		if req.Query.Get("per_page") == fmt.Sprint(postsPerPage) && req.Query.Get("page") != "4" {
+			t.Errorf("unexpected request of the page %s", req.Query.Get("page"))
+		}
+	}
+
+	if posts, err = getChannelHistory(channel.ID, time.Time{}, start, false, false, config.Options{}); err != nil || len(posts) != 0 {
+		t.Errorf("unexpected history before the first post: %+v, %v", posts, err)
+	}
+}
@@ -0,0 +1,242 @@
+/*
+  Copyright 2026 Davide Madrisan <d.madrisan@proton.me>
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+      http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/madrisan/go-mattermost-notify/config"
+	mattermost "github.com/madrisan/go-mattermost-notify/mattermost"
+	"github.com/madrisan/go-mattermost-notify/spool"
+)
+
+var (
+	// flushRetries is the number of times the delivery of a spooled post is retried.
+	flushRetries int
+	// flushRetryDelay is the time to wait before retrying the delivery of a spooled post.
+	flushRetryDelay time.Duration
+)
+
+// getSpool returns the spool set in the configuration file (key spool.dir)
+// or the default one located in the user cache directory.
+func getSpool() (*spool.Spool, error) {
+	dir := viper.GetString("spool.dir")
+	if dir == "" {
+		cacheDir, err := os.UserCacheDir()
+		if err != nil {
+			return nil, fmt.Errorf("cannot find the spool directory: %v", err)
+		}
+		dir = filepath.Join(cacheDir, "go-mattermost-notify", "spool")
+	}
+	return spool.New(dir), nil
+}
+
+// isTransientError tells if the given error may go away by retrying later: a network error,
+// a server error, or a rate limit. The other Mattermost errors, like a missing channel or
+// a forbidden post, are permanent.
+func isTransientError(err error) bool {
+	var apiErr *mattermost.APIError
+	if !errors.As(err, &apiErr) {
+		return true
+	}
+	return apiErr.StatusCode >= 500 || apiErr.StatusCode == http.StatusTooManyRequests
+}
+
+// spoolPost saves in the spool the payload that could not be posted to the destination.
+func spoolPost(destination string, payload []byte, cause error) error {
+	s, err := getSpool()
+	if err != nil {
+		return err
+	}
+
+	name, err := s.Add(spool.Item{
+		Destination: destination,
+		Endpoint:    "/posts",
+		Payload:     payload,
+		Error:       cause.Error(),
+	})
+	if err != nil {
+		return fmt.Errorf("cannot spool the post to %s: %v (post error: %v)", destination, err, cause)
+	}
+
+	fmt.Fprintf(os.Stderr, "Warning: the post to %s has failed (%v) and has been spooled to %s\n",
+		destination, cause, filepath.Join(s.Dir(), name))
+	return nil
+}
+
+// resolveSpooledPayload sets the channel ID of the spooled payload when it was not known at spool time.
+func resolveSpooledPayload(item spool.Item, opts config.Options) ([]byte, error) {
+	var data map[string]interface{}
+	if err := json.Unmarshal(item.Payload, &data); err != nil {
+		return nil, err
+	}
+	if id, _ := data["channel_id"].(string); id != "" {
+		return item.Payload, nil
+	}
+
+	channelID, err := getChannelID(item.Destination, opts)
+	if err != nil {
+		return nil, err
+	}
+	data["channel_id"] = channelID
+
+	return json.Marshal(data)
+}
+
+// deliverSpooled sends the spooled item to Mattermost, retrying up to the given number of times
+// unless the error is permanent.
+func deliverSpooled(item spool.Item, retries int, delay time.Duration, opts config.Options) (interface{}, error) {
+	var err error
+	for attempt := 0; attempt <= retries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(delay)
+		}
+
+		var payload []byte
+		payload, err = resolveSpooledPayload(item, opts)
+		if err == nil {
+			var response interface{}
+			response, err = mattermostPost(item.Endpoint, bytes.NewReader(payload), opts)
+			if err == nil {
+				return response, nil
+			}
+		}
+		if !isTransientError(err) {
+			break
+		}
+	}
+
+	return nil, err
+}
+
+// flushSpool replays in order the spooled posts, skipping the ones older than ttl.
+// The posts rejected by Mattermost are moved to the rejected subdirectory of the spool. The flush
+// stops at the first post that cannot be delivered for now, in order to preserve the posts order.
+// The spool is locked during the flush, so that concurrent flushes cannot deliver a post twice.
+func flushSpool(w io.Writer, s *spool.Spool, ttl time.Duration, opts config.Options) error {
+	unlock, err := s.Lock()
+	if err != nil {
+		return fmt.Errorf("cannot lock the spool %s: %v", s.Dir(), err)
+	}
+	defer unlock()
+
+	entries, err := s.List()
+	if err != nil {
+		return err
+	}
+
+	var delivered, expired, rejected int
+	for i, entry := range entries {
+		if ttl > 0 && time.Since(entry.Created) > ttl {
+			fmt.Fprintf(w, "expired: %s (to %s, spooled at %s)\n",
+				entry.Name, entry.Destination, entry.Created.Format(time.RFC3339))
+			if err := s.Remove(entry.Name); err != nil {
+				return err
+			}
+			expired++
+			continue
+		}
+
+		response, err := deliverSpooled(entry.Item, flushRetries, flushRetryDelay, opts)
+		if err != nil && !isTransientError(err) {
+			path, rerr := s.Reject(entry.Name, err)
+			if rerr != nil {
+				return rerr
+			}
+			fmt.Fprintf(w, "rejected: %s (to %s, %v), moved to %s\n", entry.Name, entry.Destination, err, path)
+			rejected++
+			continue
+		}
+		if err != nil {
+			fmt.Fprintf(w, "delivered: %d, expired: %d, rejected: %d, pending: %d\n",
+				delivered, expired, rejected, len(entries)-i)
+			return fmt.Errorf("cannot deliver %s to %s: %v", entry.Name, entry.Destination, err)
+		}
+
+		postID, _ := getKV(response, "id")
+		fmt.Fprintf(w, "delivered: %s (to %s, post %s)\n", entry.Name, entry.Destination, postID)
+		if err := s.Remove(entry.Name); err != nil {
+			return err
+		}
+		delivered++
+	}
+
+	fmt.Fprintf(w, "delivered: %d, expired: %d, rejected: %d, pending: 0\n", delivered, expired, rejected)
+	return nil
+}
+
+// flushCmd represents the flush CLI command.
+var flushCmd = &cobra.Command{
+	Use:   "flush",
+	Short: "Deliver the posts saved in the spool directory",
+	Long: `Deliver in order the posts that have been saved in the spool directory
+by the command 'post --spool-on-failure' because Mattermost was not reachable.
+
+The posts older than the TTL are discarded, and the posts rejected by Mattermost
+(for instance because the channel does not exist) are moved to the 'rejected'
+subdirectory of the spool. The spool directory can be set in the configuration
+file (key spool.dir) and defaults to a directory in the user cache directory.`,
+	Example: `  flush
+  flush --ttl 2h --retries 5 --retry-delay 30s`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var opts = newOptions()
+
+		s, err := getSpool()
+		if err != nil {
+			return err
+		}
+
+		var w = cmd.OutOrStdout()
+		if viper.GetBool("quiet") {
+			w = io.Discard
+		}
+
+		return flushSpool(w, s, viper.GetDuration("spool.ttl"), opts)
+	},
+}
+
+// init initializes the flush command flags.
+func init() {
+	rootCmd.AddCommand(flushCmd)
+
+	flushCmd.Flags().BoolVarP(&mattermostSkipTLSVerify,
+		"insecure", "i", false, "ignore SSL/TLS certificate check")
+	flushCmd.Flags().IntVar(&flushRetries,
+		"retries", 3, "the number of times the delivery of a post is retried")
+	flushCmd.Flags().DurationVar(&flushRetryDelay,
+		"retry-delay", 10*time.Second, "the time to wait before retrying the delivery of a post")
+	flushCmd.Flags().DurationVarP(&mattermostConnectionTimeout,
+		"timeout", "s", 10*time.Second, "the maximum time in seconds allowed for a Mattermost connection")
+	flushCmd.Flags().Duration("ttl", 24*time.Hour,
+		"discard the posts spooled for longer than this duration (0 means never)")
+
+	err := viper.BindPFlag("spool.ttl", flushCmd.Flags().Lookup("ttl"))
+	if err != nil {
+		checkErr(fmt.Sprintf("unable to bind 'ttl' flag: %v", err))
+	}
+}
@@ -0,0 +1,170 @@
+/*
+  Copyright 2026 Davide Madrisan <d.madrisan@proton.me>
+
+  Licensed under the Mozilla Public License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+      https://www.mozilla.org/en-US/MPL/2.0/
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/madrisan/go-mattermost-notify/config"
+	mattermost "github.com/madrisan/go-mattermost-notify/mattermost"
+	"github.com/madrisan/go-mattermost-notify/spool"
+	"github.com/spf13/viper"
+)
+
+func TestFlushSpool(t *testing.T) {
+	oldMattermostPost := mattermostPost
+	oldSpoolDir := viper.Get("spool.dir")
+	defer func() {
+		mattermostPost = oldMattermostPost
+		viper.Set("spool.dir", oldSpoolDir)
+	}()
+
+	viper.Set("spool.dir", t.TempDir())
+	s, err := getSpool()
+	if err != nil {
+		t.Fatal("getSpool has failed:", err)
+	}
+
+	var delivered []string
+	var serverDown = true
+	mattermostPost = func(endpoint string, payload io.Reader, opts config.Options) (interface{}, error) {
+		if serverDown {
+			return nil, fmt.Errorf("connection refused")
+		}
+		var data map[string]interface{}
+		if err := json.NewDecoder(payload).Decode(&data); err != nil {
+			return nil, err
+		}
+		if data["channel_id"] == "archived" {
+			return nil, &mattermost.APIError{StatusCode: http.StatusForbidden}
+		}
+		delivered = append(delivered, data["channel_id"].(string))
+		return map[string]interface{}{"id": "p" + data["channel_id"].(string)}, nil
+	}
+
+	for _, channel := range []string{"channel1", "archived", "channel2"} {
+		payload := []byte(fmt.Sprintf(`{"channel_id":"%s"}`, channel))
+		if err := spoolPost(channel, payload, fmt.Errorf("connection refused")); err != nil {
+			t.Fatal("spoolPost has failed:", err)
+		}
+	}
+	_, err = s.Add(spool.Item{
+		Created:     time.Now().Add(-48 * time.Hour),
+		Destination: "channel0",
+		Endpoint:    "/posts",
+		Payload:     json.RawMessage(`{"channel_id":"channel0"}`),
+	})
+	if err != nil {
+		t.Fatal("Add has failed:", err)
+	}
+
+	flushRetries, flushRetryDelay = 1, time.Millisecond
+
+	var outbuf bytes.Buffer
+	if err := flushSpool(&outbuf, s, 24*time.Hour, config.Options{}); err == nil {
+		t.Fatal("flushSpool should fail when Mattermost is not reachable")
+	}
+	if entries, _ := s.List(); len(entries) != 3 {
+		t.Fatalf("expected 3 pending posts, got %d", len(entries))
+	}
+
+	unlock, err := s.Lock()
+	if err != nil {
+		t.Fatal("Lock has failed:", err)
+	}
+	serverDown = false
+	if err := flushSpool(io.Discard, s, 24*time.Hour, config.Options{}); err == nil || len(delivered) != 0 {
+		t.Fatal("flushSpool should fail when the spool is locked")
+	}
+	unlock()
+	outbuf.Reset()
+	if err := flushSpool(&outbuf, s, 24*time.Hour, config.Options{}); err != nil {
+		t.Fatal("flushSpool has failed:", err)
+	}
+	if fmt.Sprint(delivered) != "[channel1 channel2]" {
+		t.Error("unexpected delivered posts:", delivered)
+	}
+	if entries, _ := s.List(); len(entries) != 0 {
+		t.Errorf("expected an empty spool, got %d posts", len(entries))
+	}
+	if !bytes.Contains(outbuf.Bytes(), []byte("delivered: 2, expired: 0, rejected: 1, pending: 0")) {
+		t.Error("unexpected report:", outbuf.String())
+	}
+	if rejected, _ := os.ReadDir(filepath.Join(s.Dir(), spool.RejectedDir)); len(rejected) != 1 {
+		t.Errorf("expected 1 rejected post, got %d", len(rejected))
+	}
+}
+
+func TestPostMessageSpool(t *testing.T) {
+	oldMattermostPost := mattermostPost
+	oldSpoolDir := viper.Get("spool.dir")
+	defer func() {
+		mattermostPost = oldMattermostPost
+		viper.Set("spool.dir", oldSpoolDir)
+	}()
+
+	viper.Set("spool.dir", t.TempDir())
+	s, err := getSpool()
+	if err != nil {
+		t.Fatal("getSpool has failed:", err)
+	}
+
+	var postErr error
+	mattermostPost = func(endpoint string, payload io.Reader, opts config.Options) (interface{}, error) {
+		return nil, postErr
+	}
+
+	postErr = &mattermost.APIError{StatusCode: http.StatusServiceUnavailable}
+	if _, err := postMessage("channel1", message{Text: "unavailable"}, true, config.Options{}); err != nil {
+		t.Error("a post failing with a transient error should be spooled, got", err)
+	}
+	postErr = &mattermost.APIError{StatusCode: http.StatusForbidden}
+	if _, err := postMessage("channel1", message{Text: "forbidden"}, true, config.Options{}); err == nil {
+		t.Error("a post failing with a permanent error should not be spooled")
+	}
+	if entries, _ := s.List(); len(entries) != 1 {
+		t.Errorf("expected 1 spooled post, got %d", len(entries))
+	}
+}
+
+func TestIsTransientError(t *testing.T) {
+	t.Parallel()
+
+	var testCases = []struct {
+		err      error
+		shouldBe bool
+	}{
+		{fmt.Errorf("connection refused"), true},
+		{&mattermost.APIError{StatusCode: http.StatusBadGateway}, true},
+		{fmt.Errorf("cannot post: %w", &mattermost.APIError{StatusCode: http.StatusTooManyRequests}), true},
+		{&mattermost.APIError{StatusCode: http.StatusBadRequest}, false},
+		{&mattermost.APIError{StatusCode: http.StatusNotFound}, false},
+	}
+	for _, tc := range testCases {
+		if v := isTransientError(tc.err); v != tc.shouldBe {
+			t.Errorf("%v: expected %t, got %t", tc.err, tc.shouldBe, v)
+		}
+	}
+}
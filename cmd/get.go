@@ -22,8 +22,6 @@ import (
 
 	mattermost "github.com/madrisan/go-mattermost-notify/mattermost"
 	"github.com/spf13/cobra"
-
-	"github.com/madrisan/go-mattermost-notify/config"
 )
 
 // getCmd represents the get CLI command.
@@ -41,10 +39,7 @@ See the Mattermost API documentation:
 		if len(args) == 0 {
 			return fmt.Errorf("An endpoint must be specified in the command-line arguments")
 		}
-		var opts = config.Options{
-			ConnectionTimeout: mattermostConnectionTimeout,
-			SkipTLSVerify:     mattermostSkipTLSVerify,
-		}
+		var opts = newOptions()
 		response, err := mattermostGet(args[0], opts)
 		if err != nil {
 			return err
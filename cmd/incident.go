@@ -0,0 +1,171 @@
+/*
+  Copyright 2026 Davide Madrisan <d.madrisan@proton.me>
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+      http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	// incidentChannelName is the name of the incident channel.
+	incidentChannelName string
+	// incidentLevel is the level of the kickoff message.
+	incidentLevel string
+	// incidentMembers are the users invited to the incident channel.
+	incidentMembers []string
+)
+
+// maxChannelNameLength is the maximum length of a Mattermost channel name.
+const maxChannelNameLength = 64
+
+// nonSlugRegexp matches the sequences of characters not allowed in the channel names.
+var nonSlugRegexp = regexp.MustCompile(`[^a-z0-9]+`)
+
+// getIncidentChannelName returns the name of the channel of the incident with the given title,
+// opened at the given time. Example: incident-20261019-database-outage.
+func getIncidentChannelName(title string, now time.Time) string {
+	name := "incident-" + now.Format("20060102")
+	if slug := strings.Trim(nonSlugRegexp.ReplaceAllString(strings.ToLower(title), "-"), "-"); slug != "" {
+		name += "-" + slug
+	}
+	if len(name) > maxChannelNameLength {
+		name = strings.TrimRight(name[:maxChannelNameLength], "-")
+	}
+	return name
+}
+
+// incidentCmd represents the incident CLI command.
+var incidentCmd = &cobra.Command{
+	Use:   "incident",
+	Short: "Manage the incident channels",
+}
+
+// incidentOpenCmd represents the incident open CLI command.
+var incidentOpenCmd = &cobra.Command{
+	Use:   "open TITLE",
+	Short: "Open an incident channel",
+	Long: `Open an incident in one step: create the incident channel, invite the responders,
+set the channel header and purpose, and post the kickoff message.
+
+The channel is named after the date and the title of the incident, like
+incident-20261019-database-outage, unless --name is set. Its display name is the title.`,
+	Example: `  incident open --team ops "Database outage" --member @alice --member @bob \
+    --header "Status: investigating | Lead: @alice" -m "The primary database is unreachable."`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var opts = newOptions()
+
+		title := args[0]
+
+		// All the responders are resolved before creating the channel, so a typo does not
+		// leave an incomplete channel behind.
+		var memberIDs []string
+		for _, member := range incidentMembers {
+			userID, err := resolveUserID(member, opts)
+			if err != nil {
+				return fmt.Errorf("cannot find the user %s: %v", member, err)
+			}
+			memberIDs = append(memberIDs, userID)
+		}
+
+		teamID, err := getTeamID(mattermostTeam, opts)
+		if err != nil {
+			return err
+		}
+		var channel = teamChannel{
+			TeamID:      teamID,
+			Name:        strings.TrimPrefix(incidentChannelName, "~"),
+			DisplayName: title,
+			Type:        "O",
+			Header:      channelHeader,
+			Purpose:     channelPurpose,
+		}
+		if channel.Name == "" {
+			channel.Name = getIncidentChannelName(title, time.Now())
+		}
+		if channelPrivate {
+			channel.Type = "P"
+		}
+		channelID, err := createTeamChannel(channel, opts)
+		if err != nil {
+			return err
+		}
+
+		for i, userID := range memberIDs {
+			if err := addChannelMember(channelID, userID, opts); err != nil {
+				return fmt.Errorf("cannot add %s to the channel ~%s: %v", incidentMembers[i], channel.Name, err)
+			}
+		}
+
+		var msg = message{
+			Author: messageAuthor,
+			Level:  incidentLevel,
+			Title:  title,
+			Text:   messageContent,
+		}
+		if msg.Text == "" {
+			msg.Text = "The incident has been opened."
+		}
+		if len(incidentMembers) > 0 {
+			msg.Text += "\n\nResponders: " + strings.Join(incidentMembers, ", ")
+		}
+		if _, err := postMessage(channelID, msg, false, opts); err != nil {
+			return fmt.Errorf("cannot post the kickoff message in the channel ~%s: %v", channel.Name, err)
+		}
+
+		if !viper.GetBool("quiet") {
+			fmt.Fprintf(cmd.OutOrStdout(), "Incident channel ~%s opened with the ID %s\n", channel.Name, channelID)
+		}
+		return nil
+	},
+}
+
+// init initializes the incident command flags.
+func init() {
+	rootCmd.AddCommand(incidentCmd)
+	incidentCmd.AddCommand(incidentOpenCmd)
+
+	incidentCmd.PersistentFlags().BoolVarP(&mattermostSkipTLSVerify,
+		"insecure", "i", false, "ignore SSL/TLS certificate check")
+	incidentCmd.PersistentFlags().StringVarP(&mattermostTeam,
+		"team", "T", "", "the Mattermost team name or ID (default is the only team of the logged user)")
+	incidentCmd.PersistentFlags().DurationVarP(&mattermostConnectionTimeout,
+		"timeout", "s", 10*time.Second, "the maximum time in seconds allowed for a Mattermost connection")
+
+	incidentOpenCmd.Flags().StringVarP(&messageAuthor,
+		"author", "A", "", "author of the kickoff message")
+	incidentOpenCmd.Flags().StringVar(&channelHeader,
+		"header", "", "the (markdown-formatted) header of the incident channel")
+	incidentOpenCmd.Flags().StringVarP(&incidentLevel,
+		"level", "l", "critical", "the level of the kickoff message (info, success, warning, or critical)")
+	incidentOpenCmd.Flags().StringArrayVar(&incidentMembers,
+		"member", nil, "a username or ID of a responder invited to the channel (can be repeated)")
+	incidentOpenCmd.Flags().StringVarP(&messageContent,
+		"message", "m", "", "the (markdown-formatted) text of the kickoff message")
+	incidentOpenCmd.Flags().StringVar(&incidentChannelName,
+		"name", "", "the name of the incident channel (default is incident-YYYYMMDD-<title>)")
+	incidentOpenCmd.Flags().BoolVar(&channelPrivate,
+		"private", false, "create a private incident channel")
+	incidentOpenCmd.Flags().StringVar(&channelPurpose,
+		"purpose", "", "the purpose of the incident channel")
+}
@@ -0,0 +1,121 @@
+/*
+  Copyright 2026 Davide Madrisan <d.madrisan@proton.me>
+
+  Licensed under the Mozilla Public License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+      https://www.mozilla.org/en-US/MPL/2.0/
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/madrisan/go-mattermost-notify/config"
+	"github.com/madrisan/go-mattermost-notify/mattermost/mattermosttest"
+	"github.com/spf13/viper"
+)
+
+func TestGetIncidentChannelName(t *testing.T) {
+	now := time.Date(2026, 10, 19, 14, 30, 0, 0, time.UTC)
+
+	var testCases = []struct {
+		title    string
+		shouldBe string
+	}{
+		{"Database outage", "incident-20261019-database-outage"},
+		{"  DNS: resolution failures!! ", "incident-20261019-dns-resolution-failures"},
+		{"???", "incident-20261019"},
+		{strings.Repeat("very long title ", 10), "incident-20261019-very-long-title-very-long-title-very-long-titl"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			if name := getIncidentChannelName(tc.title, now); name != tc.shouldBe {
+				t.Errorf("expected %q, got %q", tc.shouldBe, name)
+			}
+			if len(tc.shouldBe) > maxChannelNameLength {
+				t.Errorf("the name %q is too long", tc.shouldBe)
+			}
+		})
+	}
+}
+
+func TestIncidentOpen(t *testing.T) {
+	srv := mattermosttest.NewServer()
+	defer srv.Close()
+	srv.AddTeam("ops")
+	alice := srv.AddUser("alice")
+	bob := srv.AddUser("bob")
+
+	defer saveSettings("url", "access-token", "quiet")()
+	viper.Set("url", srv.URL)
+	viper.Set("access-token", srv.Token)
+	viper.Set("quiet", false)
+
+	var out bytes.Buffer
+	incidentOpenCmd.SetOut(&out)
+	defer incidentOpenCmd.SetOut(nil)
+
+	oldTeam, oldName, oldMembers, oldHeader := mattermostTeam, incidentChannelName, incidentMembers, channelHeader
+	oldLevel, oldContent := incidentLevel, messageContent
+	defer func() {
+		mattermostTeam, incidentChannelName, incidentMembers, channelHeader = oldTeam, oldName, oldMembers, oldHeader
+		incidentLevel, messageContent = oldLevel, oldContent
+	}()
+	mattermostTeam, incidentChannelName, channelHeader = "", "", "Lead: @alice"
+	incidentLevel, messageContent = "critical", "The primary database is unreachable."
+
+	incidentMembers = []string{"@alice", "@nobody"}
+	if err := incidentOpenCmd.RunE(incidentOpenCmd, []string{"Database outage"}); err == nil {
+		t.Fatal("an unknown responder should be rejected")
+	}
+	if posts := srv.Posts(); len(posts) != 0 {
+		t.Fatal("no kickoff message should be posted")
+	}
+
+	incidentMembers = []string{"@alice", "bob"}
+	if err := incidentOpenCmd.RunE(incidentOpenCmd, []string{"Database outage"}); err != nil {
+		t.Fatal("cannot open the incident:", err)
+	}
+
+	name := getIncidentChannelName("Database outage", time.Now())
+	if !strings.Contains(out.String(), "~"+name) {
+		t.Errorf("unexpected output: %s", out.String())
+	}
+	channelID, err := resolveChannelID(name, "ops", false, config.Options{})
+	if err != nil {
+		t.Fatal("cannot find the incident channel:", err)
+	}
+	if c := srv.Channel(channelID); c.DisplayName != "Database outage" || c.Header != "Lead: @alice" {
+		t.Errorf("unexpected incident channel: %+v", c)
+	}
+	if members := srv.ChannelMembers(channelID); len(members) != 3 ||
+		!strings.Contains(strings.Join(members, " "), alice.ID) || !strings.Contains(strings.Join(members, " "), bob.ID) {
+		t.Errorf("unexpected members: %v", members)
+	}
+
+	posts := srv.Posts()
+	if len(posts) != 1 || posts[0].ChannelID != channelID {
+		t.Fatalf("expected a kickoff message in the incident channel, got %+v", posts)
+	}
+	attachments, _ := posts[0].Props["attachments"].([]interface{})
+	if len(attachments) != 1 {
+		t.Fatalf("unexpected kickoff message: %+v", posts[0])
+	}
+	attachment, _ := attachments[0].(map[string]interface{})
+	if text, _ := attachment["text"].(string); !strings.Contains(text, "unreachable") || !strings.Contains(text, "Responders: @alice, bob") {
+		t.Errorf("unexpected kickoff message: %s", text)
+	}
+}
@@ -0,0 +1,141 @@
+/*
+  Copyright 2026 Davide Madrisan <d.madrisan@proton.me>
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+      http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+*/
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	mattermost "github.com/madrisan/go-mattermost-notify/mattermost"
+	"github.com/spf13/cobra"
+)
+
+var (
+	// listenChannels contains the IDs of the channels whose events are printed.
+	listenChannels []string
+	// listenEvents contains the types of the events printed.
+	listenEvents []string
+)
+
+// eventFilter selects the WebSocket events to be printed.
+type eventFilter struct {
+	channels map[string]bool
+	events   map[string]bool
+}
+
+// newEventFilter returns a filter matching the events of the given types related to the given
+// channels. All the event types but hello, or all the channels, match when none is given.
+func newEventFilter(channels, events []string) eventFilter {
+	var f eventFilter
+	if len(channels) > 0 {
+		f.channels = make(map[string]bool)
+		for _, c := range channels {
+			f.channels[c] = true
+		}
+	}
+	if len(events) > 0 {
+		f.events = make(map[string]bool)
+		for _, e := range events {
+			f.events[strings.ToLower(e)] = true
+		}
+	}
+	return f
+}
+
+// match tells whether the given event must be printed.
+func (f eventFilter) match(e mattermost.Event) bool {
+	if f.events == nil {
+		if e.Event == "hello" {
+			return false
+		}
+	} else if !f.events[e.Event] {
+		return false
+	}
+	if f.channels != nil && !f.channels[e.ChannelID()] {
+		return false
+	}
+	return true
+}
+
+// decodeEventData decodes the objects, like the post of a posted event, that Mattermost
+// sends as JSON strings in the event data.
+func decodeEventData(e *mattermost.Event) {
+	for key, value := range e.Data {
+		s, ok := value.(string)
+		if !ok || !strings.HasPrefix(s, "{") {
+			continue
+		}
+		var object map[string]interface{}
+		if err := json.Unmarshal([]byte(s), &object); err == nil {
+			e.Data[key] = object
+		}
+	}
+}
+
+// listenCmd represents the listen CLI command.
+var listenCmd = &cobra.Command{
+	Use:   "listen",
+	Short: "Print the Mattermost events as JSON lines",
+	Long: `Connect to the Mattermost WebSocket API and print the received events
+(posted, reaction_added, typing, status_change, ...) as JSON lines to the standard output.
+
+The connection is resumed after a disconnection, without losing any event when possible.`,
+	Example: `  listen
+  listen --channel rybfbdi9ojy8xxxjjxc88kh3me --event posted --event reaction_added`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var opts = newOptions()
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		filter := newEventFilter(listenChannels, listenEvents)
+		encoder := json.NewEncoder(os.Stdout)
+
+		handler := func(e mattermost.Event) error {
+			if !filter.match(e) {
+				return nil
+			}
+			decodeEventData(&e)
+			return encoder.Encode(e)
+		}
+		onError := func(err error) {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+		}
+
+		return mattermost.Listen(ctx, opts, handler, onError)
+	},
+}
+
+// init initializes the listen command flags.
+func init() {
+	rootCmd.AddCommand(listenCmd)
+
+	listenCmd.Flags().StringArrayVarP(&listenChannels,
+		"channel", "c", nil, "print only the events of the Mattermost channel with the given ID (can be repeated)")
+	listenCmd.Flags().StringArrayVarP(&listenEvents,
+		"event", "e", nil, "print only the events of the given type, like posted or reaction_added (can be repeated)")
+	listenCmd.Flags().BoolVarP(&mattermostSkipTLSVerify,
+		"insecure", "i", false, "ignore SSL/TLS certificate check")
+	listenCmd.Flags().DurationVarP(&mattermostConnectionTimeout,
+		"timeout", "s", 10*time.Second, "the maximum time in seconds allowed for a Mattermost connection")
+}
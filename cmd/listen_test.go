@@ -0,0 +1,84 @@
+/*
+  Copyright 2026 Davide Madrisan <d.madrisan@proton.me>
+
+  Licensed under the Mozilla Public License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+      https://www.mozilla.org/en-US/MPL/2.0/
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+*/
+
+package cmd
+
+import (
+	"testing"
+
+	"github.com/go-test/deep"
+
+	mattermost "github.com/madrisan/go-mattermost-notify/mattermost"
+)
+
+func TestEventFilter(t *testing.T) {
+	posted := mattermost.Event{
+		Event:     "posted",
+		Broadcast: mattermost.EventBroadcast{ChannelID: "channel1"},
+	}
+	status := mattermost.Event{
+		Event: "status_change",
+		Data:  map[string]interface{}{"status": "away"},
+	}
+	hello := mattermost.Event{Event: "hello"}
+
+	cases := []struct {
+		name     string
+		channels []string
+		events   []string
+		event    mattermost.Event
+		shouldBe bool
+	}{
+		{"no filter", nil, nil, posted, true},
+		{"no filter, hello", nil, nil, hello, false},
+		{"hello requested", nil, []string{"hello"}, hello, true},
+		{"event matching", nil, []string{"Posted"}, posted, true},
+		{"event not matching", nil, []string{"typing"}, posted, false},
+		{"channel matching", []string{"channel2", "channel1"}, nil, posted, true},
+		{"channel not matching", []string{"channel2"}, nil, posted, false},
+		{"no channel", []string{"channel1"}, nil, status, false},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			f := newEventFilter(tc.channels, tc.events)
+			if v := f.match(tc.event); v != tc.shouldBe {
+				t.Error("expected", tc.shouldBe, "got", v)
+			}
+		})
+	}
+}
+
+func TestDecodeEventData(t *testing.T) {
+	e := mattermost.Event{
+		Event: "posted",
+		Data: map[string]interface{}{
+			"post":        `{"id":"post1","message":"hello"}`,
+			"sender_name": "@alice",
+			"mentions":    `["user1"]`,
+		},
+	}
+	decodeEventData(&e)
+
+	shouldBe := map[string]interface{}{
+		"post":        map[string]interface{}{"id": "post1", "message": "hello"},
+		"sender_name": "@alice",
+		"mentions":    `["user1"]`,
+	}
+	if diff := deep.Equal(e.Data, shouldBe); diff != nil {
+		t.Error(diff)
+	}
+}
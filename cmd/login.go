@@ -0,0 +1,320 @@
+/*
+  Copyright 2026 Davide Madrisan <d.madrisan@proton.me>
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+      http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+*/
+
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+
+	mattermost "github.com/madrisan/go-mattermost-notify/mattermost"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"golang.org/x/term"
+
+	"github.com/madrisan/go-mattermost-notify/config"
+)
+
+var (
+	// loginUsername is the username or email of the user logging in.
+	loginUsername string
+	// loginPasswordStdin tells if the password must be read from the standard input.
+	loginPasswordStdin bool
+	// loginMFACode is the multi-factor authentication code of the user logging in.
+	loginMFACode string
+	// loginSSO tells if the user must login through the SSO service of Mattermost.
+	loginSSO bool
+	// loginSSOService is the SSO service to login with, the first one enabled in Mattermost if empty.
+	loginSSOService string
+	// loginNoBrowser tells if the SSO login URL must only be printed, not opened in a browser.
+	loginNoBrowser bool
+)
+
+// isTerminal tells whether the standard input is a terminal the credentials can be prompted from.
+// It's a variable so it can be mocked in the unit tests.
+var isTerminal = func() bool {
+	return term.IsTerminal(int(os.Stdin.Fd()))
+}
+
+// readPassword reads a password from the terminal without echoing it.
+// It's a variable so it can be mocked in the unit tests.
+var readPassword = func() (string, error) {
+	password, err := term.ReadPassword(int(os.Stdin.Fd()))
+	fmt.Fprintln(os.Stderr)
+	return string(password), err
+}
+
+// startBrowser opens the given URL in the default browser of the user.
+// It's a variable so it can be mocked in the unit tests.
+var startBrowser = func(url string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", url)
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", url)
+	default:
+		cmd = exec.Command("xdg-open", url)
+	}
+	return cmd.Start()
+}
+
+// openBrowser prints the SSO login URL and, unless --no-browser is set, opens it in a browser.
+// The URL can always be opened manually, for instance when the browser cannot be started.
+func openBrowser(url string) error {
+	fmt.Fprintf(os.Stderr, "Open the following URL in a browser to login:\n\n    %s\n\n", url)
+	if loginNoBrowser {
+		return nil
+	}
+	if err := startBrowser(url); err != nil {
+		fmt.Fprintln(os.Stderr, os.Args[0], "Warning: cannot open the browser:", err)
+	}
+	return nil
+}
+
+// ssoLogin opens a session through the SSO service set at command-line,
+// or the first one enabled in Mattermost.
+func ssoLogin(opts config.Options) (*mattermost.Session, error) {
+	baseURL := viper.GetString("url")
+	if baseURL == "" {
+		return nil, fmt.Errorf("the Mattermost URL has not been set")
+	}
+	if mattermost.IsLocalMode() {
+		return nil, fmt.Errorf("the SSO login is not available in local mode")
+	}
+
+	service := loginSSOService
+	if service == "" {
+		var err error
+		if service, err = mattermost.GetSSOService(baseURL, opts); err != nil {
+			return nil, err
+		}
+	}
+	return mattermost.SSOLogin(baseURL, service, openBrowser, opts)
+}
+
+// renewSSOSession opens a new SSO session in place of the expired one, when the user can
+// complete the login in a browser.
+func renewSSOSession(expired *mattermost.Session, opts config.Options) (*mattermost.Session, error) {
+	profile := mattermost.GetProfile()
+	if !isTerminal() {
+		return nil, fmt.Errorf("the SSO session of the profile %s has expired, please login again", profile)
+	}
+	fmt.Fprintf(os.Stderr, "The SSO session of the profile %s has expired.\n", profile)
+	return mattermost.SSOLogin(expired.URL, expired.SSO, openBrowser, opts)
+}
+
+// readLine returns the first line read from r, without the trailing newline.
+func readLine(r *bufio.Reader) (string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil && (err != io.EOF || line == "") {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// prompt writes the prompt on the standard error and returns the line read from r.
+func prompt(r *bufio.Reader, text string) (string, error) {
+	fmt.Fprint(os.Stderr, text)
+	return readLine(r)
+}
+
+// getLoginCredentials returns the username and password set at command-line,
+// read from the standard input, or prompted for.
+func getLoginCredentials(stdin *bufio.Reader) (string, string, error) {
+	var username, password = loginUsername, ""
+	var err error
+
+	if username == "" {
+		if !isTerminal() {
+			return "", "", fmt.Errorf("the username must be set at command-line (--username)")
+		}
+		if username, err = prompt(stdin, "Username: "); err != nil {
+			return "", "", err
+		}
+	}
+
+	switch {
+	case loginPasswordStdin:
+		password, err = readLine(stdin)
+	case isTerminal():
+		fmt.Fprint(os.Stderr, "Password: ")
+		password, err = readPassword()
+	default:
+		err = fmt.Errorf("the password must be typed in a terminal or read from the standard input (--password-stdin)")
+	}
+	if err != nil {
+		return "", "", err
+	}
+
+	if username == "" || password == "" {
+		return "", "", fmt.Errorf("the username and the password cannot be empty")
+	}
+	return username, password, nil
+}
+
+// describeSession returns a description of the session for the user.
+func describeSession(profile string, session *mattermost.Session) string {
+	text := fmt.Sprintf("Logged in to %s as @%s", session.URL, session.Username)
+	if session.SSO != "" {
+		text += " through " + session.SSO
+	}
+	text += fmt.Sprintf(" (profile %s)", profile)
+	if !session.ExpiresAt.IsZero() {
+		text += ", the session expires on " + session.ExpiresAt.Format(time.RFC1123)
+	}
+	return text
+}
+
+// loginCmd represents the login CLI command.
+var loginCmd = &cobra.Command{
+	Use:   "login",
+	Short: "Open a Mattermost session with a username and password",
+	Long: `Open a Mattermost session with a username and password, and an MFA code if the
+multi-factor authentication is enabled, for the environments where the personal
+access tokens are disabled. With --sso, the login is made in a browser through the
+SSO service (GitLab, OpenID Connect, ...) configured in Mattermost, and the SSO
+session is renewed in the same way, when run in a terminal, once it has expired.
+
+The session token is cached, readable only by the user, for the current profile
+(--profile), and is used by the other commands until it expires or the logout
+command is run. An access token set at command-line, in the environment or in the
+configuration file has precedence over the cached session.`,
+	Example: `  login -u https://mattermost.example.com --username alice
+  login -P prod -u https://mattermost.example.com --username ci --password-stdin < password.txt
+  login -u https://mattermost.example.com --username alice --mfa-code 123456
+  login -u https://mattermost.example.com --sso`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var opts = newOptions()
+
+		var session *mattermost.Session
+		if loginSSO {
+			var err error
+			if session, err = ssoLogin(opts); err != nil {
+				return fmt.Errorf("cannot login through SSO: %v", err)
+			}
+		} else {
+			stdin := bufio.NewReader(cmd.InOrStdin())
+			username, password, err := getLoginCredentials(stdin)
+			if err != nil {
+				return err
+			}
+
+			session, err = mattermost.Login(username, password, loginMFACode, opts)
+			if mattermost.IsMFARequired(err) && loginMFACode == "" && isTerminal() {
+				var code string
+				if code, err = prompt(stdin, "MFA code: "); err != nil {
+					return err
+				}
+				session, err = mattermost.Login(username, password, code, opts)
+			}
+			if err != nil {
+				return fmt.Errorf("cannot login as %s: %v", username, err)
+			}
+		}
+
+		profile := mattermost.GetProfile()
+		if err := mattermost.SaveSession(profile, session); err != nil {
+			return err
+		}
+
+		if !viper.GetBool("quiet") {
+			fmt.Println(describeSession(profile, session))
+		}
+		return nil
+	},
+}
+
+// logoutCmd represents the logout CLI command.
+var logoutCmd = &cobra.Command{
+	Use:   "logout",
+	Short: "Revoke the Mattermost session opened by the login command",
+	Long: `Revoke the Mattermost session opened by the login command for the current
+profile (--profile), and remove it from the cache.`,
+	Example: `  logout
+  logout -P prod`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var opts = newOptions()
+
+		profile := mattermost.GetProfile()
+		session, err := mattermost.LoadSession(profile)
+		if err != nil {
+			return err
+		}
+		if session == nil {
+			return fmt.Errorf("there is no session for the profile %s", profile)
+		}
+
+		// An expired or already revoked session is just removed from the cache.
+		var apiErr *mattermost.APIError
+		if err := mattermost.Logout(session, opts); err != nil &&
+			!(errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusUnauthorized) {
+			return fmt.Errorf("cannot revoke the session: %v", err)
+		}
+		if err := mattermost.DeleteSession(profile); err != nil {
+			return err
+		}
+
+		if !viper.GetBool("quiet") {
+			fmt.Printf("Logged out from %s (profile %s)\n", session.URL, profile)
+		}
+		return nil
+	},
+}
+
+// init initializes the login and logout command flags.
+func init() {
+	rootCmd.AddCommand(loginCmd)
+	rootCmd.AddCommand(logoutCmd)
+
+	mattermost.SessionRenewer = renewSSOSession
+
+	loginCmd.Flags().BoolVarP(&mattermostSkipTLSVerify,
+		"insecure", "i", false, "ignore SSL/TLS certificate check")
+	loginCmd.Flags().StringVar(&loginMFACode,
+		"mfa-code", "", "the multi-factor authentication code (prompted for when required and not set)")
+	loginCmd.Flags().BoolVar(&loginNoBrowser,
+		"no-browser", false, "print the SSO login URL without opening it in a browser")
+	loginCmd.Flags().BoolVar(&loginPasswordStdin,
+		"password-stdin", false, "read the password from the standard input")
+	loginCmd.Flags().BoolVar(&loginSSO,
+		"sso", false, "login in a browser through the SSO service configured in Mattermost")
+	loginCmd.Flags().StringVar(&loginSSOService,
+		"sso-service", "", "the SSO service to login with: gitlab, openid, google or office365 (default is the first one enabled)")
+	loginCmd.Flags().DurationVarP(&mattermostConnectionTimeout,
+		"timeout", "s", 10*time.Second, "the maximum time in seconds allowed for a Mattermost connection")
+	loginCmd.Flags().StringVar(&loginUsername,
+		"username", "", "the username or email of the user (prompted for when not set)")
+
+	for _, flag := range []string{"mfa-code", "password-stdin", "username"} {
+		loginCmd.MarkFlagsMutuallyExclusive("sso", flag)
+	}
+
+	logoutCmd.Flags().BoolVarP(&mattermostSkipTLSVerify,
+		"insecure", "i", false, "ignore SSL/TLS certificate check")
+	logoutCmd.Flags().DurationVarP(&mattermostConnectionTimeout,
+		"timeout", "s", 10*time.Second, "the maximum time in seconds allowed for a Mattermost connection")
+}
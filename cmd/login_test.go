@@ -0,0 +1,191 @@
+/*
+  Copyright 2026 Davide Madrisan <d.madrisan@proton.me>
+
+  Licensed under the Mozilla Public License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+      https://www.mozilla.org/en-US/MPL/2.0/
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+*/
+
+package cmd
+
+import (
+	"bufio"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/madrisan/go-mattermost-notify/config"
+	mattermost "github.com/madrisan/go-mattermost-notify/mattermost"
+	"github.com/madrisan/go-mattermost-notify/mattermost/mattermosttest"
+	"github.com/spf13/viper"
+)
+
+// saveSettings returns a function restoring the given viper settings. The keys not explicitly set
+// are restored to nil, so the flag bindings and the environment variables keep working in the
+// following tests.
+func saveSettings(keys ...string) func() {
+	var settings = make(map[string]interface{})
+	for _, key := range keys {
+		if viper.IsSet(key) {
+			settings[key] = viper.Get(key)
+		} else {
+			settings[key] = nil
+		}
+	}
+	return func() {
+		for key, value := range settings {
+			viper.Set(key, value)
+		}
+	}
+}
+
+func TestGetLoginCredentials(t *testing.T) {
+	oldUsername, oldPasswordStdin, oldIsTerminal, oldReadPassword := loginUsername, loginPasswordStdin, isTerminal, readPassword
+	defer func() {
+		loginUsername, loginPasswordStdin, isTerminal, readPassword = oldUsername, oldPasswordStdin, oldIsTerminal, oldReadPassword
+	}()
+	readPassword = func() (string, error) { return "typed", nil }
+
+	var testCases = []struct {
+		name          string
+		username      string
+		passwordStdin bool
+		terminal      bool
+		stdin         string
+		shouldBe      string
+		shouldFail    bool
+	}{
+		{"password from stdin", "alice", true, false, "secret\n", "alice:secret", false},
+		{"prompted", "", false, true, "alice\n", "alice:typed", false},
+		{"no terminal", "alice", false, false, "", "", true},
+		{"empty password", "alice", true, false, "\n", "", true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			loginUsername, loginPasswordStdin = tc.username, tc.passwordStdin
+			isTerminal = func() bool { return tc.terminal }
+
+			username, password, err := getLoginCredentials(bufio.NewReader(strings.NewReader(tc.stdin)))
+			if (err != nil) != tc.shouldFail {
+				t.Fatal("unexpected error:", err)
+			}
+			if v := username + ":" + password; !tc.shouldFail && v != tc.shouldBe {
+				t.Errorf("expected %s, got %s", tc.shouldBe, v)
+			}
+		})
+	}
+}
+
+func TestLoginLogout(t *testing.T) {
+	srv := mattermosttest.NewServer()
+	defer srv.Close()
+	alice := srv.AddUser("alice")
+	srv.SetPassword(alice.ID, "secret", "123456")
+
+	restoreSettings := saveSettings("url", "access-token", "sessions.dir", "quiet")
+	oldIsTerminal, oldUsername, oldMFACode, oldPasswordStdin := isTerminal, loginUsername, loginMFACode, loginPasswordStdin
+	defer func() {
+		restoreSettings()
+		isTerminal, loginUsername, loginMFACode, loginPasswordStdin = oldIsTerminal, oldUsername, oldMFACode, oldPasswordStdin
+		loginCmd.SetIn(nil)
+	}()
+	viper.Set("url", srv.URL)
+	viper.Set("access-token", "")
+	viper.Set("sessions.dir", t.TempDir())
+	viper.Set("quiet", true)
+	isTerminal = func() bool { return false }
+	loginUsername, loginPasswordStdin = "alice", true
+
+	loginCmd.SetIn(strings.NewReader("secret\n"))
+	if err := loginCmd.RunE(loginCmd, nil); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Fatal("the login without the MFA code should fail, got", err)
+	}
+
+	loginMFACode = "123456"
+	loginCmd.SetIn(strings.NewReader("secret\n"))
+	if err := loginCmd.RunE(loginCmd, nil); err != nil {
+		t.Fatal("login has failed:", err)
+	}
+
+	session, err := mattermost.LoadSession(mattermost.DefaultProfile)
+	if err != nil || session == nil || session.Username != "alice" || session.Expired() {
+		t.Fatalf("unexpected session %+v (%v)", session, err)
+	}
+	path := viper.GetString("sessions.dir") + "/default.json"
+	if fi, err := os.Stat(path); err != nil || fi.Mode().Perm() != 0600 {
+		t.Error("the session file should only be readable by the user", fi.Mode(), err)
+	}
+
+	// The cached session is used when no access token is set.
+	if username, err := getLoggedUsername(config.Options{}); err != nil || username != "alice" {
+		t.Errorf("expected alice, got %s (%v)", username, err)
+	}
+
+	if err := logoutCmd.RunE(logoutCmd, nil); err != nil {
+		t.Fatal("logout has failed:", err)
+	}
+	if session, _ := mattermost.LoadSession(mattermost.DefaultProfile); session != nil {
+		t.Error("the session should have been removed from the cache")
+	}
+	viper.Set("access-token", session.Token)
+	if _, err := getLoggedUsername(config.Options{}); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Error("the session should have been revoked, got", err)
+	}
+}
+
+func TestLoginSSO(t *testing.T) {
+	idp := mattermosttest.NewIdentityProvider("carol")
+	defer idp.Close()
+	srv := mattermosttest.NewServer()
+	defer srv.Close()
+	srv.EnableSSO("gitlab", idp.URL)
+
+	restoreSettings := saveSettings("url", "access-token", "sessions.dir", "quiet")
+	oldSSO, oldStartBrowser := loginSSO, startBrowser
+	defer func() {
+		restoreSettings()
+		loginSSO, startBrowser = oldSSO, oldStartBrowser
+	}()
+	viper.Set("url", srv.URL)
+	viper.Set("access-token", "")
+	viper.Set("sessions.dir", t.TempDir())
+	viper.Set("quiet", true)
+
+	// The browser follows the redirections to the identity provider and back.
+	var opened string
+	startBrowser = func(url string) error {
+		opened = url
+		go func() {
+			if response, err := http.Get(url); err == nil {
+				response.Body.Close()
+			}
+		}()
+		return nil
+	}
+	loginSSO = true
+
+	if err := loginCmd.RunE(loginCmd, nil); err != nil {
+		t.Fatal("the SSO login has failed:", err)
+	}
+	if !strings.HasPrefix(opened, srv.URL+"/oauth/gitlab/mobile_login?") {
+		t.Error("unexpected login URL", opened)
+	}
+
+	session, err := mattermost.LoadSession(mattermost.DefaultProfile)
+	if err != nil || session == nil || session.Username != "carol" || session.SSO != "gitlab" {
+		t.Fatalf("unexpected session %+v (%v)", session, err)
+	}
+	if username, err := getLoggedUsername(config.Options{}); err != nil || username != "carol" {
+		t.Errorf("expected carol, got %s (%v)", username, err)
+	}
+}
@@ -0,0 +1,246 @@
+/*
+  Copyright 2026 Davide Madrisan <d.madrisan@proton.me>
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+      http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	mattermost "github.com/madrisan/go-mattermost-notify/mattermost"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// nagiosNotification is a Nagios or Icinga host or service notification.
+type nagiosNotification struct {
+	Host       string
+	Service    string
+	State      string
+	Output     string
+	LongOutput string
+	Type       string
+	Author     string
+	Comment    string
+}
+
+var (
+	// nagiosAuthor contains the author of the Mattermost post.
+	nagiosAuthor string
+	// nagiosFlags contains the notification properties set at command-line.
+	nagiosFlags nagiosNotification
+)
+
+// getNagiosMacro returns the value of the Nagios (NAGIOS_<name>) or Icinga (ICINGA_<name>)
+// macro exported in the environment.
+func getNagiosMacro(name string) string {
+	if value := os.Getenv("NAGIOS_" + name); value != "" {
+		return value
+	}
+	return os.Getenv("ICINGA_" + name)
+}
+
+// getNagiosNotification returns the notification set at command-line, completed with the
+// macros exported in the environment by Nagios or Icinga.
+func getNagiosNotification(flags nagiosNotification) nagiosNotification {
+	var n = flags
+
+	fromEnv := func(value *string, names ...string) {
+		for _, name := range names {
+			if *value != "" {
+				return
+			}
+			*value = getNagiosMacro(name)
+		}
+	}
+
+	fromEnv(&n.Host, "HOSTNAME")
+	fromEnv(&n.Service, "SERVICEDESC")
+	fromEnv(&n.Type, "NOTIFICATIONTYPE")
+	fromEnv(&n.Author, "NOTIFICATIONAUTHOR")
+	fromEnv(&n.Comment, "NOTIFICATIONCOMMENT")
+	if n.Service != "" {
+		fromEnv(&n.State, "SERVICESTATE")
+		fromEnv(&n.Output, "SERVICEOUTPUT")
+		fromEnv(&n.LongOutput, "LONGSERVICEOUTPUT")
+	} else {
+		fromEnv(&n.State, "HOSTSTATE")
+		fromEnv(&n.Output, "HOSTOUTPUT")
+		fromEnv(&n.LongOutput, "LONGHOSTOUTPUT")
+	}
+
+	n.State = strings.ToUpper(n.State)
+	n.Type = strings.ToUpper(n.Type)
+	if n.Type == "" {
+		n.Type = "PROBLEM"
+	}
+
+	return n
+}
+
+// level returns the criticity level of the notification, according to its type and state.
+func (n nagiosNotification) level() string {
+	switch n.Type {
+	case "PROBLEM":
+		switch n.State {
+		case "OK", "UP":
+			return "success"
+		case "WARNING", "UNKNOWN":
+			return "warning"
+		default:
+			return "critical"
+		}
+	case "RECOVERY":
+		return "success"
+	}
+
+	// ACKNOWLEDGEMENT, FLAPPINGSTART, FLAPPINGSTOP, DOWNTIMESTART, DOWNTIMEEND, CUSTOM...
+	return "info"
+}
+
+// title returns the title of the notification. Example: PROBLEM: HTTP on web1 is CRITICAL
+func (n nagiosNotification) title() string {
+	var object = n.Host
+	if n.Service != "" {
+		object = n.Service + " on " + n.Host
+	}
+	return fmt.Sprintf("%s: %s is %s", n.Type, object, n.State)
+}
+
+// text returns the markdown text of the notification.
+func (n nagiosNotification) text() string {
+	var text = n.Output
+	if n.LongOutput != "" {
+		text += "\n" + strings.ReplaceAll(n.LongOutput, `\n`, "\n")
+	}
+	if n.Comment != "" {
+		var by string
+		if n.Author != "" {
+			by = " by " + n.Author
+		}
+		text += fmt.Sprintf("\n\n_Comment%s: %s_", by, n.Comment)
+	}
+	return strings.TrimSpace(text)
+}
+
+// fields returns the attachment fields of the notification.
+func (n nagiosNotification) fields() []mattermost.MsgField {
+	fields := []mattermost.MsgField{{Title: "Host", Value: n.Host, Short: true}}
+	if n.Service != "" {
+		fields = append(fields, mattermost.MsgField{Title: "Service", Value: n.Service, Short: true})
+	}
+	return append(fields,
+		mattermost.MsgField{Title: "State", Value: n.State, Short: true},
+		mattermost.MsgField{Title: "Notification type", Value: n.Type, Short: true})
+}
+
+// nagiosCmd represents the nagios CLI command.
+var nagiosCmd = &cobra.Command{
+	Use:   "nagios",
+	Short: "Post a Nagios or Icinga notification",
+	Long: `Post a Nagios or Icinga host or service notification, to be used as a notification command.
+
+The notification properties are read from the NAGIOS_* or ICINGA_* environment macros
+(HOSTNAME, SERVICEDESC, SERVICESTATE, SERVICEOUTPUT, NOTIFICATIONTYPE, ...) and can be
+overridden at command-line. The level is set according to the notification type (PROBLEM,
+RECOVERY, ACKNOWLEDGEMENT, ...) and state (OK, WARNING, CRITICAL, UNKNOWN, UP, DOWN...).
+
+The following notifications for the same host or service, recovery included, are posted
+as replies in the thread of the problem notification.`,
+	Example: `  nagios -c rybfbdi9ojy8xxxjjxc88kh3me
+
+  define command {
+    command_name notify-service-by-mattermost
+    command_line /usr/bin/go-mattermost-notify nagios -c rybfbdi9ojy8xxxjjxc88kh3me
+  }
+
+  nagios -c @alice --host web1 --service HTTP --state CRITICAL --type PROBLEM --output "Connection refused"`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var opts = newOptions()
+
+		n := getNagiosNotification(nagiosFlags)
+		if n.Host == "" || n.State == "" {
+			return fmt.Errorf("the host and the state must be set at command-line or by the Nagios macros")
+		}
+
+		labels, err := parseLabels(messageLabels)
+		if err != nil {
+			return err
+		}
+
+		stateFile, err := getStateFile("nagios")
+		if err != nil {
+			return err
+		}
+
+		msg := message{
+			Author:      nagiosAuthor,
+			Labels:      labels,
+			Level:       n.level(),
+			Text:        n.text(),
+			Title:       n.title(),
+			Options:     []mattermost.MsgOption{mattermost.WithFields(n.fields())},
+			Threads:     &stateThreads{file: stateFile},
+			ThreadKey:   n.Host + "/" + n.Service,
+			CloseThread: n.Type == "RECOVERY",
+		}
+
+		posts, err := postMessage(mattermostChannel, msg, spoolOnFailure, opts)
+		if err != nil {
+			return err
+		}
+
+		if !viper.GetBool("quiet") {
+			for _, post := range posts {
+				mattermost.PrettyPrint(os.Stdout, post.Response)
+			}
+		}
+
+		return nil
+	},
+}
+
+// init initializes the nagios command flags.
+func init() {
+	rootCmd.AddCommand(nagiosCmd)
+
+	nagiosCmd.Flags().StringVarP(&nagiosAuthor,
+		"author", "A", "Nagios", "author of the message")
+	nagiosCmd.Flags().StringVarP(&mattermostChannel,
+		"channel", "c", "", "Mattermost channel ID or username. Example: rybfbdi9ojy8xxxjjxc88kh3me or @alice")
+	nagiosCmd.Flags().StringVar(&nagiosFlags.Comment,
+		"comment", "", "the notification comment (default $NAGIOS_NOTIFICATIONCOMMENT)")
+	nagiosCmd.Flags().StringVar(&nagiosFlags.Host,
+		"host", "", "the host name (default $NAGIOS_HOSTNAME)")
+	nagiosCmd.Flags().BoolVarP(&mattermostSkipTLSVerify,
+		"insecure", "i", false, "ignore SSL/TLS certificate check")
+	nagiosCmd.Flags().StringArrayVar(&messageLabels,
+		"label", nil, "label in the form key=value used for routing the message (can be repeated)")
+	nagiosCmd.Flags().StringVar(&nagiosFlags.Output,
+		"output", "", "the plugin output (default $NAGIOS_SERVICEOUTPUT or $NAGIOS_HOSTOUTPUT)")
+	nagiosCmd.Flags().StringVar(&nagiosFlags.Service,
+		"service", "", "the service description, empty for host notifications (default $NAGIOS_SERVICEDESC)")
+	nagiosCmd.Flags().BoolVar(&spoolOnFailure,
+		"spool-on-failure", false, "save the message in the spool directory when it cannot be posted (see the flush command)")
+	nagiosCmd.Flags().StringVar(&nagiosFlags.State,
+		"state", "", "the service or host state (default $NAGIOS_SERVICESTATE or $NAGIOS_HOSTSTATE)")
+	nagiosCmd.Flags().DurationVarP(&mattermostConnectionTimeout,
+		"timeout", "s", 10*time.Second, "the maximum time in seconds allowed for a Mattermost connection")
+	nagiosCmd.Flags().StringVar(&nagiosFlags.Type,
+		"type", "", "the notification type (default $NAGIOS_NOTIFICATIONTYPE)")
+}
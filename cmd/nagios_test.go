@@ -0,0 +1,109 @@
+/*
+  Copyright 2026 Davide Madrisan <d.madrisan@proton.me>
+
+  Licensed under the Mozilla Public License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+      https://www.mozilla.org/en-US/MPL/2.0/
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+*/
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/madrisan/go-mattermost-notify/config"
+	mattermost "github.com/madrisan/go-mattermost-notify/mattermost"
+	"github.com/spf13/viper"
+)
+
+func TestGetNagiosNotification(t *testing.T) {
+	t.Setenv("NAGIOS_HOSTNAME", "web1")
+	t.Setenv("NAGIOS_SERVICEDESC", "HTTP")
+	t.Setenv("NAGIOS_SERVICESTATE", "CRITICAL")
+	t.Setenv("NAGIOS_SERVICEOUTPUT", "Connection refused")
+	t.Setenv("ICINGA_NOTIFICATIONTYPE", "PROBLEM")
+
+	n := getNagiosNotification(nagiosNotification{State: "warning"})
+	if n.Host != "web1" || n.Service != "HTTP" || n.Output != "Connection refused" || n.Type != "PROBLEM" {
+		t.Error("unexpected notification", n)
+	}
+	if n.State != "WARNING" {
+		t.Error("the command-line state should override the environment, got", n.State)
+	}
+	if v := n.title(); v != "PROBLEM: HTTP on web1 is WARNING" {
+		t.Error("unexpected title", v)
+	}
+}
+
+func TestNagiosLevel(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		notificationType string
+		state            string
+		shouldBe         string
+	}{
+		{"PROBLEM", "CRITICAL", "critical"},
+		{"PROBLEM", "DOWN", "critical"},
+		{"PROBLEM", "WARNING", "warning"},
+		{"PROBLEM", "UNKNOWN", "warning"},
+		{"RECOVERY", "OK", "success"},
+		{"ACKNOWLEDGEMENT", "CRITICAL", "info"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.notificationType+"_"+tc.state, func(t *testing.T) {
+			n := nagiosNotification{Type: tc.notificationType, State: tc.state}
+			if v := n.level(); v != tc.shouldBe {
+				t.Error("expected", tc.shouldBe, "got", v)
+			}
+		})
+	}
+}
+
+func TestNagiosThreads(t *testing.T) {
+	oldMattermostPost := mattermostPost
+	oldStateDir := viper.Get("state.dir")
+	defer func() {
+		mattermostPost = oldMattermostPost
+		viper.Set("state.dir", oldStateDir)
+	}()
+	viper.Set("state.dir", t.TempDir())
+
+	var posted []mattermost.MsgPayload
+	mattermostPost = func(endpoint string, payload io.Reader, opts config.Options) (interface{}, error) {
+		var data mattermost.MsgPayload
+		if err := json.NewDecoder(payload).Decode(&data); err != nil {
+			return nil, err
+		}
+		posted = append(posted, data)
+		return map[string]interface{}{"id": fmt.Sprintf("post%d", len(posted))}, nil
+	}
+
+	for _, notificationType := range []string{"PROBLEM", "ACKNOWLEDGEMENT", "RECOVERY", "PROBLEM"} {
+		rootCmd.SetArgs([]string{"nagios", "-q", "-c", "alerts",
+			"--host", "web1", "--service", "HTTP", "--state", "CRITICAL", "--type", notificationType})
+		if err := rootCmd.Execute(); err != nil {
+			t.Fatal("nagios has failed:", err)
+		}
+	}
+
+	var rootIDs []string
+	for _, p := range posted {
+		rootIDs = append(rootIDs, p.RootID)
+	}
+	if fmt.Sprintf("%q", rootIDs) != `["" "post1" "post1" ""]` {
+		t.Errorf("unexpected threads %q", rootIDs)
+	}
+}
@@ -22,6 +22,7 @@ import (
 	"fmt"
 	"os"
 	"reflect"
+	"sort"
 	"strings"
 	"time"
 
@@ -41,21 +42,38 @@ var (
 	mattermostSkipTLSVerify bool
 	// mattermostTeam contains the Mattermost Team.
 	mattermostTeam string
+	// dedupKey is the key identifying the duplicate messages to be suppressed.
+	dedupKey string
+	// dedupWindow is the period during which the duplicate messages are suppressed.
+	dedupWindow time.Duration
 	// messageAuthor contains the author of the Mattermost post to be sent.
 	messageAuthor string
 	// messageContent contains the text message of the Mattermost post.
 	messageContent string
+	// messageLabels contains the labels (in the form key=value) used by the routing rules.
+	messageLabels []string
 	// messageLevel defines the criticity of the post message.
 	// Can be "info" (the default), "success", "warning", or "critical".
 	messageLevel string
 	// messageTitle contains the title of the post message to be sent.
 	messageTitle string
+	// spoolOnFailure tells if the posts that cannot be delivered must be saved in the spool directory.
+	spoolOnFailure bool
+	// mattermostDelete contains the pointer to the Delete function in the mattermost package.
+	// It's used to easily mockup the Mattermost server in the unit tests.
+	mattermostDelete = mattermost.Delete
+	// mattermostDo contains the pointer to the Do function in the mattermost package.
+	// It's used to easily mockup the Mattermost server in the unit tests.
+	mattermostDo = mattermost.Do
 	// mattermostGet contains the pointer to the Get function in the mattermost package.
 	// It's used to easily mockup the Mattermost server in the unit tests.
 	mattermostGet = mattermost.Get
 	// mattermostPost contains the pointer to the Post function in the mattermost package.
 	// It's used to easily mockup the Mattermost server in the unit tests.
 	mattermostPost = mattermost.Post
+	// mattermostPut contains the pointer to the Put function in the mattermost package.
+	// It's used to easily mockup the Mattermost server in the unit tests.
+	mattermostPut = mattermost.Put
 )
 
 // The HTML colors used in the post message attachment.
@@ -101,12 +119,7 @@ func getKV(response interface{}, key string) (string, error) {
 }
 
 // getLoggedUsername returns the username of the logged Mattermost user.
-func getLoggedUsername() (string, error) {
-	var opts = config.Options{
-		ConnectionTimeout: mattermostConnectionTimeout,
-		SkipTLSVerify:     mattermostSkipTLSVerify,
-	}
-
+func getLoggedUsername(opts config.Options) (string, error) {
 	response, err := mattermostGet("/users/me", opts)
 	if err != nil {
 		return "", err
@@ -121,13 +134,13 @@ func getLoggedUsername() (string, error) {
 }
 
 // getLoggedUserID returns the Mattermost ID of the logged user.
-func getLoggedUserID() (string, error) {
-	username, err := getLoggedUsername()
+func getLoggedUserID(opts config.Options) (string, error) {
+	username, err := getLoggedUsername(opts)
 	if err != nil {
 		return "", err
 	}
 
-	id, err := getUserID(username)
+	id, err := getUserID(username, opts)
 	if err != nil {
 		return "", err
 	}
@@ -136,12 +149,7 @@ func getLoggedUserID() (string, error) {
 }
 
 // getUserID returns the Mattemost ID associated to the given user.
-func getUserID(username string) (string, error) {
-	var opts = config.Options{
-		ConnectionTimeout: mattermostConnectionTimeout,
-		SkipTLSVerify:     mattermostSkipTLSVerify,
-	}
-
+func getUserID(username string, opts config.Options) (string, error) {
 	endpoint := fmt.Sprintf("/users/username/%s", username)
 	response, err := mattermostGet(endpoint, opts)
 	if err != nil {
@@ -154,69 +162,337 @@ func getUserID(username string) (string, error) {
 	return id, nil
 }
 
+// getChannelID returns the Mattermost ID of the given channel.
+// When the channel is a username in the form @username, the ID of the direct channel
+// between the logged user and this user is returned.
+func getChannelID(channel string, opts config.Options) (string, error) {
+	if !strings.HasPrefix(channel, "@") {
+		return channel, nil
+	}
+
+	userIDFrom, err := getLoggedUserID(opts)
+	if err != nil {
+		return "", err
+	}
+
+	userIDTo, err := getUserID(strings.TrimLeft(channel, "@"), opts)
+	if err != nil {
+		return "", err
+	}
+
+	payload, err := json.Marshal([]string{userIDFrom, userIDTo})
+	if err != nil {
+		return "", err
+	}
+
+	response, err := mattermostPost("/channels/direct", bytes.NewReader(payload), opts)
+	if err != nil {
+		return "", err
+	}
+
+	channelID, err := getKV(response, "id")
+	if err != nil {
+		return "", fmt.Errorf("cannot get the Mattermost direct channel ID %v", err)
+	}
+
+	return channelID, nil
+}
+
+// findDirectChannelID returns the Mattermost ID of the existing direct channel between the logged
+// user and the given user (in the form @username), or an empty string if there is none yet.
+// Unlike getChannelID, it only reads from Mattermost and never creates the channel.
+func findDirectChannelID(channel string, opts config.Options) (string, error) {
+	userIDFrom, err := getLoggedUserID(opts)
+	if err != nil {
+		return "", err
+	}
+	userIDTo, err := getUserID(strings.TrimLeft(channel, "@"), opts)
+	if err != nil {
+		return "", err
+	}
+
+	// The name of a direct channel is made of the sorted IDs of its two members.
+	var ids = []string{userIDFrom, userIDTo}
+	sort.Strings(ids)
+	name := strings.Join(ids, "__")
+
+	response, err := mattermostGet("/users/"+userIDFrom+"/channels", opts)
+	if err != nil {
+		return "", err
+	}
+	channels, _ := response.([]interface{})
+	for _, c := range channels {
+		info, _ := c.(map[string]interface{})
+		if channelType, _ := getKV(info, "type"); channelType != "D" {
+			continue
+		}
+		if n, _ := getKV(info, "name"); n == name {
+			return getKV(info, "id")
+		}
+	}
+	return "", nil
+}
+
+// getDestinations returns the channels the message must be posted to:
+// the one set at command-line or, if not set, the ones selected by the routing rules.
+func getDestinations(channel, level string, labels map[string]string, author string) ([]string, error) {
+	if channel != "" {
+		return []string{channel}, nil
+	}
+
+	routes, err := getRoutes()
+	if err != nil {
+		return nil, err
+	}
+
+	destinations := resolveRoutes(routes, level, labels, author)
+	if len(destinations) == 0 {
+		return nil, fmt.Errorf("no channel has been set and no route matches the message")
+	}
+
+	return destinations, nil
+}
+
+// message is a message to be posted to Mattermost.
+type message struct {
+	Author string
+	// Labels are used by the routing rules for selecting the destinations of the message.
+	Labels map[string]string
+	Level  string
+	Text   string
+	Title  string
+	// Options contains the optional properties of the message payload.
+	Options []mattermost.MsgOption
+	// Threads, when set, records the threads the messages with the same ThreadKey are posted in.
+	// The first message of a thread is a new post, the following ones are replies to it.
+	Threads   threadStore
+	ThreadKey string
+	// CloseThread tells that the message is the last one of its thread.
+	CloseThread bool
+}
+
+// sentPost is a post delivered to Mattermost.
+type sentPost struct {
+	Destination string
+	Response    interface{}
+}
+
+// poster resolves the destinations of the messages and delivers their payloads to Mattermost.
+type poster interface {
+	// channelID returns the Mattermost ID of the given channel ID or @username.
+	channelID(destination string) (string, error)
+	// post sends the post payload and returns the Mattermost response.
+	post(payload []byte) (interface{}, error)
+}
+
+// directPoster is the poster querying Mattermost for each message.
+type directPoster struct {
+	opts config.Options
+}
+
+// channelID returns the Mattermost ID of the given channel.
+func (p directPoster) channelID(destination string) (string, error) {
+	return getChannelID(destination, p.opts)
+}
+
+// post sends the post payload to Mattermost.
+func (p directPoster) post(payload []byte) (interface{}, error) {
+	return mattermostPost("/posts", bytes.NewReader(payload), p.opts)
+}
+
+// postMessage posts the message to the given channel or, if not set, to the destinations selected
+// by the routing rules. When spool is true, the posts that cannot be delivered because of a
+// transient error are saved in the spool directory instead of returning an error.
+func postMessage(channel string, msg message, spool bool, opts config.Options) ([]sentPost, error) {
+	return sendMessage(channel, msg, spool, directPoster{opts: opts})
+}
+
+// sendMessage posts the message like postMessage, resolving and delivering it with the given poster.
+func sendMessage(channel string, msg message, spool bool, p poster) ([]sentPost, error) {
+	destinations, err := getDestinations(channel, msg.Level, msg.Labels, msg.Author)
+	if err != nil {
+		return nil, err
+	}
+
+	var posts []sentPost
+	for _, destination := range destinations {
+		// The channel ID is left empty when it cannot be resolved: it will be resolved on flush.
+		mattermostChannelID, err := p.channelID(destination)
+		if err != nil && (!spool || !isTransientError(err)) {
+			return posts, err
+		}
+
+		var threadKey, rootID string
+		if msg.Threads != nil && msg.ThreadKey != "" && mattermostChannelID != "" {
+			threadKey = mattermostChannelID + "/" + msg.ThreadKey
+			if rootID, err = msg.Threads.get(threadKey); err != nil {
+				return posts, err
+			}
+		}
+
+		options := append([]mattermost.MsgOption{mattermost.WithRootID(rootID)}, msg.Options...)
+		payload, perr := mattermost.CreateMsgPayload(
+			getAttachmentColor(msg.Level),
+			mattermostChannelID,
+			msg.Author, msg.Text, msg.Title, options...)
+		if perr != nil {
+			return posts, perr
+		}
+
+		var response interface{}
+		if err == nil {
+			response, err = p.post(payload)
+		}
+		if err != nil {
+			if !spool || !isTransientError(err) {
+				return posts, err
+			}
+			if err := spoolPost(destination, payload, err); err != nil {
+				return posts, err
+			}
+			continue
+		}
+
+		if threadKey != "" {
+			postID, _ := getKV(response, "id")
+			switch {
+			case msg.CloseThread:
+				err = msg.Threads.set(threadKey, "")
+			case rootID == "":
+				err = msg.Threads.set(threadKey, postID)
+			}
+			if err != nil {
+				return posts, err
+			}
+		}
+
+		posts = append(posts, sentPost{Destination: destination, Response: response})
+	}
+
+	return posts, nil
+}
+
+// checkRequiredFlags returns an error when one of the given flags has not been set.
+// The flags required only outside the batch mode cannot be marked as required.
+func checkRequiredFlags(cmd *cobra.Command, names ...string) error {
+	var missing []string
+	for _, name := range names {
+		if !cmd.Flags().Changed(name) {
+			missing = append(missing, `"`+name+`"`)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("required flag(s) %s not set", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
 // postCmd represents the post CLI command.
 var postCmd = &cobra.Command{
 	Use:   "post",
 	Short: "Post a message to a Mattermost channel or user",
-	Long:  `Post a message to a Mattermost channel or user using its REST APIv4 interface.`,
+	Long: `Post a message to a Mattermost channel or user using its REST APIv4 interface.
+
+When no channel is set, the message is posted to the destinations selected by
+the 'routes' section of the configuration file, according to the message level,
+labels and author.
+
+When a deduplication key is set, the messages with the same key sent during the
+deduplication window are suppressed, even by concurrent processes. The next message
+that gets through reports how many times it has been repeated.
+
+When a digest name is set, the message is queued instead of being posted, unless its
+level is critical. The queued messages are summarized in a single post by the command
+'digest flush' or 'digest run'.
+
+The buttons and select menus added with --action and --menu call the endpoint /actions
+of the serve command, which runs the handler configured for the action and updates the post.`,
 	Example: `  post -c rybfbdi9ojy8xxxjjxc88kh3me -A CI -t "Job Status" -m "The job \#BEEF has failed :bug:" -l critical
-  post -c @alice -A CI -t "Job Status" -m "The job \#BEEF ended successfully :tada:" -l success -s 3s`,
+  post -c @alice -A CI -t "Job Status" -m "The job \#BEEF ended successfully :tada:" -l success -s 3s
+  post -A CI -t "Database" -m "Replication is broken" -l critical --label service=db
+  post -c rybfbdi9ojy8xxxjjxc88kh3me -A monitoring -t "Disk" -m "/var is full" --dedup-key disk-var --dedup-window 1h
+  post -c rybfbdi9ojy8xxxjjxc88kh3me -A CI -t "Build" -m "Build \#42 succeeded" -l success --digest builds
+  post -c rybfbdi9ojy8xxxjjxc88kh3me -A CI -t "Deploy" -m "v1.4.2 deployed" --action Rollback=rollback --menu "Silence=silence:1h,4h,1d"`,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		attachmentColor := getAttachmentColor(messageLevel)
+		var opts = newOptions()
 
-		var mattermostChannelID string
-		var opts = config.Options{
-			ConnectionTimeout: mattermostConnectionTimeout,
-			SkipTLSVerify:     mattermostSkipTLSVerify,
+		if batchFile != "" {
+			return runBatch(batchFile, opts)
+		}
+		if err := checkRequiredFlags(cmd, "author", "message", "title"); err != nil {
+			return err
 		}
 
-		if opts.SkipTLSVerify {
-			fmt.Fprintln(os.Stderr, os.Args[0], "Warning: SSL/TLS certificate check is disabled!")
+		labels, err := parseLabels(messageLabels)
+		if err != nil {
+			return err
 		}
 
-		if strings.HasPrefix(mattermostChannel, "@") {
-			userIDFrom, err := getLoggedUserID()
-			if err != nil {
-				return err
-			}
+		var url = actionURL
+		if url == "" {
+			url = viper.GetString("actions.url")
+		}
+		actions, err := parseActions(messageActions, messageMenus, url, viper.GetString("actions.token"))
+		if err != nil {
+			return err
+		}
 
-			userIDTo, err := getUserID(strings.TrimLeft(mattermostChannel, "@"))
-			if err != nil {
-				return err
-			}
+		msg := message{
+			Author: messageAuthor,
+			Labels: labels,
+			Level:  messageLevel,
+			Text:   messageContent,
+			Title:  messageTitle,
+		}
+		if len(actions) > 0 {
+			msg.Options = append(msg.Options, mattermost.WithActions(actions))
+		}
 
-			payload, err := json.Marshal([]string{userIDFrom, userIDTo})
+		// The critical messages are posted immediately.
+		if digestName != "" && messageLevel != "critical" {
+			return queueDigestEvent(digestName, mattermostChannel, msg)
+		}
+
+		var dedup *dedupFilter
+		var dedupPrevious dedupEntry
+		if dedupKey != "" {
+			stateFile, err := getStateFile("dedup")
 			if err != nil {
 				return err
 			}
+			dedup = newDedupFilter(stateFile)
 
-			response, err := mattermostPost("/channels/direct", bytes.NewReader(payload), opts)
+			var suppress bool
+			var repeated int
+			suppress, repeated, dedupPrevious, err = dedup.check(dedupKey, dedupWindow)
 			if err != nil {
 				return err
 			}
-
-			mattermostChannelID, err = getKV(response, "id")
-			if err != nil {
-				return fmt.Errorf("cannot get the Mattermost direct channel ID %v", err)
+			if suppress {
+				if !viper.GetBool("quiet") {
+					fmt.Fprintf(os.Stderr, "The message with the deduplication key \"%s\" has been suppressed\n", dedupKey)
+				}
+				return nil
+			}
+			if repeated > 0 {
+				msg.Text += "\n\n" + getRepeatedNote(repeated)
 			}
-		} else {
-			mattermostChannelID = mattermostChannel
 		}
 
-		payload, err := mattermost.CreateMsgPayload(
-			attachmentColor,
-			mattermostChannelID,
-			messageAuthor, messageContent, messageTitle)
+		posts, err := postMessage(mattermostChannel, msg, spoolOnFailure, opts)
 		if err != nil {
+			if dedup != nil {
+				if err := dedup.restore(dedupKey, dedupPrevious); err != nil {
+					fmt.Fprintln(os.Stderr, "Error:", err)
+				}
+			}
 			return err
 		}
 
-		response, err := mattermostPost("/posts", bytes.NewReader(payload), opts)
-		if err != nil {
-			return err
-		}
 		if !viper.GetBool("quiet") {
-			mattermost.PrettyPrint(os.Stdout, response)
+			for _, post := range posts {
+				mattermost.PrettyPrint(os.Stdout, post.Response)
+			}
 		}
 
 		return nil
@@ -227,31 +503,44 @@ var postCmd = &cobra.Command{
 func init() {
 	rootCmd.AddCommand(postCmd)
 
+	postCmd.Flags().StringArrayVar(&messageActions,
+		"action", nil, "button in the form Label=id calling the handler of the action id (can be repeated)")
+	postCmd.Flags().StringVar(&actionURL,
+		"action-url", "", "the URL of the serve endpoint /actions (default is the actions.url configuration key)")
 	postCmd.Flags().StringVarP(&messageAuthor,
 		"author", "A", "", "author of the message")
 	postCmd.Flags().StringVarP(&mattermostChannel,
 		"channel", "c", "", "Mattermost channel ID or username. Example: rybfbdi9ojy8xxxjjxc88kh3me or @alice")
+	postCmd.Flags().StringVar(&dedupKey,
+		"dedup-key", "", "suppress the messages with this key sent during the deduplication window")
+	postCmd.Flags().DurationVar(&dedupWindow,
+		"dedup-window", 10*time.Minute, "the period during which the messages with the same deduplication key are suppressed")
+	postCmd.Flags().StringVar(&digestName,
+		"digest", "", "queue the message in the digest with this name instead of posting it (see the digest command)")
+	postCmd.Flags().StringArrayVar(&messageLabels,
+		"label", nil, "label in the form key=value used for routing the message (can be repeated)")
 	postCmd.Flags().BoolVarP(&mattermostSkipTLSVerify,
 		"insecure", "i", false, "ignore SSL/TLS certificate check")
 	postCmd.Flags().StringVarP(&messageLevel,
 		"level", "l", "info", "criticity level. Can be info, success, warning, or critical")
+	postCmd.Flags().StringArrayVar(&messageMenus,
+		"menu", nil, "select menu in the form Label=id:option1,option2... calling the handler of the action id (can be repeated)")
 	postCmd.Flags().StringVarP(&messageContent,
 		"message", "m", "", "the (markdown-formatted) message to send to the Mattermost channel")
+	postCmd.Flags().BoolVar(&spoolOnFailure,
+		"spool-on-failure", false, "save the message in the spool directory when it cannot be posted (see the flush command)")
 	postCmd.Flags().StringVarP(&mattermostTeam, "team", "T", "", "the Mattermost team")
 	postCmd.Flags().DurationVarP(&mattermostConnectionTimeout,
 		"timeout", "s", 10*time.Second, "the maximum time in seconds allowed for a Mattermost connection")
 	postCmd.Flags().StringVarP(&messageTitle,
 		"title", "t", "", "the title that will precede the text message")
 
-	var requiredFlags = [...]string{
-		"author",
-		"channel",
-		"message",
-		"title",
-	}
+	postCmd.Flags().StringVar(&batchFile,
+		"batch", "", "post the messages read from a JSON Lines file, or from the standard input for -")
+	postCmd.Flags().IntVar(&batchWorkers,
+		"workers", 4, "the number of messages posted concurrently in batch mode")
 
-	for _, requiredFlag := range requiredFlags {
-		err := postCmd.MarkFlagRequired(requiredFlag)
-		checkErr(err)
+	for _, flag := range []string{"action", "dedup-key", "digest", "menu", "message", "title"} {
+		postCmd.MarkFlagsMutuallyExclusive("batch", flag)
 	}
 }
@@ -24,6 +24,7 @@ import (
 
 	"github.com/madrisan/go-mattermost-notify/config"
 	mattermost "github.com/madrisan/go-mattermost-notify/mattermost"
+	"github.com/madrisan/go-mattermost-notify/mattermost/mattermosttest"
 	"github.com/spf13/viper"
 )
 
@@ -188,3 +189,50 @@ func TestEnvVariables(t *testing.T) {
 		}
 	}
 }
+
+func TestPostEndToEnd(t *testing.T) {
+	srv := mattermosttest.NewServer()
+	defer srv.Close()
+	alice := srv.AddUser("alice")
+
+	oldURL, oldToken := viper.Get("url"), viper.Get("access-token")
+	defer func() {
+		viper.Set("url", oldURL)
+		viper.Set("access-token", oldToken)
+	}()
+	viper.Set("url", srv.URL)
+	viper.Set("access-token", srv.Token)
+
+	rootCmd.SetArgs([]string{"post", "-q", "-c", "@alice", "-A", "CI", "-t", "Job Status", "-m", "The job has failed", "-l", "critical"})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatal("post has failed:", err)
+	}
+
+	posts := srv.Posts()
+	if len(posts) != 1 {
+		t.Fatal("one post was expected, got", posts)
+	}
+	attachments, _ := posts[0].Props["attachments"].([]interface{})
+	if len(attachments) != 1 || attachments[0].(map[string]interface{})["color"] != colorCritical {
+		t.Error("unexpected post properties", posts[0].Props)
+	}
+
+	var endpoints []string
+	for _, r := range srv.Requests() {
+		endpoints = append(endpoints, r.Method+" "+r.Path)
+	}
+	shouldBe := "GET /api/v4/users/me, GET /api/v4/users/username/bot, GET /api/v4/users/username/alice, " +
+		"POST /api/v4/channels/direct, POST /api/v4/posts"
+	if v := strings.Join(endpoints, ", "); v != shouldBe {
+		t.Error("expected", shouldBe, "got", v)
+	}
+	if !strings.Contains(string(srv.Requests()[3].Body), alice.ID) {
+		t.Error("the direct channel should include alice")
+	}
+
+	srv.SetError("POST", "/api/v4/posts", 500)
+	rootCmd.SetArgs([]string{"post", "-q", "-c", "@alice", "-A", "CI", "-t", "Job Status", "-m", "The job has failed"})
+	if err := rootCmd.Execute(); err == nil {
+		t.Error("post should fail when Mattermost fails")
+	}
+}
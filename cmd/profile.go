@@ -0,0 +1,113 @@
+/*
+  Copyright 2026 Davide Madrisan <d.madrisan@proton.me>
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+      http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	homedir "github.com/mitchellh/go-homedir"
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
+
+	"github.com/madrisan/go-mattermost-notify/fileutil"
+)
+
+// getConfigFile returns the path of the configuration file read at startup, or the one set at
+// command-line, or the default one.
+func getConfigFile() (string, error) {
+	if path := viper.ConfigFileUsed(); path != "" {
+		return path, nil
+	}
+	if cfgFile != "" {
+		return cfgFile, nil
+	}
+	home, err := homedir.Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".go-mattermost-notify.yaml"), nil
+}
+
+// getMappingValue returns the value of the given key of a YAML mapping node,
+// adding an empty mapping for it when missing.
+func getMappingValue(mapping *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return mapping.Content[i+1]
+		}
+	}
+	value := &yaml.Node{Kind: yaml.MappingNode}
+	mapping.Content = append(mapping.Content,
+		&yaml.Node{Kind: yaml.ScalarNode, Value: key}, value)
+	return value
+}
+
+// saveProfileSettings sets the given settings (like url and access-token) of the given profile in
+// the 'profiles' section of the YAML configuration file at the given path, which is created if
+// missing. The other settings and the comments of the file are preserved.
+func saveProfileSettings(path, profile string, settings map[string]string) error {
+	var doc yaml.Node
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("invalid configuration file %s: %v", path, err)
+	}
+	if doc.Kind == 0 {
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode}}}
+	}
+	if len(doc.Content) != 1 || doc.Content[0].Kind != yaml.MappingNode {
+		return fmt.Errorf("invalid configuration file %s: not a YAML mapping", path)
+	}
+
+	profiles := getMappingValue(doc.Content[0], "profiles")
+	if profiles.Kind != yaml.MappingNode {
+		return fmt.Errorf("invalid configuration file %s: the profiles section is not a mapping", path)
+	}
+	section := getMappingValue(profiles, profile)
+	if section.Kind != yaml.MappingNode {
+		return fmt.Errorf("invalid configuration file %s: the profile %s is not a mapping", path, profile)
+	}
+
+	var keys []string
+	for key := range settings {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		value := getMappingValue(section, key)
+		value.Kind, value.Tag, value.Value, value.Content = yaml.ScalarNode, "!!str", settings[key], nil
+	}
+
+	var b bytes.Buffer
+	encoder := yaml.NewEncoder(&b)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&doc); err != nil {
+		return err
+	}
+	if err := encoder.Close(); err != nil {
+		return err
+	}
+
+	// The configuration file now contains a secret: it's made readable only by the user.
+	return fileutil.WriteFile(path, b.Bytes())
+}
@@ -0,0 +1,95 @@
+/*
+  Copyright 2026 Davide Madrisan <d.madrisan@proton.me>
+
+  Licensed under the Mozilla Public License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+      https://www.mozilla.org/en-US/MPL/2.0/
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+*/
+
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestSaveProfileSettings(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	config := `# The default Mattermost server.
+mattermost:
+  url: https://mattermost.example.com
+profiles:
+  # The staging server.
+  staging:
+    url: https://mattermost-staging.example.com
+    access-token: old-token
+`
+	if err := os.WriteFile(path, []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := saveProfileSettings(path, "staging", map[string]string{"access-token": "new-token"}); err != nil {
+		t.Fatal("cannot save the staging profile:", err)
+	}
+	if err := saveProfileSettings(path, "ci", map[string]string{"access-token": "ci-token", "url": "https://ci.example.com"}); err != nil {
+		t.Fatal("cannot save the ci profile:", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, comment := range []string{"# The default Mattermost server.", "# The staging server."} {
+		if !strings.Contains(string(data), comment) {
+			t.Errorf("the comment %q should have been preserved:\n%s", comment, data)
+		}
+	}
+	if fi, err := os.Stat(path); err != nil || fi.Mode().Perm() != 0600 {
+		t.Error("the configuration file should only be readable by the user", fi.Mode(), err)
+	}
+
+	v := viper.New()
+	v.SetConfigFile(path)
+	if err := v.ReadInConfig(); err != nil {
+		t.Fatal(err)
+	}
+	var settings = map[string]string{
+		"mattermost.url":                "https://mattermost.example.com",
+		"profiles.staging.url":          "https://mattermost-staging.example.com",
+		"profiles.staging.access-token": "new-token",
+		"profiles.ci.url":               "https://ci.example.com",
+		"profiles.ci.access-token":      "ci-token",
+	}
+	for key, shouldBe := range settings {
+		if value := v.GetString(key); value != shouldBe {
+			t.Errorf("%s: expected %s, got %s", key, shouldBe, value)
+		}
+	}
+
+	newPath := filepath.Join(t.TempDir(), "new.yaml")
+	if err := saveProfileSettings(newPath, "ci", map[string]string{"access-token": "ci-token"}); err != nil {
+		t.Error("the missing configuration file should be created:", err)
+	}
+	if data, _ := os.ReadFile(newPath); string(data) != "profiles:\n  ci:\n    access-token: ci-token\n" {
+		t.Errorf("unexpected configuration file:\n%s", data)
+	}
+
+	if err := os.WriteFile(path, []byte("profiles: [a, b]\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := saveProfileSettings(path, "ci", map[string]string{"access-token": "ci-token"}); err == nil {
+		t.Error("an invalid profiles section should be rejected")
+	}
+}
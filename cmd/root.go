@@ -18,10 +18,14 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"os"
+	"strconv"
 	"strings"
 
+	"github.com/madrisan/go-mattermost-notify/config"
+	mattermost "github.com/madrisan/go-mattermost-notify/mattermost"
 	homedir "github.com/mitchellh/go-homedir"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -43,12 +47,48 @@ var rootCmd = &cobra.Command{
 	Long:  `Post a message to a Mattermost channel using its REST APIv4 interface.`,
 }
 
+// exitCodeAnnotation is the annotation of the commands exiting on error with a code other than 1.
+const exitCodeAnnotation = "exit-code-on-error"
+
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
 func Execute() {
-	if err := rootCmd.Execute(); err != nil {
-		os.Exit(1)
+	if cmd, err := rootCmd.ExecuteC(); err != nil {
+		os.Exit(getErrorExitCode(cmd, err))
+	}
+}
+
+// exitCodeError is returned by the commands exiting with a given code without an error message,
+// like exec with the exit code of the command it has run.
+type exitCodeError struct {
+	code int
+}
+
+// Error returns the exit code, for the callers printing the error.
+func (e *exitCodeError) Error() string {
+	return fmt.Sprintf("exit code %d", e.code)
+}
+
+// exitWithCode returns the error making Execute exit with the given code, and silences the error
+// message and the usage of the given command.
+func exitWithCode(cmd *cobra.Command, code int) error {
+	cmd.SilenceErrors = true
+	cmd.SilenceUsage = true
+	return &exitCodeError{code: code}
+}
+
+// getErrorExitCode returns the exit code of the given command on the given error: the code of an
+// exitCodeError or, for the other errors, 1 unless the command sets another code in its
+// exitCodeAnnotation annotation.
+func getErrorExitCode(cmd *cobra.Command, err error) int {
+	var exitErr *exitCodeError
+	if errors.As(err, &exitErr) {
+		return exitErr.code
+	}
+	if code, err := strconv.Atoi(cmd.Annotations[exitCodeAnnotation]); err == nil {
+		return code
 	}
+	return 1
 }
 
 // checkErr prints the msg with the prefix 'Error:' and exits with error code 1. If the msg is nil, it does nothing.
@@ -59,6 +99,19 @@ func checkErr(msg interface{}) {
 	}
 }
 
+// newOptions returns the options of the Mattermost queries set by the command-line flags,
+// warning when the SSL/TLS certificate check is disabled.
+func newOptions() config.Options {
+	var opts = config.Options{
+		ConnectionTimeout: mattermostConnectionTimeout,
+		SkipTLSVerify:     mattermostSkipTLSVerify,
+	}
+	if opts.SkipTLSVerify {
+		fmt.Fprintln(os.Stderr, os.Args[0], "Warning: SSL/TLS certificate check is disabled!")
+	}
+	return opts
+}
+
 // init initializes the persistent (global) flags.
 func init() {
 	cobra.OnInitialize(initConfig)
@@ -71,11 +124,19 @@ func init() {
 	rootCmd.PersistentFlags().StringVarP(&mattermostAccessToken,
 		"access-token", "a", "",
 		"Mattermost Access Token. The command-line value has precedence over the MATTERMOST_ACCESS_TOKEN environment variable.")
+	rootCmd.PersistentFlags().StringP("profile", "P", "",
+		"the profile of the Mattermost server and credentials (default is \"default\"). Can be set via the MATTERMOST_PROFILE environment variable.")
 	rootCmd.PersistentFlags().BoolP("quiet", "q", false, "quiet mode")
-
-	err := viper.BindPFlag("quiet", rootCmd.PersistentFlags().Lookup("quiet"))
-	if err != nil {
-		checkErr(fmt.Sprintf("unable to bind 'quiet' flag: %v", err))
+	rootCmd.PersistentFlags().BoolP("verbose", "v", false,
+		"log to stderr the Mattermost HTTP queries with their timings and headers (the token is redacted)")
+	rootCmd.PersistentFlags().Bool("trace", false, "like --verbose, also logging the request and response bodies")
+	rootCmd.PersistentFlags().String("log-format", "text", "the format of the verbose and trace logs: text or json")
+
+	for _, flag := range []string{"profile", "quiet", "verbose", "trace", "log-format"} {
+		err := viper.BindPFlag(flag, rootCmd.PersistentFlags().Lookup(flag))
+		if err != nil {
+			checkErr(fmt.Sprintf("unable to bind '%s' flag: %v", flag, err))
+		}
 	}
 }
 
@@ -126,6 +187,18 @@ func initConfig() {
 		}
 	} else {
 		// Using the config file: viper.ConfigFileUsed()
+		// The settings of the profile have precedence over the 'mattermost' section.
+		profile := "profiles." + mattermost.GetProfile()
+		if viper.IsSet(profile+".access-token") && !viper.IsSet("access-token") {
+			val := viper.Get(profile + ".access-token")
+			err := rootCmd.Flags().Set("access-token", fmt.Sprintf("%v", val))
+			checkErr(err)
+		}
+		if viper.IsSet(profile+".url") && !viper.IsSet("url") {
+			val := viper.Get(profile + ".url")
+			err := rootCmd.Flags().Set("url", fmt.Sprintf("%v", val))
+			checkErr(err)
+		}
 		if viper.IsSet("mattermost.access-token") && !viper.IsSet("access-token") {
 			val := viper.Get("mattermost.access-token")
 			err := rootCmd.Flags().Set("access-token", fmt.Sprintf("%v", val))
@@ -18,10 +18,14 @@ package cmd
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
 	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
 )
 
 func TestCheckErr(t *testing.T) {
@@ -53,3 +57,34 @@ func TestCheckErr(t *testing.T) {
 	}
 	t.Fatalf("process ran with err %v, want exit status 1", err)
 }
+
+func TestGetErrorExitCode(t *testing.T) {
+	t.Parallel()
+
+	var err = errors.New("failure")
+	if code := getErrorExitCode(postCmd, err); code != 1 {
+		t.Error("expected exit code 1 for the post command, got", code)
+	}
+	if code := getErrorExitCode(approveCmd, err); code != exitCodeApprovalError {
+		t.Error("expected exit code", exitCodeApprovalError, "for the approve command, got", code)
+	}
+
+	cmd := &cobra.Command{}
+	err = fmt.Errorf("wrapped: %w", exitWithCode(cmd, 42))
+	if code := getErrorExitCode(approveCmd, err); code != 42 {
+		t.Error("expected exit code 42, got", code)
+	}
+	if !cmd.SilenceErrors || !cmd.SilenceUsage {
+		t.Error("the error message and the usage should be silenced")
+	}
+}
+
+func TestNewOptions(t *testing.T) {
+	oldTimeout, oldSkipTLSVerify := mattermostConnectionTimeout, mattermostSkipTLSVerify
+	defer func() { mattermostConnectionTimeout, mattermostSkipTLSVerify = oldTimeout, oldSkipTLSVerify }()
+	mattermostConnectionTimeout, mattermostSkipTLSVerify = 3*time.Second, false
+
+	if opts := newOptions(); opts.ConnectionTimeout != 3*time.Second || opts.SkipTLSVerify {
+		t.Errorf("unexpected options %+v", opts)
+	}
+}
@@ -0,0 +1,129 @@
+/*
+  Copyright 2026 Davide Madrisan <d.madrisan@proton.me>
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+      http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// routeMatch contains the conditions that a message must satisfy for a route to apply.
+// An empty condition always matches.
+type routeMatch struct {
+	Levels []string          `mapstructure:"level"`
+	Labels map[string]string `mapstructure:"labels"`
+	Author string            `mapstructure:"author"`
+}
+
+// route is a routing rule of the 'routes' section of the configuration file.
+type route struct {
+	Match        routeMatch `mapstructure:"match"`
+	Destinations []string   `mapstructure:"destinations"`
+	// Continue tells whether the routes following a matching one must be evaluated as well.
+	Continue bool `mapstructure:"continue"`
+}
+
+// matches returns true if the message with the given level, labels and author satisfies the route conditions.
+func (r route) matches(level string, labels map[string]string, author string) bool {
+	if len(r.Match.Levels) > 0 {
+		found := false
+		for _, l := range r.Match.Levels {
+			if strings.EqualFold(l, level) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	for key, value := range r.Match.Labels {
+		if v, found := labels[strings.ToLower(key)]; !found || v != value {
+			return false
+		}
+	}
+
+	if r.Match.Author != "" && r.Match.Author != author {
+		return false
+	}
+
+	return true
+}
+
+// getRoutes returns the routing rules set in the configuration file.
+func getRoutes() ([]route, error) {
+	var routes []route
+	if err := viper.UnmarshalKey("routes", &routes); err != nil {
+		return nil, fmt.Errorf("invalid 'routes' section in the configuration file: %v", err)
+	}
+	return routes, nil
+}
+
+// resolveRoutes returns the destinations of the routes matching the given message properties.
+// The routes are evaluated in order and the evaluation stops at the first matching route,
+// unless the 'continue' flag is set for it.
+func resolveRoutes(routes []route, level string, labels map[string]string, author string) []string {
+	var destinations []string
+	var seen = make(map[string]bool)
+
+	for _, r := range routes {
+		if !r.matches(level, labels, author) {
+			continue
+		}
+		for _, dest := range r.Destinations {
+			if !seen[dest] {
+				seen[dest] = true
+				destinations = append(destinations, dest)
+			}
+		}
+		if !r.Continue {
+			break
+		}
+	}
+
+	return destinations
+}
+
+// parseLabels converts a list of labels in the form key=value into a map.
+// Label names are case insensitive and converted to lowercase.
+func parseLabels(args []string) (map[string]string, error) {
+	var labels = make(map[string]string, len(args))
+
+	for _, arg := range args {
+		key, value, found := strings.Cut(arg, "=")
+		key = strings.TrimSpace(key)
+		if !found || key == "" {
+			return nil, fmt.Errorf("invalid label \"%s\": must be in the form key=value", arg)
+		}
+		labels[key] = value
+	}
+
+	return normalizeLabels(labels), nil
+}
+
+// normalizeLabels returns a copy of the given labels with the names converted to lowercase,
+// as expected by the routing rules.
+func normalizeLabels(labels map[string]string) map[string]string {
+	var normalized = make(map[string]string, len(labels))
+	for key, value := range labels {
+		normalized[strings.ToLower(key)] = value
+	}
+	return normalized
+}
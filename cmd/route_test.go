@@ -0,0 +1,112 @@
+/*
+  Copyright 2026 Davide Madrisan <d.madrisan@proton.me>
+
+  Licensed under the Mozilla Public License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+      https://www.mozilla.org/en-US/MPL/2.0/
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+*/
+
+package cmd
+
+import (
+	"testing"
+
+	"github.com/go-test/deep"
+)
+
+func TestResolveRoutes(t *testing.T) {
+	t.Parallel()
+
+	routes := []route{
+		{
+			Match: routeMatch{
+				Levels: []string{"critical"},
+				Labels: map[string]string{"service": "db"},
+			},
+			Destinations: []string{"dba-channel", "@dba-oncall"},
+			Continue:     true,
+		},
+		{
+			Match: routeMatch{
+				Author: "CI",
+			},
+			Destinations: []string{"ci-channel"},
+		},
+		{
+			Destinations: []string{"dba-channel", "default-channel"},
+		},
+	}
+
+	cases := []struct {
+		name     string
+		level    string
+		labels   map[string]string
+		author   string
+		shouldBe []string
+	}{
+		{
+			"critical_db",
+			"critical",
+			map[string]string{"service": "db"},
+			"monitoring",
+			[]string{"dba-channel", "@dba-oncall", "default-channel"},
+		},
+		{
+			"warning_db",
+			"warning",
+			map[string]string{"service": "db"},
+			"monitoring",
+			[]string{"dba-channel", "default-channel"},
+		},
+		{
+			"critical_ci",
+			"critical",
+			map[string]string{"service": "web"},
+			"CI",
+			[]string{"ci-channel"},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			v := resolveRoutes(routes, tc.level, tc.labels, tc.author)
+			if diff := deep.Equal(v, tc.shouldBe); diff != nil {
+				t.Error("For", tc.name, diff)
+			}
+		})
+	}
+
+	t.Run("no_match", func(t *testing.T) {
+		v := resolveRoutes(routes[:1], "info", nil, "CI")
+		if len(v) != 0 {
+			t.Error("expected no destinations, got", v)
+		}
+	})
+}
+
+func TestParseLabels(t *testing.T) {
+	t.Parallel()
+
+	labels, err := parseLabels([]string{"Service=db", "env=prod=eu"})
+	if err != nil {
+		t.Fatal("parseLabels has failed:", err)
+	}
+	shouldBe := map[string]string{"service": "db", "env": "prod=eu"}
+	if diff := deep.Equal(labels, shouldBe); diff != nil {
+		t.Error(diff)
+	}
+
+	for _, invalid := range []string{"service", "=db"} {
+		if _, err := parseLabels([]string{invalid}); err == nil {
+			t.Error("parseLabels should fail for", invalid)
+		}
+	}
+}
@@ -0,0 +1,221 @@
+/*
+  Copyright 2026 Davide Madrisan <d.madrisan@proton.me>
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+      http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/madrisan/go-mattermost-notify/config"
+)
+
+var (
+	// searchFormat is the format of the search results: text or jsonl.
+	searchFormat string
+	// searchFrom is the list of users whose posts are searched.
+	searchFrom []string
+	// searchIn is the list of channels whose posts are searched.
+	searchIn []string
+	// searchLimit is the maximum number of search results.
+	searchLimit int
+	// searchOr tells if the posts matching any of the terms must be returned.
+	searchOr bool
+	// searchSince is the date of the oldest posts searched.
+	searchSince string
+	// searchUntil is the date of the most recent posts searched.
+	searchUntil string
+)
+
+// getSearchTerms returns the search terms of Mattermost for the given words, users, channels,
+// and time range (zero times for no limits). The after: and before: modifiers select whole
+// days in the local time zone, so the posts must be filtered afterwards by inTimeRange.
+func getSearchTerms(words, from, in []string, since, until time.Time) string {
+	var terms = append([]string{}, words...)
+	for _, user := range from {
+		terms = append(terms, "from:"+strings.TrimPrefix(user, "@"))
+	}
+	for _, channel := range in {
+		terms = append(terms, "in:"+strings.TrimPrefix(channel, "~"))
+	}
+
+	// The after: and before: modifiers exclude the given day.
+	if !since.IsZero() {
+		terms = append(terms, "after:"+since.Local().AddDate(0, 0, -1).Format("2006-01-02"))
+	}
+	if !until.IsZero() {
+		day := until.Local()
+		if !day.Equal(startOfDay(day)) {
+			day = day.AddDate(0, 0, 1)
+		}
+		terms = append(terms, "before:"+day.Format("2006-01-02"))
+	}
+	return strings.Join(terms, " ")
+}
+
+// startOfDay returns the start of the day of the given time, in its time zone.
+func startOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// inTimeRange tells if the given time is in the range starting at since and ending before until
+// (zero times for no limits).
+func inTimeRange(t, since, until time.Time) bool {
+	return (since.IsZero() || !t.Before(since)) && (until.IsZero() || t.Before(until))
+}
+
+// searchPosts returns at most limit posts of the given team matching the given terms and created
+// in the given time range, newest first. The usernames and the channel names are resolved.
+func searchPosts(teamID, terms string, isOrSearch bool, since, until time.Time, limit int,
+	opts config.Options) ([]exportedPost, error) {
+	_, offset := time.Now().Zone()
+	var results []exportedPost
+
+	for page := 0; len(results) < limit; page++ {
+		payload, err := json.Marshal(map[string]interface{}{
+			"terms":            terms,
+			"is_or_search":     isOrSearch,
+			"time_zone_offset": offset,
+			"page":             page,
+			"per_page":         postsPerPage,
+		})
+		if err != nil {
+			return nil, err
+		}
+		response, err := mattermostPost("/teams/"+teamID+"/posts/search", bytes.NewReader(payload), opts)
+		if err != nil {
+			return nil, err
+		}
+		posts := getPostList(response)
+		for _, post := range posts {
+			p := newExportedPost(post, false, opts)
+			if len(results) < limit && inTimeRange(p.Time, since, until) {
+				results = append(results, p)
+			}
+		}
+		if len(posts) < postsPerPage {
+			break
+		}
+	}
+
+	// The channels are usually few and looked up once.
+	var channels = make(map[string]string)
+	for i, p := range results {
+		name, found := channels[p.ChannelID]
+		if !found {
+			name = p.ChannelID
+			if response, err := mattermostGet("/channels/"+p.ChannelID, opts); err == nil {
+				if n, err := getKV(response, "name"); err == nil {
+					name = n
+				}
+			}
+			channels[p.ChannelID] = name
+		}
+		results[i].Channel = name
+	}
+
+	if err := setUsernames(results, opts); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// writeSearchResults writes the given search results, one per line.
+func writeSearchResults(w io.Writer, posts []exportedPost) {
+	for _, p := range posts {
+		message := strings.Join(strings.Fields(p.Message), " ")
+		fmt.Fprintf(w, "%s  ~%s  @%s  %s\n", p.Time.Format(exportTimeFormat), p.Channel, p.Username, message)
+	}
+}
+
+// searchCmd represents the search CLI command.
+var searchCmd = &cobra.Command{
+	Use:   "search TERMS...",
+	Short: "Search the posts of a team",
+	Long: `Search the posts of a team containing all the given terms, or any of them with --or.
+The terms can include the Mattermost search modifiers (from:, in:, after:, before:, on:),
+a trailing '*' for a prefix search, and quoted phrases.
+
+The results are listed newest first, one per line, or in the JSON Lines format with
+--format jsonl. The --since and --until times are dates (2006-01-02, whole days included)
+or RFC3339 times, like for the export command.`,
+	Example: `  search --team ops deploy failed --since 2026-09-01
+  search --team ops --from alice --in deploys rollback
+  search --team ops --or "disk full" oom --format jsonl`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var opts = newOptions()
+
+		if searchFormat != "text" && searchFormat != "jsonl" {
+			return fmt.Errorf("invalid format %q (must be text or jsonl)", searchFormat)
+		}
+		if searchLimit <= 0 {
+			return fmt.Errorf("invalid limit %d (must be positive)", searchLimit)
+		}
+		since, until, err := getTimeRange(searchSince, searchUntil)
+		if err != nil {
+			return err
+		}
+		terms := getSearchTerms(args, searchFrom, searchIn, since, until)
+
+		teamID, err := getTeamID(mattermostTeam, opts)
+		if err != nil {
+			return err
+		}
+		posts, err := searchPosts(teamID, terms, searchOr, since, until, searchLimit, opts)
+		if err != nil {
+			return fmt.Errorf("cannot search the posts: %v", err)
+		}
+
+		if searchFormat == "jsonl" {
+			return writeJSONLines(cmd.OutOrStdout(), posts)
+		}
+		writeSearchResults(cmd.OutOrStdout(), posts)
+		return nil
+	},
+}
+
+// init initializes the search command flags.
+func init() {
+	rootCmd.AddCommand(searchCmd)
+
+	searchCmd.Flags().StringVar(&searchFormat,
+		"format", "text", "the format of the results: text or jsonl")
+	searchCmd.Flags().StringArrayVar(&searchFrom,
+		"from", nil, "search the posts of this user (can be repeated)")
+	searchCmd.Flags().StringArrayVar(&searchIn,
+		"in", nil, "search the posts of this channel (can be repeated)")
+	searchCmd.Flags().BoolVarP(&mattermostSkipTLSVerify,
+		"insecure", "i", false, "ignore SSL/TLS certificate check")
+	searchCmd.Flags().IntVar(&searchLimit,
+		"limit", 100, "the maximum number of results")
+	searchCmd.Flags().BoolVar(&searchOr,
+		"or", false, "search the posts containing any of the terms")
+	searchCmd.Flags().StringVar(&searchSince,
+		"since", "", "search the posts created since this date (2006-01-02) or RFC3339 time")
+	searchCmd.Flags().StringVarP(&mattermostTeam,
+		"team", "T", "", "the Mattermost team name or ID (default is the only team of the logged user)")
+	searchCmd.Flags().DurationVarP(&mattermostConnectionTimeout,
+		"timeout", "s", 10*time.Second, "the maximum time in seconds allowed for a Mattermost connection")
+	searchCmd.Flags().StringVar(&searchUntil,
+		"until", "", "search the posts created until this date (2006-01-02, included) or RFC3339 time")
+}
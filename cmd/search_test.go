@@ -0,0 +1,133 @@
+/*
+  Copyright 2026 Davide Madrisan <d.madrisan@proton.me>
+
+  Licensed under the Mozilla Public License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+      https://www.mozilla.org/en-US/MPL/2.0/
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/madrisan/go-mattermost-notify/config"
+	"github.com/madrisan/go-mattermost-notify/mattermost/mattermosttest"
+	"github.com/spf13/viper"
+)
+
+func TestGetSearchTerms(t *testing.T) {
+	day := func(d, h int) time.Time { return time.Date(2026, 9, d, h, 0, 0, 0, time.Local) }
+
+	var testCases = []struct {
+		words, from, in []string
+		since, until    time.Time
+		shouldBe        string
+	}{
+		{[]string{"deploy"}, nil, nil, time.Time{}, time.Time{}, "deploy"},
+		{[]string{"deploy", "failed"}, []string{"@alice", "bob"}, []string{"~deploys"}, time.Time{}, time.Time{},
+			"deploy failed from:alice from:bob in:deploys"},
+		{[]string{"oom"}, nil, nil, day(1, 0), day(30, 0), "oom after:2026-08-31 before:2026-09-30"},
+		{[]string{"oom"}, nil, nil, day(1, 10), day(29, 18), "oom after:2026-08-31 before:2026-09-30"},
+	}
+	for _, tc := range testCases {
+		if terms := getSearchTerms(tc.words, tc.from, tc.in, tc.since, tc.until); terms != tc.shouldBe {
+			t.Errorf("expected %q, got %q", tc.shouldBe, terms)
+		}
+	}
+}
+
+func TestSearchCommand(t *testing.T) {
+	srv := mattermosttest.NewServer()
+	defer srv.Close()
+	ops := srv.AddTeam("ops")
+	alice := srv.AddUser("alice")
+	bob := srv.AddUser("bob")
+
+	defer saveSettings("url", "access-token")()
+	viper.Set("url", srv.URL)
+	viper.Set("access-token", srv.Token)
+
+	var channels = make(map[string]string)
+	for _, name := range []string{"deploys", "alerts"} {
+		id, err := createTeamChannel(teamChannel{TeamID: ops.ID, Name: name}, config.Options{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		channels[name] = id
+	}
+	at := func(d, h int) time.Time { return time.Date(2026, 9, d, h, 0, 0, 0, time.Local) }
+	srv.AddPost(channels["deploys"], alice.ID, "", "Deploy of v1 failed", at(1, 10))
+	srv.AddPost(channels["deploys"], bob.ID, "", "deploy of v2 failed\nrolling back", at(2, 10))
+	srv.AddPost(channels["alerts"], bob.ID, "", "disk full, deploy blocked", at(3, 10))
+	srv.AddPost(channels["deploys"], alice.ID, "", "deploy of v3 done", at(4, 10))
+
+	saved := []interface{}{searchFormat, searchFrom, searchIn, searchLimit, searchOr, searchSince, searchUntil, mattermostTeam}
+	defer func() {
+		searchFormat, searchFrom, searchIn = saved[0].(string), saved[1].([]string), saved[2].([]string)
+		searchLimit, searchOr = saved[3].(int), saved[4].(bool)
+		searchSince, searchUntil, mattermostTeam = saved[5].(string), saved[6].(string), saved[7].(string)
+	}()
+
+	var out bytes.Buffer
+	searchCmd.SetOut(&out)
+	defer searchCmd.SetOut(nil)
+
+	var testCases = []struct {
+		terms        []string
+		from, in     []string
+		since, until string
+		or           bool
+		limit        int
+		shouldBe     []string
+	}{
+		{[]string{"deploy", "failed"}, nil, nil, "", "", false, 100, []string{
+			"2026-09-02 10:00  ~deploys  @bob  deploy of v2 failed rolling back",
+			"2026-09-01 10:00  ~deploys  @alice  Deploy of v1 failed",
+		}},
+		{[]string{"deploy"}, []string{"bob"}, []string{"alerts"}, "", "", false, 100, []string{
+			"2026-09-03 10:00  ~alerts  @bob  disk full, deploy blocked",
+		}},
+		{[]string{"deploy"}, nil, nil, "2026-09-02", "2026-09-03", false, 100, []string{
+			"2026-09-03 10:00  ~alerts  @bob  disk full, deploy blocked",
+			"2026-09-02 10:00  ~deploys  @bob  deploy of v2 failed rolling back",
+		}},
+		{[]string{"deploy"}, nil, nil, at(2, 10).Format(time.RFC3339), at(3, 10).Format(time.RFC3339), false, 100, []string{
+			"2026-09-02 10:00  ~deploys  @bob  deploy of v2 failed rolling back",
+		}},
+		{[]string{"done", "disk"}, nil, nil, "", "", true, 1, []string{
+			"2026-09-04 10:00  ~deploys  @alice  deploy of v3 done",
+		}},
+	}
+	for _, tc := range testCases {
+		out.Reset()
+		searchFormat, searchFrom, searchIn, searchLimit = "text", tc.from, tc.in, tc.limit
+		searchOr, searchSince, searchUntil, mattermostTeam = tc.or, tc.since, tc.until, ""
+		if err := searchCmd.RunE(searchCmd, tc.terms); err != nil {
+			t.Fatalf("%v: cannot search the posts: %v", tc.terms, err)
+		}
+		if lines := strings.Split(strings.TrimSpace(out.String()), "\n"); strings.Join(lines, "|") != strings.Join(tc.shouldBe, "|") {
+			t.Errorf("%v: expected %q, got %q", tc.terms, tc.shouldBe, lines)
+		}
+	}
+
+	out.Reset()
+	searchFormat, searchFrom, searchIn, searchSince, searchUntil, searchOr = "jsonl", nil, nil, "", "", false
+	if err := searchCmd.RunE(searchCmd, []string{"blocked"}); err != nil {
+		t.Fatal("cannot search the posts:", err)
+	}
+	if !strings.Contains(out.String(), `"channel":"alerts"`) || !strings.Contains(out.String(), `"username":"bob"`) {
+		t.Errorf("unexpected JSON Lines results: %s", out.String())
+	}
+}
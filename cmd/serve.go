@@ -0,0 +1,468 @@
+/*
+  Copyright 2026 Davide Madrisan <d.madrisan@proton.me>
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+      http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+*/
+
+package cmd
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	mattermost "github.com/madrisan/go-mattermost-notify/mattermost"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/madrisan/go-mattermost-notify/config"
+)
+
+var (
+	// serveListen is the address the relay server listens to (host:port or unix:///path/to/socket).
+	serveListen string
+	// serveShutdownTimeout is the maximum time allowed to the pending requests to complete at shutdown.
+	serveShutdownTimeout time.Duration
+)
+
+// maxRequestBodySize is the maximum size in bytes of a request sent to the relay server.
+const maxRequestBodySize = 1 << 20
+
+// relayClientKey is the context key of the name of the client authenticated by the relay server.
+type relayClientKey struct{}
+
+// relayRequest is a message received by the relay server.
+type relayRequest struct {
+	Author  string            `json:"author"`
+	Channel string            `json:"channel"`
+	Fields  map[string]string `json:"fields"`
+	Labels  map[string]string `json:"labels"`
+	Level   string            `json:"level"`
+	Message string            `json:"message"`
+	Title   string            `json:"title"`
+}
+
+// relayPost is a post created by the relay server.
+type relayPost struct {
+	Channel string `json:"channel"`
+	ID      string `json:"id"`
+}
+
+// relayServer forwards to Mattermost the messages sent by the local clients.
+type relayServer struct {
+	// apiKeys maps the API keys to the names of the clients.
+	apiKeys map[string]string
+	logger  *log.Logger
+	opts    config.Options
+	// alertThreads contains the posts notifying the firing Alertmanager alert groups.
+	alertThreads memoryThreads
+	// actionToken authenticates the action requests sent by Mattermost.
+	// The endpoint /actions is disabled when it is empty.
+	actionToken string
+	// actionHandlers contains the handlers of the actions, indexed by action ID.
+	actionHandlers map[string]actionHandler
+	// chatops contains the slash commands and outgoing webhooks served on the endpoint /chatops.
+	chatops chatopsConfig
+	// workers limits the number of action and ChatOps commands running concurrently.
+	workers chan struct{}
+	// jobs tracks the running action and ChatOps commands.
+	jobs sync.WaitGroup
+	// jobsCtx is the context of the action and ChatOps commands, canceled by cancelJobs
+	// to kill them when the shutdown timeout expires.
+	jobsCtx    context.Context
+	cancelJobs context.CancelFunc
+}
+
+// waitJobs waits for the action and ChatOps commands to complete until ctx expires, then kills
+// them and waits at most commandWaitDelay more. It tells whether the commands have completed.
+func (s *relayServer) waitJobs(ctx context.Context) bool {
+	var done = make(chan struct{})
+	go func() {
+		s.jobs.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return true
+	case <-ctx.Done():
+	}
+
+	s.cancelJobs()
+	select {
+	case <-done:
+	case <-time.After(commandWaitDelay):
+	}
+	return false
+}
+
+// getAPIKeys returns the API keys of the relay server clients set in the
+// configuration file (section serve.api-keys), indexed by key.
+func getAPIKeys() (map[string]string, error) {
+	var apiKeys = make(map[string]string)
+
+	for client, key := range viper.GetStringMapString("serve.api-keys") {
+		if key == "" {
+			return nil, fmt.Errorf("empty API key for the client %s", client)
+		}
+		if other, found := apiKeys[key]; found {
+			return nil, fmt.Errorf("the clients %s and %s share the same API key", other, client)
+		}
+		apiKeys[key] = client
+	}
+
+	if len(apiKeys) == 0 {
+		return nil, fmt.Errorf("no API key has been set in the configuration file (section serve.api-keys)")
+	}
+
+	return apiKeys, nil
+}
+
+// writeJSON sends to the client the JSON encoding of v with the given HTTP status code.
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeError sends to the client the given error message with the given HTTP status code.
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+// statusRecorder is an http.ResponseWriter recording the HTTP status code sent to the client.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+// WriteHeader records the HTTP status code before sending it.
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// logRequests logs the requests processed by the given handler.
+func (s *relayServer) logRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var start = time.Now()
+		var recorder = &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		var client = "-"
+
+		r = r.WithContext(context.WithValue(r.Context(), relayClientKey{}, &client))
+		next.ServeHTTP(recorder, r)
+
+		s.logger.Printf("%s %s %s %s %d %s",
+			r.RemoteAddr, client, r.Method, r.URL.Path, recorder.status, time.Since(start).Round(time.Millisecond))
+	})
+}
+
+// authenticate rejects the requests that do not provide a valid API key,
+// either in the header 'Authorization: Bearer <key>' or in the header 'X-API-Key'.
+func (s *relayServer) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get("X-API-Key")
+		if bearer, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); found {
+			key = bearer
+		}
+
+		var client string
+		for k, name := range s.apiKeys {
+			if subtle.ConstantTimeCompare([]byte(k), []byte(key)) == 1 {
+				client = name
+			}
+		}
+		if client == "" {
+			writeError(w, http.StatusUnauthorized, fmt.Errorf("invalid or missing API key"))
+			return
+		}
+
+		if p, ok := r.Context().Value(relayClientKey{}).(*string); ok {
+			*p = client
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// parseKeyValues converts a list of strings in the form key=value into a map.
+func parseKeyValues(values []string) (map[string]string, error) {
+	var kv = make(map[string]string, len(values))
+	for _, value := range values {
+		k, v, found := strings.Cut(value, "=")
+		if !found || k == "" {
+			return nil, fmt.Errorf("invalid value \"%s\": must be in the form key=value", value)
+		}
+		kv[k] = v
+	}
+	return kv, nil
+}
+
+// parseRelayRequest decodes the message sent by a client either as a JSON document or as a form.
+func parseRelayRequest(r *http.Request) (*relayRequest, error) {
+	var req relayRequest
+
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			return nil, fmt.Errorf("invalid JSON request: %v", err)
+		}
+		return &req, nil
+	}
+
+	if err := r.ParseMultipartForm(maxRequestBodySize); err != nil && !errors.Is(err, http.ErrNotMultipart) {
+		return nil, fmt.Errorf("invalid form request: %v", err)
+	}
+
+	req.Author = r.FormValue("author")
+	req.Channel = r.FormValue("channel")
+	req.Level = r.FormValue("level")
+	req.Message = r.FormValue("message")
+	req.Title = r.FormValue("title")
+
+	var err error
+	if req.Fields, err = parseKeyValues(r.Form["fields"]); err != nil {
+		return nil, err
+	}
+	if req.Labels, err = parseKeyValues(r.Form["labels"]); err != nil {
+		return nil, err
+	}
+
+	return &req, nil
+}
+
+// getMsgFields converts the given map into attachment fields sorted by title.
+func getMsgFields(fields map[string]string) []mattermost.MsgField {
+	var titles = make([]string, 0, len(fields))
+	for title := range fields {
+		titles = append(titles, title)
+	}
+	sort.Strings(titles)
+
+	var msgFields = make([]mattermost.MsgField, 0, len(fields))
+	for _, title := range titles {
+		msgFields = append(msgFields, mattermost.MsgField{
+			Title: title,
+			Value: fields[title],
+			Short: true,
+		})
+	}
+	return msgFields
+}
+
+// handlePost forwards to Mattermost the message sent by a client.
+func (s *relayServer) handlePost(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestBodySize)
+
+	req, err := parseRelayRequest(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if req.Message == "" {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("the message is empty"))
+		return
+	}
+	if req.Level == "" {
+		req.Level = "info"
+	}
+	if req.Author == "" {
+		if p, ok := r.Context().Value(relayClientKey{}).(*string); ok {
+			req.Author = *p
+		}
+	}
+
+	msg := message{
+		Author:  req.Author,
+		Labels:  normalizeLabels(req.Labels),
+		Level:   req.Level,
+		Text:    req.Message,
+		Title:   req.Title,
+		Options: []mattermost.MsgOption{mattermost.WithFields(getMsgFields(req.Fields))},
+	}
+
+	sent, err := postMessage(req.Channel, msg, false, s.opts)
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+
+	var posts []relayPost
+	for _, post := range sent {
+		postID, _ := getKV(post.Response, "id")
+		posts = append(posts, relayPost{Channel: post.Destination, ID: postID})
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"posts": posts})
+}
+
+// handler returns the HTTP handler of the relay server.
+func (s *relayServer) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("POST /post", s.authenticate(http.HandlerFunc(s.handlePost)))
+	mux.Handle("POST /alertmanager", s.authenticate(http.HandlerFunc(s.handleAlertmanager)))
+	if s.actionToken != "" {
+		// Mattermost authenticates with the token set in the context of the actions.
+		mux.HandleFunc("POST /actions", s.handleAction)
+	}
+	if len(s.chatops.Commands) > 0 {
+		// Mattermost authenticates with the token of the slash command or outgoing webhook.
+		mux.HandleFunc("POST /chatops", s.handleChatops)
+	}
+
+	return s.logRequests(mux)
+}
+
+// listen announces on the given address, either host:port or unix:///path/to/socket.
+func listen(address string) (net.Listener, error) {
+	if path, found := strings.CutPrefix(address, "unix://"); found {
+		// Remove the socket left by a previous instance.
+		if fi, err := os.Stat(path); err == nil && fi.Mode()&os.ModeSocket != 0 {
+			if err := os.Remove(path); err != nil {
+				return nil, err
+			}
+		}
+		return net.Listen("unix", path)
+	}
+
+	return net.Listen("tcp", address)
+}
+
+// serveCmd represents the serve CLI command.
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Run a local HTTP server relaying messages to Mattermost",
+	Long: `Run a long-lived HTTP server that forwards to Mattermost the messages sent
+by the local clients, so that they do not need to know the Mattermost access token.
+
+The messages are sent to the endpoint /post as a JSON document or a form with the
+keys title, message, level, channel, author, fields and labels. When no channel is
+set, the message is routed according to the 'routes' section of the configuration file.
+
+The Prometheus Alertmanager webhook notifications are received on the endpoint
+/alertmanager. The destination channel can be set with the 'channel' query parameter.
+
+The clicks on the buttons and menus added by 'post --action' and 'post --menu' are received
+on the endpoint /actions, which runs the command or renders the template set for the action
+in the 'actions.handlers' section of the configuration file, and updates the post with the result.
+
+The Mattermost slash commands and outgoing webhooks set in the 'chatops.commands' section of
+the configuration file are served on the endpoint /chatops.
+
+The clients authenticate with an API key sent in the header 'Authorization: Bearer <key>'
+or 'X-API-Key'. The API keys are set in the configuration file:
+
+  serve:
+    api-keys:
+      backup-script: 2f1d6e5c0b3a4f0e9d8c7b6a5f4e3d2c
+      cron: 7a6b5c4d3e2f1a0b9c8d7e6f5a4b3c2d`,
+	Example: `  serve --listen 127.0.0.1:8066
+  serve --listen unix:///run/go-mattermost-notify.sock
+
+  curl -H "X-API-Key: 2f1d6e5c0b3a4f0e9d8c7b6a5f4e3d2c" \
+    -d title="Backup" -d message="Backup completed" -d level=success \
+    -d channel=rybfbdi9ojy8xxxjjxc88kh3me -d fields=Host=db1 \
+    http://127.0.0.1:8066/post`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var opts = newOptions()
+		// All the requests share the same client and connection pool.
+		opts.HTTPClient = mattermost.NewHTTPClient(opts)
+
+		apiKeys, err := getAPIKeys()
+		if err != nil {
+			return err
+		}
+
+		actionHandlers, err := getActionHandlers()
+		if err != nil {
+			return err
+		}
+
+		chatops, err := getChatopsConfig()
+		if err != nil {
+			return err
+		}
+
+		s := &relayServer{
+			apiKeys:        apiKeys,
+			logger:         log.New(os.Stderr, "", log.LstdFlags),
+			opts:           opts,
+			actionToken:    viper.GetString("actions.token"),
+			actionHandlers: actionHandlers,
+			chatops:        chatops,
+			workers:        make(chan struct{}, chatops.Workers),
+		}
+		s.jobsCtx, s.cancelJobs = context.WithCancel(context.Background())
+		defer s.cancelJobs()
+
+		listener, err := listen(serveListen)
+		if err != nil {
+			return err
+		}
+
+		srv := &http.Server{
+			Handler:           s.handler(),
+			ReadHeaderTimeout: 10 * time.Second,
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		var serveErr = make(chan error, 1)
+		go func() {
+			s.logger.Printf("listening on %s", serveListen)
+			serveErr <- srv.Serve(listener)
+		}()
+
+		select {
+		case err := <-serveErr:
+			return err
+		case <-ctx.Done():
+		}
+
+		s.logger.Printf("shutting down")
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), serveShutdownTimeout)
+		defer cancel()
+
+		err = srv.Shutdown(shutdownCtx)
+		if !s.waitJobs(shutdownCtx) {
+			s.logger.Printf("the pending commands have been killed")
+		}
+
+		return err
+	},
+}
+
+// init initializes the serve command flags.
+func init() {
+	rootCmd.AddCommand(serveCmd)
+
+	serveCmd.Flags().BoolVarP(&mattermostSkipTLSVerify,
+		"insecure", "i", false, "ignore SSL/TLS certificate check")
+	serveCmd.Flags().StringVarP(&serveListen,
+		"listen", "L", "127.0.0.1:8066", "the address to listen to: host:port or unix:///path/to/socket")
+	serveCmd.Flags().DurationVar(&serveShutdownTimeout,
+		"shutdown-timeout", 30*time.Second, "the maximum time allowed to the pending requests and commands to complete at shutdown")
+	serveCmd.Flags().DurationVarP(&mattermostConnectionTimeout,
+		"timeout", "s", 10*time.Second, "the maximum time in seconds allowed for a Mattermost connection")
+}
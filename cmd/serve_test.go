@@ -0,0 +1,163 @@
+/*
+  Copyright 2026 Davide Madrisan <d.madrisan@proton.me>
+
+  Licensed under the Mozilla Public License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+      https://www.mozilla.org/en-US/MPL/2.0/
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+*/
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/madrisan/go-mattermost-notify/config"
+	mattermost "github.com/madrisan/go-mattermost-notify/mattermost"
+)
+
+func TestRelayServer(t *testing.T) {
+	oldMattermostPost := mattermostPost
+	defer func() {
+		mattermostPost = oldMattermostPost
+	}()
+
+	var posted []mattermost.MsgPayload
+	mattermostPost = func(endpoint string, payload io.Reader, opts config.Options) (interface{}, error) {
+		var data mattermost.MsgPayload
+		if err := json.NewDecoder(payload).Decode(&data); err != nil {
+			return nil, err
+		}
+		posted = append(posted, data)
+		return map[string]interface{}{"id": "post1"}, nil
+	}
+
+	s := &relayServer{
+		apiKeys: map[string]string{"secret": "cron"},
+		logger:  log.New(io.Discard, "", 0),
+	}
+	srv := httptest.NewServer(s.handler())
+	defer srv.Close()
+
+	t.Run("unauthorized", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodPost, srv.URL+"/post", strings.NewReader("message=hello"))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set("X-API-Key", "wrong")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusUnauthorized {
+			t.Error("expected status 401, got", resp.StatusCode)
+		}
+	})
+
+	t.Run("json", func(t *testing.T) {
+		posted = nil
+		body := `{"channel":"channel1","title":"Backup","message":"done","level":"success","fields":{"Host":"db1","Duration":"3m"}}`
+		req, _ := http.NewRequest(http.MethodPost, srv.URL+"/post", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer secret")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatal("expected status 200, got", resp.StatusCode)
+		}
+		if len(posted) != 1 {
+			t.Fatal("expected one post, got", len(posted))
+		}
+		attachment := posted[0].Properties.Attachments[0]
+		if posted[0].ID != "channel1" || attachment.Author != "cron" || attachment.Color != colorSuccess {
+			t.Error("unexpected payload", posted[0])
+		}
+		if len(attachment.Fields) != 2 || attachment.Fields[0].Title != "Duration" {
+			t.Error("unexpected fields", attachment.Fields)
+		}
+	})
+
+	t.Run("form", func(t *testing.T) {
+		posted = nil
+		form := url.Values{
+			"channel": {"channel2"},
+			"author":  {"backup"},
+			"message": {"failed"},
+			"level":   {"critical"},
+			"fields":  {"Host=db2"},
+		}
+		req, _ := http.NewRequest(http.MethodPost, srv.URL+"/post", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set("X-API-Key", "secret")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK || len(posted) != 1 {
+			t.Fatal("unexpected status", resp.StatusCode, "or posts", posted)
+		}
+		attachment := posted[0].Properties.Attachments[0]
+		if posted[0].ID != "channel2" || attachment.Author != "backup" || attachment.Fields[0].Value != "db2" {
+			t.Error("unexpected payload", posted[0])
+		}
+	})
+}
+
+func TestWaitJobs(t *testing.T) {
+	t.Setenv("GO_WANT_CHATOPS_HELPER_PROCESS", "1")
+	helper := []string{os.Args[0], "-test.run=TestChatopsHelperProcess", "--", "hang"}
+
+	s := &relayServer{workers: make(chan struct{}, 1)}
+	s.jobsCtx, s.cancelJobs = context.WithCancel(context.Background())
+	defer s.cancelJobs()
+
+	// The second command waits for the worker used by the first one.
+	var results = make(chan string, 2)
+	for i := 0; i < 2; i++ {
+		s.jobs.Add(1)
+		go func() {
+			defer s.jobs.Done()
+			_, timedOut, output := s.runJobCommand(helper, nil, time.Minute)
+			if timedOut {
+				t.Error("the command should not time out")
+			}
+			results <- output
+		}()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if s.waitJobs(ctx) {
+		t.Error("the commands should be reported as killed")
+	}
+	if d := time.Since(start); d > commandWaitDelay {
+		t.Error("the commands should be killed when the timeout expires, waited", d)
+	}
+
+	for i := 0; i < 2; i++ {
+		if output := <-results; !strings.Contains(output, "killed at shutdown") {
+			t.Errorf("unexpected output %q", output)
+		}
+	}
+}
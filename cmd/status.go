@@ -0,0 +1,449 @@
+/*
+  Copyright 2026 Davide Madrisan <d.madrisan@proton.me>
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+      http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/madrisan/go-mattermost-notify/config"
+)
+
+var (
+	// statusUser is the user whose status is shown.
+	statusUser string
+	// statusUntil is the end of the "dnd" status.
+	statusUntil string
+	// statusEmoji is the emoji of the custom status.
+	statusEmoji string
+	// statusText is the text of the custom status.
+	statusText string
+	// statusDuration is how long the custom status lasts.
+	statusDuration string
+)
+
+// userStatuses are the statuses a Mattermost user can be set to.
+var userStatuses = []string{"online", "away", "dnd", "offline"}
+
+// customStatus is a Mattermost custom status.
+// Duration is one of the Mattermost durations, like "today" or "date_and_time", or empty
+// for a status that is never cleared.
+type customStatus struct {
+	Emoji     string `json:"emoji"`
+	Text      string `json:"text"`
+	Duration  string `json:"duration,omitempty"`
+	ExpiresAt string `json:"expires_at,omitempty"`
+}
+
+// parseEndTime returns the end time described by s, which is a duration from now (like "2h"),
+// an RFC3339 time, or a local time of the day in the form "15:04" (tomorrow if already passed).
+func parseEndTime(s string, now time.Time) (time.Time, error) {
+	if d, err := time.ParseDuration(s); err == nil {
+		if d <= 0 {
+			return time.Time{}, fmt.Errorf("the duration %s is not positive", s)
+		}
+		return now.Add(d), nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		if !t.After(now) {
+			return time.Time{}, fmt.Errorf("the time %s is in the past", s)
+		}
+		return t, nil
+	}
+	if t, err := time.ParseInLocation("15:04", s, now.Location()); err == nil {
+		end := time.Date(now.Year(), now.Month(), now.Day(), t.Hour(), t.Minute(), 0, 0, now.Location())
+		if !end.After(now) {
+			end = end.AddDate(0, 0, 1)
+		}
+		return end, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q: expected a duration (2h), an RFC3339 time or a time of the day (15:04)", s)
+}
+
+// getCustomStatus returns the custom status with the given text and emoji, lasting for the given
+// duration: "today", "this_week", "dont_clear" (the default), or anything accepted by parseEndTime.
+func getCustomStatus(emoji, text, duration string, now time.Time) (customStatus, error) {
+	var status = customStatus{Emoji: strings.Trim(emoji, ":"), Text: text}
+	var expiresAt time.Time
+
+	switch duration {
+	case "", "dont_clear":
+		return status, nil
+	case "today":
+		expiresAt = time.Date(now.Year(), now.Month(), now.Day(), 23, 59, 59, 0, now.Location())
+	case "this_week":
+		daysToSunday := (7 - int(now.Weekday())) % 7
+		expiresAt = time.Date(now.Year(), now.Month(), now.Day()+daysToSunday, 23, 59, 59, 0, now.Location())
+	default:
+		end, err := parseEndTime(duration, now)
+		if err != nil {
+			return status, err
+		}
+		duration, expiresAt = "date_and_time", end
+	}
+
+	status.Duration, status.ExpiresAt = duration, expiresAt.UTC().Format(time.RFC3339)
+	return status, nil
+}
+
+// getUserStatus returns the status of the user with the given ID, and the end of the "dnd" status
+// (zero if none).
+func getUserStatus(userID string, opts config.Options) (string, time.Time, error) {
+	response, err := mattermostGet("/users/"+userID+"/status", opts)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	status, err := getKV(response, "status")
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	var end time.Time
+	data, _ := response.(map[string]interface{})
+	if dndEndTime, _ := data["dnd_end_time"].(float64); dndEndTime > 0 && status == "dnd" {
+		end = time.Unix(int64(dndEndTime), 0)
+	}
+	return status, end, nil
+}
+
+// setUserStatus sets the status of the user with the given ID. The end time, if not zero,
+// ends the "dnd" status.
+func setUserStatus(userID, status string, end time.Time, opts config.Options) error {
+	var valid bool
+	for _, s := range userStatuses {
+		valid = valid || s == status
+	}
+	if !valid {
+		return fmt.Errorf("invalid status %q, must be one of: %s", status, strings.Join(userStatuses, ", "))
+	}
+
+	var request = map[string]interface{}{
+		"user_id": userID,
+		"status":  status,
+	}
+	if !end.IsZero() {
+		request["dnd_end_time"] = end.Unix()
+	}
+	payload, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+	_, err = mattermostPut("/users/"+userID+"/status", bytes.NewReader(payload), opts)
+	return err
+}
+
+// getUserCustomStatus returns the custom status of the user with the given ID, nil if none is set.
+// Mattermost keeps it JSON encoded in the user properties.
+func getUserCustomStatus(userID string, opts config.Options) (*customStatus, error) {
+	response, err := mattermostGet("/users/"+userID, opts)
+	if err != nil {
+		return nil, err
+	}
+	user, _ := response.(map[string]interface{})
+	props, _ := user["props"].(map[string]interface{})
+	data, _ := props["customStatus"].(string)
+	if data == "" {
+		return nil, nil
+	}
+
+	var status customStatus
+	if err := json.Unmarshal([]byte(data), &status); err != nil {
+		return nil, fmt.Errorf("invalid custom status: %v", err)
+	}
+	if status.Emoji == "" && status.Text == "" {
+		return nil, nil
+	}
+	if expiresAt, err := time.Parse(time.RFC3339, status.ExpiresAt); err == nil && status.Duration != "" && expiresAt.Before(time.Now()) {
+		return nil, nil
+	}
+	return &status, nil
+}
+
+// setCustomStatus sets the custom status of the logged user.
+func setCustomStatus(status customStatus, opts config.Options) error {
+	payload, err := json.Marshal(status)
+	if err != nil {
+		return err
+	}
+	_, err = mattermostPut("/users/me/status/custom", bytes.NewReader(payload), opts)
+	return err
+}
+
+// clearCustomStatus clears the custom status of the logged user.
+func clearCustomStatus(opts config.Options) error {
+	_, err := mattermostDelete("/users/me/status/custom", opts)
+	return err
+}
+
+// formatCustomStatus returns the given custom status as shown by Mattermost.
+func formatCustomStatus(status customStatus) string {
+	var parts []string
+	if status.Emoji != "" {
+		parts = append(parts, ":"+status.Emoji+":")
+	}
+	if status.Text != "" {
+		parts = append(parts, status.Text)
+	}
+	s := strings.Join(parts, " ")
+	if expiresAt, err := time.Parse(time.RFC3339, status.ExpiresAt); err == nil && status.Duration != "" {
+		s += " (until " + expiresAt.Local().Format("2006-01-02 15:04") + ")"
+	}
+	return s
+}
+
+// setStatusWhileRunning sets the status and, if emoji or text are given, the custom status of the
+// logged user. It returns the function restoring the previous ones, to be called when done.
+func setStatusWhileRunning(status, emoji, text string, opts config.Options) (func() error, error) {
+	userID, err := getLoggedUserID(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var restore []func() error
+	restoreAll := func() error {
+		var errs []string
+		for i := len(restore) - 1; i >= 0; i-- {
+			if err := restore[i](); err != nil {
+				errs = append(errs, err.Error())
+			}
+		}
+		if len(errs) > 0 {
+			return fmt.Errorf("cannot restore the status: %s", strings.Join(errs, "; "))
+		}
+		return nil
+	}
+
+	if status != "" {
+		previous, end, err := getUserStatus(userID, opts)
+		if err != nil {
+			return nil, err
+		}
+		if err := setUserStatus(userID, status, time.Time{}, opts); err != nil {
+			return nil, err
+		}
+		restore = append(restore, func() error {
+			if !end.IsZero() && !end.After(time.Now()) {
+				// The "dnd" status would have ended in the meantime.
+				previous, end = "online", time.Time{}
+			}
+			return setUserStatus(userID, previous, end, opts)
+		})
+	}
+
+	if emoji != "" || text != "" {
+		previous, err := getUserCustomStatus(userID, opts)
+		if err == nil {
+			err = setCustomStatus(customStatus{Emoji: strings.Trim(emoji, ":"), Text: text}, opts)
+		}
+		if err != nil {
+			restoreAll()
+			return nil, err
+		}
+		restore = append(restore, func() error {
+			if previous == nil {
+				return clearCustomStatus(opts)
+			}
+			return setCustomStatus(*previous, opts)
+		})
+	}
+
+	return restoreAll, nil
+}
+
+// showStatus writes the status and the custom status of the user with the given ID.
+func showStatus(w io.Writer, userID string, opts config.Options) error {
+	status, end, err := getUserStatus(userID, opts)
+	if err != nil {
+		return err
+	}
+	custom, err := getUserCustomStatus(userID, opts)
+	if err != nil {
+		return err
+	}
+
+	if !end.IsZero() {
+		status += " (until " + end.Local().Format("2006-01-02 15:04") + ")"
+	}
+	fmt.Fprintln(w, "Status:", status)
+	if custom != nil {
+		fmt.Fprintln(w, "Custom status:", formatCustomStatus(*custom))
+	}
+	return nil
+}
+
+// statusCmd represents the status CLI command.
+var statusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show or set the Mattermost user status",
+	Long: `Show or set the status (online, away, dnd, offline) and the custom status
+(an emoji and a text) of the logged user.
+
+The exec command can also set the status for the duration of a command.`,
+}
+
+// statusGetCmd represents the status get CLI command.
+var statusGetCmd = &cobra.Command{
+	Use:   "get",
+	Short: "Show the status and the custom status of a user",
+	Example: `  status get
+  status get --user @alice`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var opts = newOptions()
+
+		userID, err := resolveUserID(statusUser, opts)
+		if err != nil {
+			return fmt.Errorf("cannot find the user %s: %v", statusUser, err)
+		}
+		return showStatus(cmd.OutOrStdout(), userID, opts)
+	},
+}
+
+// statusSetCmd represents the status set CLI command.
+var statusSetCmd = &cobra.Command{
+	Use:   "set online|away|dnd|offline",
+	Short: "Set the status of the logged user",
+	Long: `Set the status of the logged user. The "dnd" (do not disturb) status can end
+at a given time (--until), expressed as a duration from now (2h), an RFC3339 time,
+or a time of the day (18:30).`,
+	Example: `  status set away
+  status set dnd --until 2h
+  status set dnd --until 18:30`,
+	Args:      cobra.ExactArgs(1),
+	ValidArgs: userStatuses,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var opts = newOptions()
+
+		var end time.Time
+		if statusUntil != "" {
+			if args[0] != "dnd" {
+				return fmt.Errorf("--until can only be used with the dnd status")
+			}
+			var err error
+			if end, err = parseEndTime(statusUntil, time.Now()); err != nil {
+				return err
+			}
+		}
+
+		userID, err := getLoggedUserID(opts)
+		if err != nil {
+			return err
+		}
+		if err := setUserStatus(userID, args[0], end, opts); err != nil {
+			return fmt.Errorf("cannot set the status: %v", err)
+		}
+
+		if !viper.GetBool("quiet") {
+			msg := "Status set to " + args[0]
+			if !end.IsZero() {
+				msg += " until " + end.Local().Format("2006-01-02 15:04")
+			}
+			fmt.Fprintln(cmd.OutOrStdout(), msg)
+		}
+		return nil
+	},
+}
+
+// statusCustomCmd represents the status custom CLI command.
+var statusCustomCmd = &cobra.Command{
+	Use:   "custom",
+	Short: "Set or clear the custom status of the logged user",
+}
+
+// statusCustomSetCmd represents the status custom set CLI command.
+var statusCustomSetCmd = &cobra.Command{
+	Use:   "set",
+	Short: "Set the custom status of the logged user",
+	Long: `Set the custom status of the logged user: an emoji and a text.
+
+The custom status is cleared after the given duration: "today", "this_week",
+a duration from now (30m), an RFC3339 time, or a time of the day (18:30).
+By default it's never cleared ("dont_clear").`,
+	Example: `  status custom set --emoji rocket --text "Deploying v1.2" --duration 1h
+  status custom set --emoji palm_tree --text "On vacation" --duration this_week`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var opts = newOptions()
+
+		if statusEmoji == "" && statusText == "" {
+			return fmt.Errorf("at least one of --emoji and --text is required")
+		}
+		status, err := getCustomStatus(statusEmoji, statusText, statusDuration, time.Now())
+		if err != nil {
+			return err
+		}
+		if err := setCustomStatus(status, opts); err != nil {
+			return fmt.Errorf("cannot set the custom status: %v", err)
+		}
+
+		if !viper.GetBool("quiet") {
+			fmt.Fprintln(cmd.OutOrStdout(), "Custom status set to", formatCustomStatus(status))
+		}
+		return nil
+	},
+}
+
+// statusCustomClearCmd represents the status custom clear CLI command.
+var statusCustomClearCmd = &cobra.Command{
+	Use:   "clear",
+	Short: "Clear the custom status of the logged user",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var opts = newOptions()
+
+		if err := clearCustomStatus(opts); err != nil {
+			return fmt.Errorf("cannot clear the custom status: %v", err)
+		}
+		if !viper.GetBool("quiet") {
+			fmt.Fprintln(cmd.OutOrStdout(), "Custom status cleared")
+		}
+		return nil
+	},
+}
+
+// init initializes the status command flags.
+func init() {
+	rootCmd.AddCommand(statusCmd)
+	statusCmd.AddCommand(statusGetCmd)
+	statusCmd.AddCommand(statusSetCmd)
+	statusCmd.AddCommand(statusCustomCmd)
+	statusCustomCmd.AddCommand(statusCustomSetCmd)
+	statusCustomCmd.AddCommand(statusCustomClearCmd)
+
+	statusCmd.PersistentFlags().BoolVarP(&mattermostSkipTLSVerify,
+		"insecure", "i", false, "ignore SSL/TLS certificate check")
+	statusCmd.PersistentFlags().DurationVarP(&mattermostConnectionTimeout,
+		"timeout", "s", 10*time.Second, "the maximum time in seconds allowed for a Mattermost connection")
+
+	statusGetCmd.Flags().StringVar(&statusUser,
+		"user", "", "the username or ID of the user (default is the logged user)")
+	statusSetCmd.Flags().StringVar(&statusUntil,
+		"until", "", "the end of the dnd status: a duration (2h), an RFC3339 time or a time of the day (18:30)")
+	statusCustomSetCmd.Flags().StringVar(&statusDuration,
+		"duration", "", `when the custom status is cleared: "today", "this_week", a duration, an RFC3339 time or a time of the day`)
+	statusCustomSetCmd.Flags().StringVar(&statusEmoji,
+		"emoji", "", "the emoji name of the custom status. Example: rocket")
+	statusCustomSetCmd.Flags().StringVar(&statusText,
+		"text", "", "the text of the custom status")
+}
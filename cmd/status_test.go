@@ -0,0 +1,225 @@
+/*
+  Copyright 2026 Davide Madrisan <d.madrisan@proton.me>
+
+  Licensed under the Mozilla Public License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+      https://www.mozilla.org/en-US/MPL/2.0/
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/madrisan/go-mattermost-notify/config"
+	"github.com/madrisan/go-mattermost-notify/mattermost/mattermosttest"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func TestParseEndTime(t *testing.T) {
+	now := time.Date(2026, 10, 19, 14, 30, 0, 0, time.UTC)
+
+	var testCases = []struct {
+		s        string
+		shouldBe time.Time
+		isValid  bool
+	}{
+		{"2h", now.Add(2 * time.Hour), true},
+		{"90m", now.Add(90 * time.Minute), true},
+		{"-1h", time.Time{}, false},
+		{"2026-10-20T08:00:00Z", time.Date(2026, 10, 20, 8, 0, 0, 0, time.UTC), true},
+		{"2026-10-18T08:00:00Z", time.Time{}, false},
+		{"18:00", time.Date(2026, 10, 19, 18, 0, 0, 0, time.UTC), true},
+		{"09:15", time.Date(2026, 10, 20, 9, 15, 0, 0, time.UTC), true},
+		{"14:30", time.Date(2026, 10, 20, 14, 30, 0, 0, time.UTC), true},
+		{"tomorrow", time.Time{}, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.s, func(t *testing.T) {
+			end, err := parseEndTime(tc.s, now)
+			if (err == nil) != tc.isValid {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !end.Equal(tc.shouldBe) {
+				t.Errorf("expected %v, got %v", tc.shouldBe, end)
+			}
+		})
+	}
+}
+
+func TestGetCustomStatus(t *testing.T) {
+	// A Monday.
+	now := time.Date(2026, 10, 19, 14, 30, 0, 0, time.UTC)
+
+	var testCases = []struct {
+		duration  string
+		shouldBe  customStatus
+		isInvalid bool
+	}{
+		{"", customStatus{Emoji: "rocket", Text: "Deploying"}, false},
+		{"dont_clear", customStatus{Emoji: "rocket", Text: "Deploying"}, false},
+		{"today", customStatus{"rocket", "Deploying", "today", "2026-10-19T23:59:59Z"}, false},
+		{"this_week", customStatus{"rocket", "Deploying", "this_week", "2026-10-25T23:59:59Z"}, false},
+		{"30m", customStatus{"rocket", "Deploying", "date_and_time", "2026-10-19T15:00:00Z"}, false},
+		{"forever", customStatus{}, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.duration, func(t *testing.T) {
+			status, err := getCustomStatus(":rocket:", "Deploying", tc.duration, now)
+			if tc.isInvalid {
+				if err == nil {
+					t.Error("an invalid duration should be rejected")
+				}
+				return
+			}
+			if err != nil || status != tc.shouldBe {
+				t.Errorf("expected %+v, got %+v (%v)", tc.shouldBe, status, err)
+			}
+		})
+	}
+}
+
+func TestStatusCommands(t *testing.T) {
+	srv := mattermosttest.NewServer()
+	defer srv.Close()
+	alice := srv.AddUser("alice")
+
+	defer saveSettings("url", "access-token", "quiet")()
+	viper.Set("url", srv.URL)
+	viper.Set("access-token", srv.Token)
+	viper.Set("quiet", false)
+
+	var out bytes.Buffer
+	for _, c := range []*cobra.Command{statusGetCmd, statusSetCmd, statusCustomSetCmd, statusCustomClearCmd} {
+		c.SetOut(&out)
+		defer c.SetOut(nil)
+	}
+
+	oldUser, oldUntil, oldEmoji, oldText, oldDuration := statusUser, statusUntil, statusEmoji, statusText, statusDuration
+	defer func() {
+		statusUser, statusUntil, statusEmoji, statusText, statusDuration = oldUser, oldUntil, oldEmoji, oldText, oldDuration
+	}()
+
+	statusUntil = "2h"
+	if err := statusSetCmd.RunE(statusSetCmd, []string{"away"}); err == nil {
+		t.Error("--until should be rejected for the away status")
+	}
+	if err := statusSetCmd.RunE(statusSetCmd, []string{"dnd"}); err != nil {
+		t.Fatal("cannot set the status:", err)
+	}
+	if status, end := srv.Status(srv.Me().ID); status != "dnd" || time.Until(end) < time.Hour {
+		t.Errorf("expected the dnd status for 2h, got %s until %v", status, end)
+	}
+	statusUntil = ""
+	if err := statusSetCmd.RunE(statusSetCmd, []string{"busy"}); err == nil {
+		t.Error("an invalid status should be rejected")
+	}
+
+	statusEmoji, statusText, statusDuration = "rocket", "Deploying v1.2", "today"
+	if err := statusCustomSetCmd.RunE(statusCustomSetCmd, nil); err != nil {
+		t.Fatal("cannot set the custom status:", err)
+	}
+	if custom := srv.CustomStatus(srv.Me().ID); !strings.Contains(custom, `"text":"Deploying v1.2"`) ||
+		!strings.Contains(custom, `"duration":"today"`) {
+		t.Errorf("unexpected custom status: %s", custom)
+	}
+
+	out.Reset()
+	if err := statusGetCmd.RunE(statusGetCmd, nil); err != nil {
+		t.Fatal("cannot get the status:", err)
+	}
+	if !strings.Contains(out.String(), "Status: dnd (until ") ||
+		!strings.Contains(out.String(), "Custom status: :rocket: Deploying v1.2 (until ") {
+		t.Errorf("unexpected status:\n%s", out.String())
+	}
+
+	out.Reset()
+	statusUser = "@alice"
+	if err := statusGetCmd.RunE(statusGetCmd, nil); err != nil {
+		t.Fatal("cannot get the status of alice:", err)
+	}
+	if out.String() != "Status: online\n" {
+		t.Errorf("unexpected status of alice:\n%s", out.String())
+	}
+	if status, _ := srv.Status(alice.ID); status != "online" {
+		t.Errorf("the status of alice should not change, got %s", status)
+	}
+
+	if err := statusCustomClearCmd.RunE(statusCustomClearCmd, nil); err != nil {
+		t.Fatal("cannot clear the custom status:", err)
+	}
+	if custom := srv.CustomStatus(srv.Me().ID); custom != "" {
+		t.Errorf("the custom status should be cleared, got %s", custom)
+	}
+}
+
+func TestSetStatusWhileRunning(t *testing.T) {
+	srv := mattermosttest.NewServer()
+	defer srv.Close()
+
+	defer saveSettings("url", "access-token")()
+	viper.Set("url", srv.URL)
+	viper.Set("access-token", srv.Token)
+
+	var opts = config.Options{}
+	userID := srv.Me().ID
+	if err := setUserStatus(userID, "away", time.Time{}, opts); err != nil {
+		t.Fatal("cannot set the status:", err)
+	}
+	previous := customStatus{Emoji: "coffee", Text: "Break"}
+	if err := setCustomStatus(previous, opts); err != nil {
+		t.Fatal("cannot set the custom status:", err)
+	}
+
+	restore, err := setStatusWhileRunning("dnd", "rocket", "Deploying", opts)
+	if err != nil {
+		t.Fatal("cannot set the status:", err)
+	}
+	if status, _ := srv.Status(userID); status != "dnd" {
+		t.Errorf("expected the dnd status, got %s", status)
+	}
+	if custom := srv.CustomStatus(userID); !strings.Contains(custom, `"text":"Deploying"`) {
+		t.Errorf("unexpected custom status: %s", custom)
+	}
+
+	if err := restore(); err != nil {
+		t.Fatal("cannot restore the status:", err)
+	}
+	if status, _ := srv.Status(userID); status != "away" {
+		t.Errorf("the away status should be restored, got %s", status)
+	}
+	if custom := srv.CustomStatus(userID); !strings.Contains(custom, `"text":"Break"`) {
+		t.Errorf("the previous custom status should be restored, got %s", custom)
+	}
+
+	// Without a previous custom status, the custom status is cleared.
+	if err := clearCustomStatus(opts); err != nil {
+		t.Fatal("cannot clear the custom status:", err)
+	}
+	if restore, err = setStatusWhileRunning("", "", "Deploying", opts); err != nil {
+		t.Fatal("cannot set the custom status:", err)
+	}
+	if err := restore(); err != nil {
+		t.Fatal("cannot restore the status:", err)
+	}
+	if custom := srv.CustomStatus(userID); custom != "" {
+		t.Errorf("the custom status should be cleared, got %s", custom)
+	}
+	if status, _ := srv.Status(userID); status != "away" {
+		t.Errorf("the status should not change, got %s", status)
+	}
+}
@@ -0,0 +1,583 @@
+/*
+  Copyright 2026 Davide Madrisan <d.madrisan@proton.me>
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+      http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	mattermost "github.com/madrisan/go-mattermost-notify/mattermost"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
+
+	"github.com/madrisan/go-mattermost-notify/config"
+)
+
+var (
+	// syncFormat is the format of the plan: text or json.
+	syncFormat string
+	// syncPrune tells if the members not listed in the sync file must be removed.
+	syncPrune bool
+)
+
+// The actions of the changes computed by planSync.
+const (
+	syncCreateChannel       = "create_channel"
+	syncUnarchiveChannel    = "unarchive_channel"
+	syncUpdateChannel       = "update_channel"
+	syncAddTeamMember       = "add_team_member"
+	syncRemoveTeamMember    = "remove_team_member"
+	syncAddChannelMember    = "add_channel_member"
+	syncRemoveChannelMember = "remove_channel_member"
+)
+
+// channelTypes maps the channel types of the sync file to the Mattermost ones.
+var channelTypes = map[string]string{
+	"open":    "O",
+	"private": "P",
+}
+
+// channelNameRegexp matches the valid names of the team channels.
+var channelNameRegexp = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,63}$`)
+
+// syncFile is the description of the Mattermost teams, channels and members kept in sync.
+type syncFile struct {
+	Teams []syncTeam `yaml:"teams"`
+}
+
+// syncTeam is a team of the sync file. The team members are managed only when Members is set.
+type syncTeam struct {
+	Name     string        `yaml:"name"`
+	Members  []string      `yaml:"members"`
+	Channels []syncChannel `yaml:"channels"`
+}
+
+// syncChannel is a channel of the sync file. Only the settings that are set are managed:
+// for instance a channel without header keeps the one it has in Mattermost.
+type syncChannel struct {
+	Name        string   `yaml:"name"`
+	DisplayName *string  `yaml:"display_name"`
+	Type        string   `yaml:"type"`
+	Header      *string  `yaml:"header"`
+	Purpose     *string  `yaml:"purpose"`
+	Members     []string `yaml:"members"`
+}
+
+// syncChange is a change needed to bring Mattermost in sync with the sync file.
+type syncChange struct {
+	Action  string `json:"action"`
+	Team    string `json:"team"`
+	Channel string `json:"channel,omitempty"`
+	User    string `json:"user,omitempty"`
+	// Field, Old and New describe the setting changed by an update_channel change.
+	Field string  `json:"field,omitempty"`
+	Old   *string `json:"old,omitempty"`
+	New   *string `json:"new,omitempty"`
+	// Settings are the settings of the channel created by a create_channel change.
+	Settings map[string]string `json:"settings,omitempty"`
+
+	// teamID, channelID and userID are the Mattermost IDs the change applies to.
+	// The channelID of a channel created by the plan is only known when it's applied.
+	teamID    string
+	channelID string
+	userID    string
+}
+
+// syncPlan is the list of changes printed by the sync plan command.
+type syncPlan struct {
+	Changes []syncChange `json:"changes"`
+}
+
+// String returns the change in a diff-like form: '+' for an addition, '-' for a removal
+// and '~' for a change.
+func (c syncChange) String() string {
+	channel := c.Team + "/~" + c.Channel
+	switch c.Action {
+	case syncCreateChannel:
+		var keys, settings []string
+		for key := range c.Settings {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			settings = append(settings, key+"="+strconv.Quote(c.Settings[key]))
+		}
+		return fmt.Sprintf("+ channel %s (%s)", channel, strings.Join(settings, ", "))
+	case syncUnarchiveChannel:
+		return fmt.Sprintf("~ channel %s: unarchive", channel)
+	case syncUpdateChannel:
+		return fmt.Sprintf("~ channel %s: %s %s => %s", channel, c.Field, strconv.Quote(*c.Old), strconv.Quote(*c.New))
+	case syncAddTeamMember:
+		return fmt.Sprintf("+ team %s: member @%s", c.Team, c.User)
+	case syncRemoveTeamMember:
+		return fmt.Sprintf("- team %s: member @%s", c.Team, c.User)
+	case syncAddChannelMember:
+		return fmt.Sprintf("+ channel %s: member @%s", channel, c.User)
+	case syncRemoveChannelMember:
+		return fmt.Sprintf("- channel %s: member @%s", channel, c.User)
+	}
+	return c.Action
+}
+
+// readSyncFile reads and checks the sync file (or the standard input for -).
+func readSyncFile(file string) (*syncFile, error) {
+	var r io.Reader = os.Stdin
+	if file != "-" {
+		f, err := os.Open(file)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	var sf syncFile
+	decoder := yaml.NewDecoder(r)
+	// A typo in a setting must not silently leave it unmanaged.
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&sf); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("invalid sync file %s: %v", file, err)
+	}
+
+	var teams = make(map[string]bool)
+	for i, team := range sf.Teams {
+		if team.Name == "" || teams[team.Name] {
+			return nil, fmt.Errorf("invalid sync file %s: missing or duplicate team name %q", file, team.Name)
+		}
+		teams[team.Name] = true
+		sf.Teams[i].Members = trimUsernames(team.Members)
+
+		var channels = make(map[string]bool)
+		for j, channel := range team.Channels {
+			name := strings.TrimPrefix(channel.Name, "~")
+			if !channelNameRegexp.MatchString(name) || channels[name] {
+				return nil, fmt.Errorf("invalid sync file %s: invalid or duplicate channel name %q in the team %s",
+					file, channel.Name, team.Name)
+			}
+			if _, found := channelTypes[channel.Type]; channel.Type != "" && !found {
+				return nil, fmt.Errorf("invalid sync file %s: invalid type %q of the channel %s (must be open or private)",
+					file, channel.Type, channel.Name)
+			}
+			channels[name] = true
+			sf.Teams[i].Channels[j].Name = name
+			sf.Teams[i].Channels[j].Members = trimUsernames(channel.Members)
+		}
+	}
+	return &sf, nil
+}
+
+// trimUsernames removes the leading '@' from the given usernames, keeping a nil list nil.
+func trimUsernames(usernames []string) []string {
+	if usernames == nil {
+		return nil
+	}
+	var trimmed = make([]string, len(usernames))
+	for i, username := range usernames {
+		trimmed[i] = strings.TrimPrefix(username, "@")
+	}
+	return trimmed
+}
+
+// syncPlanner computes the changes needed to bring Mattermost in sync with a sync file.
+type syncPlanner struct {
+	opts  config.Options
+	prune bool
+	// userIDs caches the IDs of the users, indexed by username.
+	userIDs map[string]string
+	// loggedUser is the username of the logged user, who is the first member of the created channels.
+	loggedUser string
+}
+
+// userID returns the ID of the user with the given username.
+func (p *syncPlanner) userID(username string) (string, error) {
+	if id, found := p.userIDs[username]; found {
+		return id, nil
+	}
+	id, err := getUserID(username, p.opts)
+	if err != nil {
+		return "", fmt.Errorf("cannot find the user @%s: %v", username, err)
+	}
+	p.userIDs[username] = id
+	return id, nil
+}
+
+// memberChanges returns the changes with the given actions adding the desired members missing from
+// the current ones (usernames indexed by user ID) and, when pruning, removing the extra ones.
+func (p *syncPlanner) memberChanges(base syncChange, addAction, removeAction string,
+	desired []string, current map[string]string) ([]syncChange, []syncChange, error) {
+	var adds, removes []syncChange
+	var wanted = make(map[string]bool)
+	for _, username := range desired {
+		userID, err := p.userID(username)
+		if err != nil {
+			return nil, nil, err
+		}
+		if wanted[userID] {
+			continue
+		}
+		wanted[userID] = true
+		if _, found := current[userID]; !found {
+			change := base
+			change.Action, change.User, change.userID = addAction, username, userID
+			adds = append(adds, change)
+		}
+	}
+
+	if p.prune {
+		for userID, username := range current {
+			if !wanted[userID] {
+				change := base
+				change.Action, change.User, change.userID = removeAction, username, userID
+				removes = append(removes, change)
+			}
+		}
+		sort.Slice(removes, func(i, j int) bool { return removes[i].User < removes[j].User })
+	}
+	return adds, removes, nil
+}
+
+// channelChanges returns the changes of the given channel of the team with the given ID.
+func (p *syncPlanner) channelChanges(team string, teamID string, channel syncChannel) ([]syncChange, error) {
+	var base = syncChange{Team: team, Channel: channel.Name, teamID: teamID}
+	var changes []syncChange
+
+	endpoint := fmt.Sprintf("/teams/%s/channels/name/%s?include_deleted=true", teamID, url.PathEscape(channel.Name))
+	response, err := mattermostGet(endpoint, p.opts)
+	var apiErr *mattermost.APIError
+	if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound {
+		// The channel is missing: it's created with the logged user as its only member.
+		create := base
+		create.Action = syncCreateChannel
+		create.Settings = map[string]string{"type": "open"}
+		if channel.Type != "" {
+			create.Settings["type"] = channel.Type
+		}
+		for key, value := range map[string]*string{
+			"display_name": channel.DisplayName,
+			"header":       channel.Header,
+			"purpose":      channel.Purpose,
+		} {
+			if value != nil && *value != "" {
+				create.Settings[key] = *value
+			}
+		}
+		changes = append(changes, create)
+
+		if channel.Members != nil {
+			if p.loggedUser == "" {
+				if p.loggedUser, err = getLoggedUsername(p.opts); err != nil {
+					return nil, err
+				}
+			}
+			loggedID, err := p.userID(p.loggedUser)
+			if err != nil {
+				return nil, err
+			}
+			adds, removes, err := p.memberChanges(base, syncAddChannelMember, syncRemoveChannelMember,
+				channel.Members, map[string]string{loggedID: p.loggedUser})
+			if err != nil {
+				return nil, err
+			}
+			changes = append(append(changes, adds...), removes...)
+		}
+		return changes, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot get the channel %s/~%s: %v", team, channel.Name, err)
+	}
+
+	current, _ := response.(map[string]interface{})
+	base.channelID, _ = current["id"].(string)
+	if deleteAt, _ := current["delete_at"].(float64); deleteAt != 0 {
+		change := base
+		change.Action = syncUnarchiveChannel
+		changes = append(changes, change)
+	}
+
+	currentType, _ := current["type"].(string)
+	for name, value := range channelTypes {
+		if value == currentType {
+			currentType = name
+		}
+	}
+	var desiredType *string
+	if channel.Type != "" {
+		desiredType = &channel.Type
+	}
+	for _, field := range []struct {
+		name  string
+		value *string
+	}{
+		{"type", desiredType},
+		{"display_name", channel.DisplayName},
+		{"header", channel.Header},
+		{"purpose", channel.Purpose},
+	} {
+		old, _ := current[field.name].(string)
+		if field.name == "type" {
+			old = currentType
+		}
+		if field.value != nil && *field.value != old {
+			change := base
+			change.Action, change.Field, change.Old, change.New = syncUpdateChannel, field.name, &old, field.value
+			changes = append(changes, change)
+		}
+	}
+
+	if channel.Members != nil {
+		members, err := getMembers("/channels/"+base.channelID+"/members", p.opts)
+		if err != nil {
+			return nil, fmt.Errorf("cannot get the members of the channel %s/~%s: %v", team, channel.Name, err)
+		}
+		adds, removes, err := p.memberChanges(base, syncAddChannelMember, syncRemoveChannelMember, channel.Members, members)
+		if err != nil {
+			return nil, err
+		}
+		changes = append(append(changes, adds...), removes...)
+	}
+	return changes, nil
+}
+
+// planSync returns the changes needed to bring Mattermost in sync with the given sync file.
+// The members not listed are removed only when prune is set. The teams are not created.
+func planSync(sf *syncFile, prune bool, opts config.Options) ([]syncChange, error) {
+	var p = syncPlanner{opts: opts, prune: prune, userIDs: make(map[string]string)}
+	var changes = []syncChange{}
+
+	for _, team := range sf.Teams {
+		response, err := mattermostGet("/teams/name/"+url.PathEscape(team.Name), opts)
+		if err != nil {
+			return nil, fmt.Errorf("cannot find the team %s: %v", team.Name, err)
+		}
+		teamID, err := getKV(response, "id")
+		if err != nil {
+			return nil, err
+		}
+
+		// The users are added to the team before being added to its channels, and removed
+		// from the team, which also removes them from its channels, at the end.
+		var teamRemoves []syncChange
+		if team.Members != nil {
+			members, err := getMembers("/teams/"+teamID+"/members", opts)
+			if err != nil {
+				return nil, fmt.Errorf("cannot get the members of the team %s: %v", team.Name, err)
+			}
+			var adds []syncChange
+			adds, teamRemoves, err = p.memberChanges(syncChange{Team: team.Name, teamID: teamID},
+				syncAddTeamMember, syncRemoveTeamMember, team.Members, members)
+			if err != nil {
+				return nil, err
+			}
+			changes = append(changes, adds...)
+		}
+
+		for _, channel := range team.Channels {
+			channelChanges, err := p.channelChanges(team.Name, teamID, channel)
+			if err != nil {
+				return nil, err
+			}
+			changes = append(changes, channelChanges...)
+		}
+		changes = append(changes, teamRemoves...)
+	}
+	return changes, nil
+}
+
+// applySyncChange applies the given change. The IDs of the channels created are recorded in
+// channelIDs, indexed by team ID and channel name, for the following changes.
+func applySyncChange(c syncChange, channelIDs map[string]string, opts config.Options) error {
+	channelID := c.channelID
+	if channelID == "" {
+		channelID = channelIDs[c.teamID+"/"+c.Channel]
+	}
+
+	var err error
+	switch c.Action {
+	case syncCreateChannel:
+		channelIDs[c.teamID+"/"+c.Channel], err = createTeamChannel(teamChannel{
+			TeamID:      c.teamID,
+			Name:        c.Channel,
+			DisplayName: c.Settings["display_name"],
+			Type:        channelTypes[c.Settings["type"]],
+			Header:      c.Settings["header"],
+			Purpose:     c.Settings["purpose"],
+		}, opts)
+	case syncUnarchiveChannel:
+		_, err = mattermostPost("/channels/"+channelID+"/restore", nil, opts)
+	case syncUpdateChannel:
+		if c.Field == "type" {
+			var payload []byte
+			if payload, err = json.Marshal(map[string]string{"privacy": channelTypes[*c.New]}); err == nil {
+				_, err = mattermostPut("/channels/"+channelID+"/privacy", bytes.NewReader(payload), opts)
+			}
+		} else {
+			err = patchChannel(channelID, map[string]string{c.Field: *c.New}, opts)
+		}
+	case syncAddTeamMember:
+		var payload []byte
+		if payload, err = json.Marshal(map[string]string{"team_id": c.teamID, "user_id": c.userID}); err == nil {
+			_, err = mattermostPost("/teams/"+c.teamID+"/members", bytes.NewReader(payload), opts)
+		}
+	case syncRemoveTeamMember:
+		_, err = mattermostDelete("/teams/"+c.teamID+"/members/"+c.userID, opts)
+	case syncAddChannelMember:
+		err = addChannelMember(channelID, c.userID, opts)
+	case syncRemoveChannelMember:
+		err = removeChannelMember(channelID, c.userID, opts)
+	default:
+		err = fmt.Errorf("unknown action %s", c.Action)
+	}
+	return err
+}
+
+// writePlan writes the given changes in the given format: text or json.
+func writePlan(w io.Writer, changes []syncChange, format string) error {
+	switch format {
+	case "json":
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(syncPlan{Changes: changes})
+	case "text":
+		if len(changes) == 0 {
+			fmt.Fprintln(w, "No changes: Mattermost is in sync.")
+			return nil
+		}
+		for _, c := range changes {
+			fmt.Fprintln(w, c)
+		}
+		fmt.Fprintf(w, "%d change(s) to apply\n", len(changes))
+		return nil
+	}
+	return fmt.Errorf("invalid format %q (must be text or json)", format)
+}
+
+// syncCmd represents the sync CLI command.
+var syncCmd = &cobra.Command{
+	Use:   "sync",
+	Short: "Sync the Mattermost channels and members with a YAML file",
+	Long: `Compare a YAML description of teams, channels and members against Mattermost
+(sync plan), and apply the changes needed to bring Mattermost in sync (sync apply).
+
+  teams:
+    - name: ops
+      members: [alice, bob, carol]   # optional: the team members
+      channels:
+        - name: deploys
+          display_name: Deploys
+          type: private              # open or private
+          header: "Runbook: https://wiki.example.com/deploys"
+          purpose: The CI deploys
+          members: [alice, bob]      # optional: the channel members
+
+The teams must exist. The missing channels are created, and the archived ones are
+unarchived. The settings and member lists that are omitted are left unchanged,
+and the members not listed are removed only with --prune.`,
+}
+
+// syncPlanCmd represents the sync plan CLI command.
+var syncPlanCmd = &cobra.Command{
+	Use:   "plan FILE",
+	Short: "Print the changes needed to bring Mattermost in sync with a YAML file",
+	Long: `Print the changes needed to bring Mattermost in sync with a YAML file (or the
+standard input for -), without applying them. The JSON format (--format json) is
+meant for the reviews and the automations.`,
+	Example: `  sync plan mattermost.yaml
+  sync plan --prune --format json mattermost.yaml > plan.json`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var opts = newOptions()
+
+		if syncFormat != "text" && syncFormat != "json" {
+			return fmt.Errorf("invalid format %q (must be text or json)", syncFormat)
+		}
+		sf, err := readSyncFile(args[0])
+		if err != nil {
+			return err
+		}
+		changes, err := planSync(sf, syncPrune, opts)
+		if err != nil {
+			return err
+		}
+		return writePlan(cmd.OutOrStdout(), changes, syncFormat)
+	},
+}
+
+// syncApplyCmd represents the sync apply CLI command.
+var syncApplyCmd = &cobra.Command{
+	Use:   "apply FILE",
+	Short: "Bring Mattermost in sync with a YAML file",
+	Long: `Compute the changes needed to bring Mattermost in sync with a YAML file (or the
+standard input for -) like sync plan, and apply them in order, stopping at the first error.`,
+	Example: `  sync apply mattermost.yaml
+  sync apply --prune mattermost.yaml`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var opts = newOptions()
+
+		sf, err := readSyncFile(args[0])
+		if err != nil {
+			return err
+		}
+		changes, err := planSync(sf, syncPrune, opts)
+		if err != nil {
+			return err
+		}
+
+		var channelIDs = make(map[string]string)
+		for i, c := range changes {
+			if err := applySyncChange(c, channelIDs, opts); err != nil {
+				return fmt.Errorf("cannot apply the change %q (%d change(s) applied): %v", c, i, err)
+			}
+			if !viper.GetBool("quiet") {
+				fmt.Fprintln(cmd.OutOrStdout(), c)
+			}
+		}
+		if !viper.GetBool("quiet") {
+			fmt.Fprintf(cmd.OutOrStdout(), "%d change(s) applied\n", len(changes))
+		}
+		return nil
+	},
+}
+
+// init initializes the sync command flags.
+func init() {
+	rootCmd.AddCommand(syncCmd)
+	syncCmd.AddCommand(syncPlanCmd)
+	syncCmd.AddCommand(syncApplyCmd)
+
+	syncCmd.PersistentFlags().BoolVarP(&mattermostSkipTLSVerify,
+		"insecure", "i", false, "ignore SSL/TLS certificate check")
+	syncCmd.PersistentFlags().BoolVar(&syncPrune,
+		"prune", false, "remove the members not listed in the file")
+	syncCmd.PersistentFlags().DurationVarP(&mattermostConnectionTimeout,
+		"timeout", "s", 10*time.Second, "the maximum time in seconds allowed for a Mattermost connection")
+
+	syncPlanCmd.Flags().StringVar(&syncFormat,
+		"format", "text", "the format of the plan: text or json")
+}
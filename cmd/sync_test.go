@@ -0,0 +1,226 @@
+/*
+  Copyright 2026 Davide Madrisan <d.madrisan@proton.me>
+
+  Licensed under the Mozilla Public License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+      https://www.mozilla.org/en-US/MPL/2.0/
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/madrisan/go-mattermost-notify/config"
+	"github.com/madrisan/go-mattermost-notify/mattermost/mattermosttest"
+	"github.com/spf13/viper"
+)
+
+// writeSyncFile writes a sync file with the given content in a temporary directory.
+func writeSyncFile(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "mattermost.yaml")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestReadSyncFile(t *testing.T) {
+	var testCases = []struct {
+		name    string
+		content string
+		isValid bool
+	}{
+		{"empty", "", true},
+		{"valid", "teams:\n  - name: ops\n    channels:\n      - name: ~deploys\n        type: private\n        members: ['@alice']\n", true},
+		{"unknown setting", "teams:\n  - name: ops\n    channels:\n      - name: deploys\n        headr: typo\n", false},
+		{"missing team name", "teams:\n  - channels: []\n", false},
+		{"duplicate team", "teams:\n  - name: ops\n  - name: ops\n", false},
+		{"invalid channel name", "teams:\n  - name: ops\n    channels:\n      - name: Deploys\n", false},
+		{"duplicate channel", "teams:\n  - name: ops\n    channels:\n      - name: deploys\n      - name: ~deploys\n", false},
+		{"invalid type", "teams:\n  - name: ops\n    channels:\n      - name: deploys\n        type: secret\n", false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			sf, err := readSyncFile(writeSyncFile(t, tc.content))
+			if (err == nil) != tc.isValid {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tc.name == "valid" {
+				channel := sf.Teams[0].Channels[0]
+				if channel.Name != "deploys" || channel.Members[0] != "alice" || sf.Teams[0].Members != nil {
+					t.Errorf("unexpected sync file: %+v", sf)
+				}
+			}
+		})
+	}
+}
+
+func TestSyncCommands(t *testing.T) {
+	srv := mattermosttest.NewServer()
+	defer srv.Close()
+	ops := srv.AddTeam("ops")
+	alice := srv.AddUser("alice")
+	bob := srv.AddUser("bob")
+	carol := srv.AddUser("carol")
+
+	defer saveSettings("url", "access-token", "quiet")()
+	viper.Set("url", srv.URL)
+	viper.Set("access-token", srv.Token)
+	viper.Set("quiet", false)
+
+	// The current layout: carol is in the team and in ~deploys, and ~old-incident is archived.
+	var opts = config.Options{}
+	carolInTeam := syncChange{Action: syncAddTeamMember, teamID: ops.ID, userID: carol.ID}
+	if err := applySyncChange(carolInTeam, nil, opts); err != nil {
+		t.Fatal(err)
+	}
+	deploysID, err := createTeamChannel(teamChannel{TeamID: ops.ID, Name: "deploys", Header: "old header"}, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := addChannelMember(deploysID, carol.ID, opts); err != nil {
+		t.Fatal(err)
+	}
+	oldIncidentID, err := createTeamChannel(teamChannel{TeamID: ops.ID, Name: "old-incident"}, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := mattermostDelete("/channels/"+oldIncidentID, opts); err != nil {
+		t.Fatal(err)
+	}
+
+	path := writeSyncFile(t, `
+teams:
+  - name: ops
+    members: [bot, alice, bob]
+    channels:
+      - name: deploys
+        type: private
+        header: "Runbook: https://wiki.example.com/deploys"
+        members: [bot, alice]
+      - name: incidents
+        display_name: Incidents
+        purpose: The incident reports
+        members: ["@bob"]
+      - name: old-incident
+`)
+
+	var out bytes.Buffer
+	syncPlanCmd.SetOut(&out)
+	defer syncPlanCmd.SetOut(nil)
+	syncApplyCmd.SetOut(&out)
+	defer syncApplyCmd.SetOut(nil)
+
+	oldFormat, oldPrune := syncFormat, syncPrune
+	defer func() { syncFormat, syncPrune = oldFormat, oldPrune }()
+	syncFormat, syncPrune = "json", true
+
+	if err := syncPlanCmd.RunE(syncPlanCmd, []string{path}); err != nil {
+		t.Fatal("cannot plan the sync:", err)
+	}
+	var plan syncPlan
+	if err := json.Unmarshal(out.Bytes(), &plan); err != nil {
+		t.Fatalf("the plan is not valid JSON: %v\n%s", err, out.String())
+	}
+	var actions []string
+	for _, c := range plan.Changes {
+		actions = append(actions, c.Action+" "+c.Channel+" "+c.Field+c.User)
+	}
+	var shouldBe = []string{
+		"add_team_member  alice",
+		"add_team_member  bob",
+		"update_channel deploys type",
+		"update_channel deploys header",
+		"add_channel_member deploys alice",
+		"remove_channel_member deploys carol",
+		"create_channel incidents ",
+		"add_channel_member incidents bob",
+		"remove_channel_member incidents bot",
+		"unarchive_channel old-incident ",
+		"remove_team_member  carol",
+	}
+	if strings.Join(actions, "\n") != strings.Join(shouldBe, "\n") {
+		t.Fatalf("unexpected plan:\n%s", out.String())
+	}
+	if c := plan.Changes[3]; *c.Old != "old header" || !strings.HasPrefix(*c.New, "Runbook") {
+		t.Errorf("unexpected header change: %+v", c)
+	}
+	if settings := plan.Changes[6].Settings; settings["type"] != "open" || settings["display_name"] != "Incidents" {
+		t.Errorf("unexpected channel settings: %v", settings)
+	}
+
+	out.Reset()
+	if err := syncApplyCmd.RunE(syncApplyCmd, []string{path}); err != nil {
+		t.Fatalf("cannot apply the sync: %v\n%s", err, out.String())
+	}
+	if !strings.HasSuffix(out.String(), "11 change(s) applied\n") {
+		t.Errorf("unexpected apply output:\n%s", out.String())
+	}
+
+	if c := srv.Channel(deploysID); c.Type != "P" || !strings.HasPrefix(c.Header, "Runbook") {
+		t.Errorf("unexpected ~deploys: %+v", c)
+	}
+	if members := srv.ChannelMembers(deploysID); strings.Join(members, " ") != srv.Me().ID+" "+alice.ID {
+		t.Errorf("unexpected members of ~deploys: %v", members)
+	}
+	if members := srv.TeamMembers(ops.ID); strings.Join(members, " ") != srv.Me().ID+" "+alice.ID+" "+bob.ID {
+		t.Errorf("unexpected members of the team: %v", members)
+	}
+	if c := srv.Channel(oldIncidentID); c.DeleteAt != 0 {
+		t.Error("~old-incident should be unarchived")
+	}
+	incidentsID, err := resolveChannelID("incidents", "ops", false, opts)
+	if err != nil {
+		t.Fatal("~incidents should be created:", err)
+	}
+	if members := srv.ChannelMembers(incidentsID); len(members) != 1 || members[0] != bob.ID {
+		t.Errorf("unexpected members of ~incidents: %v", members)
+	}
+
+	// Mattermost is now in sync.
+	out.Reset()
+	syncFormat = "text"
+	if err := syncPlanCmd.RunE(syncPlanCmd, []string{path}); err != nil {
+		t.Fatal("cannot plan the sync:", err)
+	}
+	if out.String() != "No changes: Mattermost is in sync.\n" {
+		t.Errorf("unexpected plan after the sync:\n%s", out.String())
+	}
+}
+
+func TestSyncChangeString(t *testing.T) {
+	old, header := "", "Runbook"
+	var testCases = []struct {
+		change   syncChange
+		shouldBe string
+	}{
+		{syncChange{Action: syncCreateChannel, Team: "ops", Channel: "deploys",
+			Settings: map[string]string{"type": "open", "purpose": "CI"}}, `+ channel ops/~deploys (purpose="CI", type="open")`},
+		{syncChange{Action: syncUnarchiveChannel, Team: "ops", Channel: "deploys"}, "~ channel ops/~deploys: unarchive"},
+		{syncChange{Action: syncUpdateChannel, Team: "ops", Channel: "deploys", Field: "header", Old: &old, New: &header},
+			`~ channel ops/~deploys: header "" => "Runbook"`},
+		{syncChange{Action: syncAddTeamMember, Team: "ops", User: "alice"}, "+ team ops: member @alice"},
+		{syncChange{Action: syncRemoveChannelMember, Team: "ops", Channel: "deploys", User: "bob"}, "- channel ops/~deploys: member @bob"},
+	}
+
+	for _, tc := range testCases {
+		if s := tc.change.String(); s != tc.shouldBe {
+			t.Errorf("expected %q, got %q", tc.shouldBe, s)
+		}
+	}
+}
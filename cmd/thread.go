@@ -0,0 +1,117 @@
+/*
+  Copyright 2026 Davide Madrisan <d.madrisan@proton.me>
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+      http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/spf13/viper"
+
+	"github.com/madrisan/go-mattermost-notify/state"
+)
+
+// threadStore records the IDs of the root posts of the threads, indexed by thread key.
+type threadStore interface {
+	// get returns the ID of the root post of the thread, or an empty string if unknown.
+	get(key string) (string, error)
+	// set records the ID of the root post of the thread, or forgets the thread if postID is empty.
+	set(key, postID string) error
+}
+
+// memoryThreads is a threadStore kept in memory, used by long-running processes.
+type memoryThreads struct {
+	mu    sync.Mutex
+	posts map[string]string
+}
+
+// get returns the ID of the root post of the thread.
+func (t *memoryThreads) get(key string) (string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.posts[key], nil
+}
+
+// set records or forgets the ID of the root post of the thread.
+func (t *memoryThreads) set(key, postID string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.posts == nil {
+		t.posts = make(map[string]string)
+	}
+	if postID == "" {
+		delete(t.posts, key)
+	} else {
+		t.posts[key] = postID
+	}
+	return nil
+}
+
+// stateThreads is a threadStore saved in a state file, so it is shared by successive processes.
+type stateThreads struct {
+	file *state.File
+}
+
+// get returns the ID of the root post of the thread.
+func (t *stateThreads) get(key string) (string, error) {
+	var posts map[string]string
+	if err := t.file.Load(&posts); err != nil {
+		return "", err
+	}
+	return posts[key], nil
+}
+
+// set records or forgets the ID of the root post of the thread.
+func (t *stateThreads) set(key, postID string) error {
+	var posts map[string]string
+	return t.file.Update(&posts, func() error {
+		if posts == nil {
+			posts = make(map[string]string)
+		}
+		if postID == "" {
+			delete(posts, key)
+		} else {
+			posts[key] = postID
+		}
+		return nil
+	})
+}
+
+// getStateDir returns the directory containing the state files, set in the configuration
+// file (key state.dir) or located in the user cache directory.
+func getStateDir() (string, error) {
+	dir := viper.GetString("state.dir")
+	if dir == "" {
+		cacheDir, err := os.UserCacheDir()
+		if err != nil {
+			return "", fmt.Errorf("cannot find the state directory: %v", err)
+		}
+		dir = filepath.Join(cacheDir, "go-mattermost-notify", "state")
+	}
+	return dir, nil
+}
+
+// getStateFile returns the state file with the given name, located in the state directory.
+func getStateFile(name string) (*state.File, error) {
+	dir, err := getStateDir()
+	if err != nil {
+		return nil, err
+	}
+	return state.New(filepath.Join(dir, name+".json")), nil
+}
@@ -0,0 +1,350 @@
+/*
+  Copyright 2026 Davide Madrisan <d.madrisan@proton.me>
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+      http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	mattermost "github.com/madrisan/go-mattermost-notify/mattermost"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/madrisan/go-mattermost-notify/config"
+	"github.com/madrisan/go-mattermost-notify/fileutil"
+)
+
+var (
+	// tokenUser is the user (username or ID) whose tokens are managed, the logged user if empty.
+	tokenUser string
+	// tokenDescription is the description of the token to create.
+	tokenDescription string
+	// tokenToProfile is the profile of the configuration file the new token is saved to.
+	tokenToProfile string
+	// tokenToFile is the file the new token is saved to.
+	tokenToFile string
+	// tokenMaxAge is the time of use above which a token is reported as old.
+	tokenMaxAge time.Duration
+	// tokenUnusedFor is the inactivity time above which a token is reported as unused.
+	tokenUnusedFor time.Duration
+)
+
+// tokenUsage is the usage of an access token, from the session Mattermost opens at its first use.
+type tokenUsage struct {
+	FirstUsed time.Time
+	LastUsed  time.Time
+}
+
+// formatDays returns the given duration in days.
+func formatDays(d time.Duration) string {
+	return fmt.Sprintf("%dd", int(d.Hours()/24))
+}
+
+// getTokenWarnings returns the reasons why a token should be revoked: inactive, never used,
+// in use for more than maxAge, or unused for more than unusedFor (zero disables the checks).
+func getTokenWarnings(active bool, usage *tokenUsage, now time.Time, maxAge, unusedFor time.Duration) []string {
+	var warnings []string
+	if !active {
+		warnings = append(warnings, "inactive")
+	}
+	if usage == nil {
+		return append(warnings, "never used")
+	}
+	if age := now.Sub(usage.FirstUsed); maxAge > 0 && age > maxAge {
+		warnings = append(warnings, "old (in use for "+formatDays(age)+")")
+	}
+	if idle := now.Sub(usage.LastUsed); unusedFor > 0 && idle > unusedFor {
+		warnings = append(warnings, "unused for "+formatDays(idle))
+	}
+	return warnings
+}
+
+// getTokenUsages returns the usage of the access tokens of the given user, indexed by token ID.
+func getTokenUsages(userID string, opts config.Options) (map[string]*tokenUsage, error) {
+	response, err := mattermostGet("/users/"+userID+"/sessions", opts)
+	if err != nil {
+		return nil, err
+	}
+	sessions, err := getList(response)
+	if err != nil {
+		return nil, err
+	}
+
+	var usages = make(map[string]*tokenUsage)
+	for _, s := range sessions {
+		session, _ := s.(map[string]interface{})
+		props, _ := session["props"].(map[string]interface{})
+		tokenID, _ := props["user_access_token_id"].(string)
+		if tokenID == "" {
+			continue
+		}
+		usage := &tokenUsage{
+			FirstUsed: getMillis(session, "create_at"),
+			LastUsed:  getMillis(session, "last_activity_at"),
+		}
+		// A token used again after its session has been revoked has several sessions.
+		if previous, found := usages[tokenID]; found {
+			if previous.FirstUsed.Before(usage.FirstUsed) {
+				usage.FirstUsed = previous.FirstUsed
+			}
+			if previous.LastUsed.After(usage.LastUsed) {
+				usage.LastUsed = previous.LastUsed
+			}
+		}
+		usages[tokenID] = usage
+	}
+	return usages, nil
+}
+
+// listTokens writes the access tokens of the given user in a table, with the reasons why they
+// should be revoked.
+func listTokens(w io.Writer, user string, maxAge, unusedFor time.Duration, opts config.Options) error {
+	userID, err := resolveUserID(user, opts)
+	if err != nil {
+		return err
+	}
+	usages, err := getTokenUsages(userID, opts)
+	if err != nil {
+		return err
+	}
+
+	var now = time.Now()
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tDESCRIPTION\tFIRST USED\tLAST USED\tWARNINGS")
+
+	for page := 0; ; page++ {
+		endpoint := fmt.Sprintf("/users/%s/tokens?page=%d&per_page=%d", userID, page, usersPerPage)
+		response, err := mattermostGet(endpoint, opts)
+		if err != nil {
+			return err
+		}
+		tokens, err := getList(response)
+		if err != nil {
+			return err
+		}
+
+		for _, t := range tokens {
+			token, _ := t.(map[string]interface{})
+			id, _ := getKV(token, "id")
+			description, _ := getKV(token, "description")
+			active, _ := token["is_active"].(bool)
+
+			var firstUsed, lastUsed = "-", "-"
+			usage := usages[id]
+			if usage != nil {
+				firstUsed = usage.FirstUsed.Local().Format("2006-01-02 15:04")
+				lastUsed = usage.LastUsed.Local().Format("2006-01-02 15:04")
+			}
+			warnings := strings.Join(getTokenWarnings(active, usage, now, maxAge, unusedFor), ", ")
+			if warnings == "" {
+				warnings = "-"
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", id, description, firstUsed, lastUsed, warnings)
+		}
+		if len(tokens) < usersPerPage {
+			break
+		}
+	}
+	return tw.Flush()
+}
+
+// createToken creates an access token for the given user and returns its ID and the token.
+func createToken(user, description string, opts config.Options) (string, string, error) {
+	userID, err := resolveUserID(user, opts)
+	if err != nil {
+		return "", "", err
+	}
+
+	payload, err := json.Marshal(map[string]string{"description": description})
+	if err != nil {
+		return "", "", err
+	}
+	response, err := mattermostPost("/users/"+userID+"/tokens", bytes.NewReader(payload), opts)
+	if err != nil {
+		return "", "", err
+	}
+	tokenID, err := getKV(response, "id")
+	if err != nil {
+		return "", "", err
+	}
+	token, err := getKV(response, "token")
+	return tokenID, token, err
+}
+
+// revokeToken revokes the access token with the given ID.
+func revokeToken(tokenID string, opts config.Options) error {
+	payload, err := json.Marshal(map[string]string{"token_id": tokenID})
+	if err != nil {
+		return err
+	}
+	_, err = mattermostPost("/users/tokens/revoke", bytes.NewReader(payload), opts)
+	return err
+}
+
+// saveToken saves the token in the given profile of the given configuration file, along with the
+// Mattermost URL, and in the given secret file.
+func saveToken(w io.Writer, token, profile, configFile, path string) error {
+	if profile != "" {
+		var settings = map[string]string{"access-token": token}
+		if url := viper.GetString("url"); url != "" {
+			settings["url"] = url
+		}
+		if err := saveProfileSettings(configFile, profile, settings); err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "Token saved in the profile %s of %s\n", profile, configFile)
+	}
+	if path != "" {
+		if err := fileutil.WriteFile(path, []byte(token+"\n")); err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "Token saved in %s\n", path)
+	}
+	return nil
+}
+
+// tokenCmd represents the token CLI command.
+var tokenCmd = &cobra.Command{
+	Use:   "token",
+	Short: "Manage the Mattermost personal access tokens",
+	Long: `Create, list and revoke the personal access tokens of the logged user, or of
+another user or bot (--user) when allowed.`,
+}
+
+// tokenCreateCmd represents the token create CLI command.
+var tokenCreateCmd = &cobra.Command{
+	Use:   "create",
+	Short: "Create a personal access token",
+	Long: `Create a personal access token. The token is printed on the standard output,
+unless it is saved in a profile of the configuration file (--to-profile), along with
+the Mattermost URL, or in a file readable only by the user (--to-file).`,
+	Example: `  token create --description "backup scripts"
+  token create --user ci-pipeline --description pipeline --to-profile ci
+  token create --user ci-pipeline --description pipeline --to-file /run/secrets/mattermost-token`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var opts = newOptions()
+		if tokenToProfile != "" {
+			if err := mattermost.CheckProfile(tokenToProfile); err != nil {
+				return err
+			}
+		}
+
+		configFile, err := getConfigFile()
+		if err != nil {
+			return err
+		}
+
+		tokenID, token, err := createToken(tokenUser, tokenDescription, opts)
+		if err != nil {
+			return fmt.Errorf("cannot create the token: %v", err)
+		}
+
+		if tokenToProfile == "" && tokenToFile == "" {
+			fmt.Fprintln(cmd.OutOrStdout(), token)
+			return nil
+		}
+		var w = cmd.OutOrStdout()
+		if viper.GetBool("quiet") {
+			w = io.Discard
+		}
+		if err := saveToken(w, token, tokenToProfile, configFile, tokenToFile); err != nil {
+			// The token is not shown again by Mattermost: it's revoked, or printed if that fails.
+			if revokeErr := revokeToken(tokenID, opts); revokeErr != nil {
+				fmt.Fprintln(cmd.OutOrStdout(), token)
+				return fmt.Errorf("cannot save the token %s, printed above: %v (cannot revoke it: %v)",
+					tokenID, err, revokeErr)
+			}
+			return fmt.Errorf("cannot save the token, revoked: %v", err)
+		}
+		return nil
+	},
+}
+
+// tokenListCmd represents the token list CLI command.
+var tokenListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the personal access tokens",
+	Long: `List the personal access tokens, with the times of their first and last use,
+and the reasons why they should be revoked: inactive, never used, in use for more
+than --max-age, or unused for more than --unused-for.`,
+	Example: `  token list
+  token list --user ci-pipeline --max-age 4320h --unused-for 168h`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var opts = newOptions()
+
+		return listTokens(cmd.OutOrStdout(), tokenUser, tokenMaxAge, tokenUnusedFor, opts)
+	},
+}
+
+// tokenRevokeCmd represents the token revoke CLI command.
+var tokenRevokeCmd = &cobra.Command{
+	Use:     "revoke TOKEN_ID...",
+	Short:   "Revoke personal access tokens",
+	Example: `  token revoke 7trmbhd8xg9tmiagqfx1fzhhjo`,
+	Args:    cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var opts = newOptions()
+
+		for _, tokenID := range args {
+			if err := revokeToken(tokenID, opts); err != nil {
+				return fmt.Errorf("cannot revoke the token %s: %v", tokenID, err)
+			}
+			if !viper.GetBool("quiet") {
+				fmt.Fprintf(cmd.OutOrStdout(), "Token %s revoked\n", tokenID)
+			}
+		}
+		return nil
+	},
+}
+
+// init initializes the token command flags.
+func init() {
+	rootCmd.AddCommand(tokenCmd)
+	tokenCmd.AddCommand(tokenCreateCmd)
+	tokenCmd.AddCommand(tokenListCmd)
+	tokenCmd.AddCommand(tokenRevokeCmd)
+
+	tokenCmd.PersistentFlags().BoolVarP(&mattermostSkipTLSVerify,
+		"insecure", "i", false, "ignore SSL/TLS certificate check")
+	tokenCmd.PersistentFlags().DurationVarP(&mattermostConnectionTimeout,
+		"timeout", "s", 10*time.Second, "the maximum time in seconds allowed for a Mattermost connection")
+
+	for _, c := range []*cobra.Command{tokenCreateCmd, tokenListCmd} {
+		c.Flags().StringVar(&tokenUser,
+			"user", "", "the username or ID of the user or bot owning the tokens (default is the logged user)")
+	}
+
+	tokenCreateCmd.Flags().StringVar(&tokenDescription,
+		"description", "", "the description of the token (required)")
+	checkErr(tokenCreateCmd.MarkFlagRequired("description"))
+	tokenCreateCmd.Flags().StringVar(&tokenToFile,
+		"to-file", "", "save the token in a file readable only by the user")
+	tokenCreateCmd.Flags().StringVar(&tokenToProfile,
+		"to-profile", "", "save the token and the Mattermost URL in the given profile of the configuration file")
+
+	tokenListCmd.Flags().DurationVar(&tokenMaxAge,
+		"max-age", 90*24*time.Hour, "the time of use above which a token is reported as old (0 to disable)")
+	tokenListCmd.Flags().DurationVar(&tokenUnusedFor,
+		"unused-for", 30*24*time.Hour, "the inactivity time above which a token is reported as unused (0 to disable)")
+}
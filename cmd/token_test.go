@@ -0,0 +1,223 @@
+/*
+  Copyright 2026 Davide Madrisan <d.madrisan@proton.me>
+
+  Licensed under the Mozilla Public License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+      https://www.mozilla.org/en-US/MPL/2.0/
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/madrisan/go-mattermost-notify/config"
+	"github.com/madrisan/go-mattermost-notify/mattermost/mattermosttest"
+	"github.com/spf13/viper"
+)
+
+func TestGetTokenWarnings(t *testing.T) {
+	var now = time.Now()
+	var day = 24 * time.Hour
+
+	var testCases = []struct {
+		name     string
+		active   bool
+		usage    *tokenUsage
+		shouldBe string
+	}{
+		{"in use", true, &tokenUsage{now.Add(-10 * day), now.Add(-time.Hour)}, ""},
+		{"never used", true, nil, "never used"},
+		{"inactive", false, &tokenUsage{now.Add(-10 * day), now.Add(-time.Hour)}, "inactive"},
+		{"old", true, &tokenUsage{now.Add(-100 * day), now}, "old (in use for 100d)"},
+		{"unused", true, &tokenUsage{now.Add(-40 * day), now.Add(-35 * day)}, "unused for 35d"},
+		{"old and unused", true, &tokenUsage{now.Add(-200 * day), now.Add(-60 * day)}, "old (in use for 200d), unused for 60d"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			warnings := strings.Join(getTokenWarnings(tc.active, tc.usage, now, 90*day, 30*day), ", ")
+			if warnings != tc.shouldBe {
+				t.Errorf("expected %q, got %q", tc.shouldBe, warnings)
+			}
+		})
+	}
+
+	if warnings := getTokenWarnings(true, &tokenUsage{now.Add(-200 * day), now.Add(-60 * day)}, now, 0, 0); len(warnings) != 0 {
+		t.Error("the age checks should be disabled, got", warnings)
+	}
+}
+
+func TestTokenCommands(t *testing.T) {
+	srv := mattermosttest.NewServer()
+	defer srv.Close()
+	srv.AddUser("ci-pipeline")
+
+	defer saveSettings("url", "access-token", "quiet")()
+	viper.Set("url", srv.URL)
+	viper.Set("access-token", srv.Token)
+	viper.Set("quiet", false)
+
+	var out bytes.Buffer
+	tokenCreateCmd.SetOut(&out)
+	tokenListCmd.SetOut(&out)
+	defer tokenCreateCmd.SetOut(nil)
+	defer tokenListCmd.SetOut(nil)
+
+	oldUser, oldDescription, oldToFile := tokenUser, tokenDescription, tokenToFile
+	defer func() {
+		tokenCreateCmd.Flags().Lookup("description").Changed = false
+		tokenUser, tokenDescription, tokenToFile = oldUser, oldDescription, oldToFile
+	}()
+
+	if err := tokenCreateCmd.ValidateRequiredFlags(); err == nil || !strings.Contains(err.Error(), "description") {
+		t.Error("the description should be required, got", err)
+	}
+	tokenCreateCmd.Flags().Set("description", "pipeline")
+
+	tokenUser, tokenToFile = "@ci-pipeline", filepath.Join(t.TempDir(), "token")
+	if err := tokenCreateCmd.RunE(tokenCreateCmd, nil); err != nil {
+		t.Fatal("cannot create the token:", err)
+	}
+	data, err := os.ReadFile(tokenToFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token := strings.TrimSpace(string(data))
+
+	// The new token works, and its use is recorded.
+	defer saveSettings("access-token")()
+	viper.Set("access-token", token)
+	if username, err := getLoggedUsername(config.Options{}); err != nil || username != "ci-pipeline" {
+		t.Fatalf("expected ci-pipeline, got %s (%v)", username, err)
+	}
+	viper.Set("access-token", srv.Token)
+
+	tokenToFile = ""
+	tokenCreateCmd.Flags().Set("description", "unused")
+	out.Reset()
+	if err := tokenCreateCmd.RunE(tokenCreateCmd, nil); err != nil {
+		t.Fatal("cannot create the token:", err)
+	}
+	if v := strings.TrimSpace(out.String()); v == "" || strings.Contains(v, " ") {
+		t.Errorf("only the token should be printed, got %q", v)
+	}
+
+	out.Reset()
+	if err := tokenListCmd.RunE(tokenListCmd, nil); err != nil {
+		t.Fatal("cannot list the tokens:", err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 3 || !strings.Contains(lines[1], "pipeline") || !strings.HasSuffix(lines[1], "-") ||
+		!strings.HasSuffix(lines[2], "never used") {
+		t.Fatalf("unexpected token list:\n%s", out.String())
+	}
+
+	// An old token not used anymore.
+	tokenID := strings.Fields(lines[1])[0]
+	srv.SetAccessTokenUsage(tokenID, time.Now().Add(-120*24*time.Hour), time.Now().Add(-45*24*time.Hour))
+	out.Reset()
+	if err := tokenListCmd.RunE(tokenListCmd, nil); err != nil {
+		t.Fatal("cannot list the tokens:", err)
+	}
+	if !strings.Contains(out.String(), "old (in use for 120d), unused for 45d") {
+		t.Errorf("the old token should be reported:\n%s", out.String())
+	}
+
+	if err := tokenRevokeCmd.RunE(tokenRevokeCmd, []string{tokenID}); err != nil {
+		t.Fatal("cannot revoke the token:", err)
+	}
+	viper.Set("access-token", token)
+	if _, err := getLoggedUsername(config.Options{}); err == nil {
+		t.Error("the revoked token should be rejected")
+	}
+}
+
+func TestSaveToken(t *testing.T) {
+	defer saveSettings("url")()
+	viper.Set("url", "https://mattermost.example.com")
+
+	dir := t.TempDir()
+	configFile, secretFile := filepath.Join(dir, "config.yaml"), filepath.Join(dir, "secret")
+
+	var out bytes.Buffer
+	if err := saveToken(&out, "new-token", "ci", configFile, secretFile); err != nil {
+		t.Fatal("cannot save the token:", err)
+	}
+
+	v := viper.New()
+	v.SetConfigFile(configFile)
+	if err := v.ReadInConfig(); err != nil {
+		t.Fatal(err)
+	}
+	if v.GetString("profiles.ci.access-token") != "new-token" || v.GetString("profiles.ci.url") != "https://mattermost.example.com" {
+		t.Errorf("unexpected profile %v", v.Get("profiles.ci"))
+	}
+	if fi, err := os.Stat(secretFile); err != nil || fi.Mode().Perm() != 0600 {
+		t.Error("the secret file should only be readable by the user", fi.Mode(), err)
+	}
+	if lines := strings.Count(out.String(), "\n"); lines != 2 {
+		t.Errorf("unexpected output %q", out.String())
+	}
+}
+
+func TestTokenCreateSaveFailure(t *testing.T) {
+	srv := mattermosttest.NewServer()
+	defer srv.Close()
+
+	defer saveSettings("url", "access-token", "quiet")()
+	viper.Set("url", srv.URL)
+	viper.Set("access-token", srv.Token)
+	viper.Set("quiet", false)
+
+	var out bytes.Buffer
+	tokenCreateCmd.SetOut(&out)
+	tokenListCmd.SetOut(&out)
+	defer tokenCreateCmd.SetOut(nil)
+	defer tokenListCmd.SetOut(nil)
+
+	oldUser, oldDescription, oldToFile := tokenUser, tokenDescription, tokenToFile
+	defer func() { tokenUser, tokenDescription, tokenToFile = oldUser, oldDescription, oldToFile }()
+	tokenUser, tokenDescription = "", "lost"
+	tokenToFile = filepath.Join(t.TempDir(), "missing", "token")
+
+	err := tokenCreateCmd.RunE(tokenCreateCmd, nil)
+	if err == nil || !strings.Contains(err.Error(), "revoked") {
+		t.Fatal("the token should be revoked when it cannot be saved, got", err)
+	}
+	if out.Len() != 0 {
+		t.Errorf("the revoked token should not be printed, got %q", out.String())
+	}
+
+	if err := tokenListCmd.RunE(tokenListCmd, nil); err != nil {
+		t.Fatal("cannot list the tokens:", err)
+	}
+	if strings.Contains(out.String(), "lost") {
+		t.Errorf("the token should not be listed:\n%s", out.String())
+	}
+
+	// The token is printed when it cannot be revoked either.
+	srv.SetError(http.MethodPost, "/api/v4/users/tokens/revoke", http.StatusInternalServerError)
+	out.Reset()
+	err = tokenCreateCmd.RunE(tokenCreateCmd, nil)
+	if err == nil || !strings.Contains(err.Error(), "cannot revoke") {
+		t.Fatal("the revocation should fail, got", err)
+	}
+	if v := strings.TrimSpace(out.String()); v == "" || strings.Contains(v, " ") {
+		t.Errorf("the token should be printed, got %q", v)
+	}
+}
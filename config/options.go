@@ -17,6 +17,7 @@
 package config
 
 import (
+	"net/http"
 	"time"
 )
 
@@ -24,4 +25,13 @@ import (
 type Options struct {
 	ConnectionTimeout time.Duration
 	SkipTLSVerify     bool
+	// HTTPClient is the client used for the Mattermost queries.
+	// A client using the process-wide shared transport is used when not set.
+	HTTPClient *http.Client
+	// MaxIdleConns is the maximum number of idle (keep-alive) connections kept in the pool.
+	MaxIdleConns int
+	// MaxIdleConnsPerHost is the maximum number of idle connections kept for each host.
+	MaxIdleConnsPerHost int
+	// IdleConnTimeout is the time an idle connection is kept in the pool before being closed.
+	IdleConnTimeout time.Duration
 }
@@ -0,0 +1,48 @@
+/*
+  Copyright 2026 Davide Madrisan <d.madrisan@proton.me>
+
+  Licensed under the Mozilla Public License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+      https://www.mozilla.org/en-US/MPL/2.0/
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+*/
+
+// Package fileutil implements the file operations shared by the go-mattermost-notify packages.
+package fileutil
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+)
+
+// ErrLocked is returned by Lock when the file is already locked.
+var ErrLocked = errors.New("the file is locked by another process")
+
+// WriteFile atomically replaces the content of the file at the given path with the given data,
+// so that concurrent readers never see a partial file. The file is readable only by the user.
+func WriteFile(path string, data []byte) error {
+	// The temporary files are created with the permissions 0600.
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,74 @@
+/*
+  Copyright 2026 Davide Madrisan <d.madrisan@proton.me>
+
+  Licensed under the Mozilla Public License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+      https://www.mozilla.org/en-US/MPL/2.0/
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+*/
+
+package fileutil
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestWriteFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "secret")
+
+	for _, content := range []string{"first", "second"} {
+		if err := WriteFile(path, []byte(content)); err != nil {
+			t.Fatal("cannot write the file:", err)
+		}
+		if data, err := os.ReadFile(path); err != nil || string(data) != content {
+			t.Errorf("expected %q, got %q (%v)", content, data, err)
+		}
+	}
+
+	if fi, err := os.Stat(path); err != nil {
+		t.Fatal(err)
+	} else if runtime.GOOS != "windows" && fi.Mode().Perm() != 0600 {
+		t.Error("the file should only be readable by the user, got", fi.Mode())
+	}
+
+	if files, err := os.ReadDir(dir); err != nil || len(files) != 1 {
+		t.Error("the temporary file should be removed, got", files, err)
+	}
+
+	if err := WriteFile(filepath.Join(dir, "missing", "file"), nil); err == nil {
+		t.Error("the write should fail in a missing directory")
+	}
+}
+
+func TestLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".lock")
+
+	unlock, err := Lock(path)
+	if err != nil {
+		t.Fatal("Lock has failed:", err)
+	}
+	if _, err := Lock(path); !errors.Is(err, ErrLocked) {
+		t.Error("expected ErrLocked, got", err)
+	}
+	if err := unlock(); err != nil {
+		t.Fatal("unlock has failed:", err)
+	}
+
+	unlock, err = Lock(path)
+	if err != nil {
+		t.Fatal("Lock has failed after unlock:", err)
+	}
+	unlock()
+}
@@ -0,0 +1,38 @@
+//go:build !(darwin || dragonfly || freebsd || linux || netbsd || openbsd)
+
+/*
+  Copyright 2026 Davide Madrisan <d.madrisan@proton.me>
+
+  Licensed under the Mozilla Public License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+      https://www.mozilla.org/en-US/MPL/2.0/
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+*/
+
+package fileutil
+
+import (
+	"os"
+)
+
+// Lock creates the given file, which must not exist, and returns the function removing it.
+// It fails with ErrLocked when the file exists. The file is left behind if the process is killed,
+// and must then be removed by hand.
+func Lock(path string) (func() error, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		if os.IsExist(err) {
+			return nil, ErrLocked
+		}
+		return nil, err
+	}
+	f.Close()
+	return func() error { return os.Remove(path) }, nil
+}
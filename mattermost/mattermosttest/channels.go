@@ -0,0 +1,505 @@
+/*
+  Copyright 2026 Davide Madrisan <d.madrisan@proton.me>
+
+  Licensed under the Mozilla Public License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+      https://www.mozilla.org/en-US/MPL/2.0/
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+*/
+
+package mattermosttest
+
+import (
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"sort"
+)
+
+// Team is a Mattermost team.
+type Team struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	DisplayName string `json:"display_name"`
+}
+
+// channelNameRegexp matches the valid names of the team channels.
+var channelNameRegexp = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,63}$`)
+
+// AddTeam creates a team with the given name, whose only member is the logged user, and returns it.
+func (s *Server) AddTeam(name string) *Team {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t := &Team{ID: s.newID(), Name: name, DisplayName: name}
+	s.teams[t.ID] = t
+	s.teamMembers[t.ID] = map[string]bool{s.me.ID: true}
+	return t
+}
+
+// Channel returns a copy of the channel with the given ID, or nil if it does not exist.
+func (s *Server) Channel(channelID string) *Channel {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if c, found := s.channels[channelID]; found {
+		channel := *c
+		return &channel
+	}
+	return nil
+}
+
+// ChannelMembers returns the sorted IDs of the members of the given channel.
+func (s *Server) ChannelMembers(channelID string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.channelMembers(channelID)
+}
+
+// channelMembers returns the sorted IDs of the members of the given channel.
+// The caller must hold s.mu.
+func (s *Server) channelMembers(channelID string) []string {
+	var members = []string{}
+	for userID := range s.members[channelID] {
+		members = append(members, userID)
+	}
+	sort.Strings(members)
+	return members
+}
+
+// findChannel returns the channel of the given team with the given name, or nil.
+// The caller must hold s.mu.
+func (s *Server) findChannel(teamID, name string) *Channel {
+	for _, c := range s.channels {
+		if c.TeamID == teamID && c.Name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+// getTeamByName handles GET /api/v4/teams/name/{name}.
+func (s *Server) getTeamByName(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, t := range s.teams {
+		if t.Name == r.PathValue("name") {
+			writeJSON(w, http.StatusOK, t)
+			return
+		}
+	}
+	writeError(w, http.StatusNotFound, "app.team.get_by_name.missing.app_error", "Unable to find the existing team.")
+}
+
+// TeamMembers returns the sorted IDs of the members of the given team.
+func (s *Server) TeamMembers(teamID string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var members = []string{}
+	for userID := range s.teamMembers[teamID] {
+		members = append(members, userID)
+	}
+	sort.Strings(members)
+	return members
+}
+
+// getTeamResource handles GET /api/v4/teams/{team_id}/members.
+// It shares a pattern, which would otherwise conflict with GET /api/v4/teams/name/{name}.
+func (s *Server) getTeamResource(w http.ResponseWriter, r *http.Request) {
+	switch r.PathValue("resource") {
+	case "members":
+		s.getTeamMembers(w, r)
+	default:
+		writeError(w, http.StatusNotFound, "api.context.404.app_error", "Sorry, we could not find the page.")
+	}
+}
+
+// teamMember returns a team member formatted like the Mattermost ones.
+func teamMember(teamID, userID string) map[string]string {
+	return map[string]string{
+		"team_id": teamID,
+		"user_id": userID,
+		"roles":   "team_user",
+	}
+}
+
+// getTeamMembers handles GET /api/v4/teams/{team_id}/members.
+func (s *Server) getTeamMembers(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	teamID := r.PathValue("team_id")
+	if _, found := s.teams[teamID]; !found {
+		writeError(w, http.StatusNotFound, "app.team.get.find.app_error", "Unable to find the existing team.")
+		return
+	}
+
+	var ids []string
+	for userID := range s.teamMembers[teamID] {
+		ids = append(ids, userID)
+	}
+	sort.Strings(ids)
+	var members = []map[string]string{}
+	for _, userID := range ids {
+		members = append(members, teamMember(teamID, userID))
+	}
+	page, perPage := getPaging(r)
+	writeJSON(w, http.StatusOK, paginate(members, page, perPage))
+}
+
+// addTeamMember handles POST /api/v4/teams/{team_id}/members.
+func (s *Server) addTeamMember(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		TeamID string `json:"team_id"`
+		UserID string `json:"user_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.TeamID != r.PathValue("team_id") {
+		writeError(w, http.StatusBadRequest, "api.context.invalid_body_param.app_error", "Invalid or missing team_id in request body.")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, found := s.teams[req.TeamID]; !found {
+		writeError(w, http.StatusNotFound, "app.team.get.find.app_error", "Unable to find the existing team.")
+		return
+	}
+	if _, found := s.users[req.UserID]; !found {
+		writeError(w, http.StatusBadRequest, "api.context.invalid_body_param.app_error", "Invalid or missing user_id in request body.")
+		return
+	}
+	s.teamMembers[req.TeamID][req.UserID] = true
+	writeJSON(w, http.StatusCreated, teamMember(req.TeamID, req.UserID))
+}
+
+// removeTeamMember handles DELETE /api/v4/teams/{team_id}/members/{user_id}.
+// Like in Mattermost, the user leaves the channels of the team too.
+func (s *Server) removeTeamMember(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	teamID, userID := r.PathValue("team_id"), r.PathValue("user_id")
+	if !s.teamMembers[teamID][userID] {
+		writeError(w, http.StatusNotFound, "app.team.get_member.missing.app_error", "No team member found for that user ID and team ID.")
+		return
+	}
+	delete(s.teamMembers[teamID], userID)
+	for _, c := range s.channels {
+		if c.TeamID == teamID {
+			delete(s.members[c.ID], userID)
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "OK"})
+}
+
+// getUserTeams handles GET /api/v4/users/{user_id}/teams.
+func (s *Server) getUserTeams(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	userID := r.PathValue("user_id")
+	if userID == "me" {
+		userID = user(r).ID
+	}
+	var teams = []*Team{}
+	for _, t := range s.teams {
+		if s.teamMembers[t.ID][userID] {
+			teams = append(teams, t)
+		}
+	}
+	sort.Slice(teams, func(i, j int) bool { return teams[i].ID < teams[j].ID })
+	writeJSON(w, http.StatusOK, teams)
+}
+
+// getUserChannels handles GET /api/v4/users/{user_id}/channels: the channels of all the teams,
+// and the direct and group channels, the user is a member of.
+func (s *Server) getUserChannels(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	userID := r.PathValue("user_id")
+	if userID == "me" {
+		userID = user(r).ID
+	}
+	var channels = []*Channel{}
+	for _, c := range s.channels {
+		if s.members[c.ID][userID] && c.DeleteAt == 0 {
+			channels = append(channels, c)
+		}
+	}
+	sort.Slice(channels, func(i, j int) bool { return channels[i].ID < channels[j].ID })
+	writeJSON(w, http.StatusOK, channels)
+}
+
+// getUsersByIDs handles POST /api/v4/users/ids.
+func (s *Server) getUsersByIDs(w http.ResponseWriter, r *http.Request) {
+	var userIDs []string
+	if err := json.NewDecoder(r.Body).Decode(&userIDs); err != nil {
+		writeError(w, http.StatusBadRequest, "api.context.invalid_body_param.app_error", "Invalid or missing user_ids in request body.")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var users = []*User{}
+	for _, id := range userIDs {
+		if u, found := s.users[id]; found {
+			users = append(users, u)
+		}
+	}
+	writeJSON(w, http.StatusOK, users)
+}
+
+// getChannelByName handles GET /api/v4/teams/{team_id}/channels/name/{channel_name}.
+func (s *Server) getChannelByName(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c := s.findChannel(r.PathValue("team_id"), r.PathValue("channel_name"))
+	if c == nil || (c.DeleteAt != 0 && r.URL.Query().Get("include_deleted") != "true") {
+		writeError(w, http.StatusNotFound, "app.channel.get_by_name.missing.app_error", "Channel does not exist.")
+		return
+	}
+	writeJSON(w, http.StatusOK, c)
+}
+
+// createTeamChannel handles POST /api/v4/channels.
+func (s *Server) createTeamChannel(w http.ResponseWriter, r *http.Request) {
+	var req Channel
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || (req.Type != "O" && req.Type != "P") {
+		writeError(w, http.StatusBadRequest, "api.context.invalid_body_param.app_error", "Invalid or missing channel in request body.")
+		return
+	}
+	if !channelNameRegexp.MatchString(req.Name) || req.DisplayName == "" {
+		writeError(w, http.StatusBadRequest, "model.channel.is_valid.name.app_error", "Invalid channel name.")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, found := s.teams[req.TeamID]; !found {
+		writeError(w, http.StatusBadRequest, "api.context.invalid_body_param.app_error", "Invalid or missing team_id in request body.")
+		return
+	}
+	if s.findChannel(req.TeamID, req.Name) != nil {
+		writeError(w, http.StatusBadRequest, "store.sql_channel.save_channel.exists.app_error", "A channel with that name already exists on the same team.")
+		return
+	}
+
+	c := &Channel{
+		ID:          s.newID(),
+		TeamID:      req.TeamID,
+		Name:        req.Name,
+		DisplayName: req.DisplayName,
+		Type:        req.Type,
+		Header:      req.Header,
+		Purpose:     req.Purpose,
+	}
+	s.channels[c.ID] = c
+	s.members[c.ID] = map[string]bool{user(r).ID: true}
+	writeJSON(w, http.StatusCreated, c)
+}
+
+// teamChannel returns the team channel with the given ID, or sends an error and returns nil.
+// The caller must hold s.mu.
+func (s *Server) teamChannel(w http.ResponseWriter, channelID string) *Channel {
+	c, found := s.channels[channelID]
+	if !found {
+		writeError(w, http.StatusNotFound, "app.channel.get.existing.app_error", "Unable to find the existing channel.")
+		return nil
+	}
+	if c.TeamID == "" {
+		writeError(w, http.StatusBadRequest, "api.channel.update_channel.typechange.app_error", "Direct and group channels cannot be changed.")
+		return nil
+	}
+	return c
+}
+
+// archiveChannel handles DELETE /api/v4/channels/{channel_id}.
+func (s *Server) archiveChannel(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c := s.teamChannel(w, r.PathValue("channel_id"))
+	if c == nil {
+		return
+	}
+	if c.DeleteAt != 0 {
+		writeError(w, http.StatusBadRequest, "api.channel.delete_channel.deleted.app_error", "The channel has been archived or deleted.")
+		return
+	}
+	c.DeleteAt = now()
+	writeJSON(w, http.StatusOK, map[string]string{"status": "OK"})
+}
+
+// restoreChannel handles POST /api/v4/channels/{channel_id}/restore.
+func (s *Server) restoreChannel(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c := s.teamChannel(w, r.PathValue("channel_id"))
+	if c == nil {
+		return
+	}
+	if c.DeleteAt == 0 {
+		writeError(w, http.StatusBadRequest, "api.channel.restore_channel.restored.app_error", "Unable to unarchive channel. The channel is not archived.")
+		return
+	}
+	c.DeleteAt = 0
+	writeJSON(w, http.StatusOK, c)
+}
+
+// patchChannel handles PUT /api/v4/channels/{channel_id}/patch.
+func (s *Server) patchChannel(w http.ResponseWriter, r *http.Request) {
+	var patch struct {
+		Name        *string `json:"name"`
+		DisplayName *string `json:"display_name"`
+		Header      *string `json:"header"`
+		Purpose     *string `json:"purpose"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&patch); err != nil {
+		writeError(w, http.StatusBadRequest, "api.context.invalid_body_param.app_error", "Invalid or missing channel in request body.")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c := s.teamChannel(w, r.PathValue("channel_id"))
+	if c == nil {
+		return
+	}
+	if c.DeleteAt != 0 {
+		writeError(w, http.StatusBadRequest, "api.channel.update_channel.deleted.app_error", "The channel has been archived or deleted.")
+		return
+	}
+	if patch.Name != nil && *patch.Name != c.Name {
+		if !channelNameRegexp.MatchString(*patch.Name) {
+			writeError(w, http.StatusBadRequest, "model.channel.is_valid.name.app_error", "Invalid channel name.")
+			return
+		}
+		if s.findChannel(c.TeamID, *patch.Name) != nil {
+			writeError(w, http.StatusBadRequest, "store.sql_channel.update.exists.app_error", "A channel with that handle already exists.")
+			return
+		}
+		c.Name = *patch.Name
+	}
+	if patch.DisplayName != nil {
+		c.DisplayName = *patch.DisplayName
+	}
+	if patch.Header != nil {
+		c.Header = *patch.Header
+	}
+	if patch.Purpose != nil {
+		c.Purpose = *patch.Purpose
+	}
+	writeJSON(w, http.StatusOK, c)
+}
+
+// updateChannelPrivacy handles PUT /api/v4/channels/{channel_id}/privacy.
+func (s *Server) updateChannelPrivacy(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Privacy string `json:"privacy"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || (req.Privacy != "O" && req.Privacy != "P") {
+		writeError(w, http.StatusBadRequest, "api.channel.update_channel_privacy.wrong_channel_type", "Invalid or missing privacy in request body.")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c := s.teamChannel(w, r.PathValue("channel_id"))
+	if c == nil {
+		return
+	}
+	c.Type = req.Privacy
+	writeJSON(w, http.StatusOK, c)
+}
+
+// channelMember returns a channel member formatted like the Mattermost ones.
+func channelMember(channelID, userID string) map[string]string {
+	return map[string]string{
+		"channel_id": channelID,
+		"user_id":    userID,
+		"roles":      "channel_user",
+	}
+}
+
+// getChannelMembers handles GET /api/v4/channels/{channel_id}/members.
+func (s *Server) getChannelMembers(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	channelID := r.PathValue("channel_id")
+	if _, found := s.channels[channelID]; !found {
+		writeError(w, http.StatusNotFound, "app.channel.get.existing.app_error", "Unable to find the existing channel.")
+		return
+	}
+
+	var members = []map[string]string{}
+	for _, userID := range s.channelMembers(channelID) {
+		members = append(members, channelMember(channelID, userID))
+	}
+	page, perPage := getPaging(r)
+	writeJSON(w, http.StatusOK, paginate(members, page, perPage))
+}
+
+// addChannelMember handles POST /api/v4/channels/{channel_id}/members.
+func (s *Server) addChannelMember(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		UserID string `json:"user_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "api.context.invalid_body_param.app_error", "Invalid or missing user_id in request body.")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c := s.teamChannel(w, r.PathValue("channel_id"))
+	if c == nil {
+		return
+	}
+	if _, found := s.users[req.UserID]; !found {
+		writeError(w, http.StatusBadRequest, "api.context.invalid_body_param.app_error", "Invalid or missing user_id in request body.")
+		return
+	}
+	if c.DeleteAt != 0 {
+		writeError(w, http.StatusBadRequest, "api.channel.add_user.to.channel.failed.deleted.app_error", "Failed to add user to channel because channel has been archived.")
+		return
+	}
+	s.members[c.ID][req.UserID] = true
+	writeJSON(w, http.StatusCreated, channelMember(c.ID, req.UserID))
+}
+
+// removeChannelMember handles DELETE /api/v4/channels/{channel_id}/members/{user_id}.
+func (s *Server) removeChannelMember(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c := s.teamChannel(w, r.PathValue("channel_id"))
+	if c == nil {
+		return
+	}
+	if !s.members[c.ID][r.PathValue("user_id")] {
+		writeError(w, http.StatusNotFound, "app.channel.get_member.missing.app_error", "No channel member found for that user ID and channel ID.")
+		return
+	}
+	delete(s.members[c.ID], r.PathValue("user_id"))
+	writeJSON(w, http.StatusOK, map[string]string{"status": "OK"})
+}
@@ -0,0 +1,122 @@
+/*
+  Copyright 2026 Davide Madrisan <d.madrisan@proton.me>
+
+  Licensed under the Mozilla Public License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+      https://www.mozilla.org/en-US/MPL/2.0/
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+*/
+
+package mattermosttest
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// IdentityProvider is a stand-in OAuth2 identity provider, like GitLab or Keycloak, for the
+// SSO tests. It authenticates every authorization request as the user Username, without asking
+// for credentials, and implements the authorization code grant:
+//
+//	GET  /authorize  redirects to the redirect_uri with an authorization code
+//	POST /token      exchanges the authorization code for an access token
+//	GET  /userinfo   returns the preferred_username of the access token owner
+type IdentityProvider struct {
+	*httptest.Server
+	// Username is the name of the user authenticated by the identity provider.
+	Username string
+
+	mu     sync.Mutex
+	nextID int
+	codes  map[string]string
+	tokens map[string]string
+}
+
+// NewIdentityProvider starts a stand-in identity provider authenticating the given user.
+// The caller should call Close when finished, to shut it down.
+func NewIdentityProvider(username string) *IdentityProvider {
+	p := &IdentityProvider{
+		Username: username,
+		codes:    make(map[string]string),
+		tokens:   make(map[string]string),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /authorize", p.authorize)
+	mux.HandleFunc("POST /token", p.token)
+	mux.HandleFunc("GET /userinfo", p.userinfo)
+	p.Server = httptest.NewServer(mux)
+	return p
+}
+
+// newID returns a new code or token.
+// It must be called with the lock held.
+func (p *IdentityProvider) newID(prefix string) string {
+	p.nextID++
+	return fmt.Sprintf("%s%010d", prefix, p.nextID)
+}
+
+// authorize handles GET /authorize.
+func (p *IdentityProvider) authorize(w http.ResponseWriter, r *http.Request) {
+	redirectURI, err := url.Parse(r.URL.Query().Get("redirect_uri"))
+	if err != nil || redirectURI.Scheme == "" {
+		http.Error(w, "invalid redirect_uri", http.StatusBadRequest)
+		return
+	}
+
+	p.mu.Lock()
+	code := p.newID("code")
+	p.codes[code] = p.Username
+	p.mu.Unlock()
+
+	query := redirectURI.Query()
+	query.Set("code", code)
+	query.Set("state", r.URL.Query().Get("state"))
+	redirectURI.RawQuery = query.Encode()
+	http.Redirect(w, r, redirectURI.String(), http.StatusFound)
+}
+
+// token handles POST /token.
+func (p *IdentityProvider) token(w http.ResponseWriter, r *http.Request) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	username, found := p.codes[r.FormValue("code")]
+	if r.FormValue("grant_type") != "authorization_code" || !found {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+	delete(p.codes, r.FormValue("code"))
+
+	token := p.newID("token")
+	p.tokens[token] = username
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token": token,
+		"token_type":   "Bearer",
+		"expires_in":   3600,
+	})
+}
+
+// userinfo handles GET /userinfo.
+func (p *IdentityProvider) userinfo(w http.ResponseWriter, r *http.Request) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	username, found := p.tokens[strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")]
+	if !found {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_token"})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"preferred_username": username})
+}
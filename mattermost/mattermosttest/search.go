@@ -0,0 +1,148 @@
+/*
+  Copyright 2026 Davide Madrisan <d.madrisan@proton.me>
+
+  Licensed under the Mozilla Public License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+      https://www.mozilla.org/en-US/MPL/2.0/
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+*/
+
+package mattermosttest
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// postSearch is a search of posts, parsed from the terms sent to /teams/{team_id}/posts/search.
+type postSearch struct {
+	words []string
+	or    bool
+	// from and in are the usernames and the channel names the posts must belong to, if any.
+	from []string
+	in   []string
+	// after and before are the times (in milliseconds) the posts must be created after and before,
+	// if not zero.
+	after  int64
+	before int64
+}
+
+// parseSearchTerms parses the given search terms, with the from:, in:, after:, before: and on:
+// modifiers, whose dates are in the time zone with the given offset from UTC in seconds.
+func parseSearchTerms(terms string, isOrSearch bool, offset int) (*postSearch, bool) {
+	var search = postSearch{or: isOrSearch}
+	location := time.FixedZone("", offset)
+
+	day := func(value string) (time.Time, bool) {
+		t, err := time.ParseInLocation("2006-01-02", value, location)
+		return t, err == nil
+	}
+	for _, term := range strings.Fields(terms) {
+		modifier, value, found := strings.Cut(term, ":")
+		if !found {
+			search.words = append(search.words, strings.ToLower(term))
+			continue
+		}
+		switch modifier {
+		case "from":
+			search.from = append(search.from, strings.TrimPrefix(value, "@"))
+		case "in":
+			search.in = append(search.in, strings.TrimPrefix(value, "~"))
+		case "after", "before", "on":
+			t, ok := day(value)
+			if !ok {
+				return nil, false
+			}
+			switch modifier {
+			case "after":
+				search.after = t.AddDate(0, 0, 1).UnixMilli()
+			case "before":
+				search.before = t.UnixMilli()
+			case "on":
+				search.after, search.before = t.UnixMilli(), t.AddDate(0, 0, 1).UnixMilli()
+			}
+		default:
+			search.words = append(search.words, strings.ToLower(term))
+		}
+	}
+	return &search, true
+}
+
+// matchesAny tells if value is one of the given values, or if there are no values.
+func matchesAny(value string, values []string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return len(values) == 0
+}
+
+// matches tells if the given post, sent by the given user in the given channel, matches the search.
+func (search *postSearch) matches(p *Post, u *User, c *Channel) bool {
+	if (search.after != 0 && p.CreateAt < search.after) || (search.before != 0 && p.CreateAt >= search.before) {
+		return false
+	}
+	if u == nil || !matchesAny(u.Username, search.from) || !matchesAny(c.Name, search.in) {
+		return false
+	}
+
+	message := strings.ToLower(p.Message)
+	for _, word := range search.words {
+		found := strings.Contains(message, strings.TrimSuffix(word, "*"))
+		if found && search.or {
+			return true
+		}
+		if !found && !search.or {
+			return false
+		}
+	}
+	return !search.or || len(search.words) == 0
+}
+
+// searchPosts handles POST /api/v4/teams/{team_id}/posts/search.
+func (s *Server) searchPosts(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Terms                  string `json:"terms"`
+		IsOrSearch             bool   `json:"is_or_search"`
+		TimeZoneOffset         int    `json:"time_zone_offset"`
+		IncludeDeletedChannels bool   `json:"include_deleted_channels"`
+		Page                   int    `json:"page"`
+		PerPage                int    `json:"per_page"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || strings.TrimSpace(req.Terms) == "" {
+		writeError(w, http.StatusBadRequest, "api.context.invalid_body_param.app_error", "Invalid or missing terms in request body.")
+		return
+	}
+	search, ok := parseSearchTerms(req.Terms, req.IsOrSearch, req.TimeZoneOffset)
+	if !ok {
+		writeError(w, http.StatusBadRequest, "api.post.search_posts.invalid_body.app_error", "Unable to parse the search terms.")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	teamID := r.PathValue("team_id")
+	if _, found := s.teams[teamID]; !found {
+		writeError(w, http.StatusNotFound, "app.team.get.find.app_error", "Unable to find the existing team.")
+		return
+	}
+	if req.PerPage <= 0 {
+		req.PerPage = 60
+	}
+	s.writePostList(w, req.Page, req.PerPage, func(p *Post) bool {
+		c := s.channels[p.ChannelID]
+		return c.TeamID == teamID && (c.DeleteAt == 0 || req.IncludeDeletedChannels) &&
+			search.matches(p, s.users[p.UserID], c)
+	})
+}
@@ -0,0 +1,1106 @@
+/*
+  Copyright 2026 Davide Madrisan <d.madrisan@proton.me>
+
+  Licensed under the Mozilla Public License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+      https://www.mozilla.org/en-US/MPL/2.0/
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+*/
+
+// Package mattermosttest implements a fake Mattermost server for the tests.
+//
+// The server keeps the users, channels, posts, files and reactions in memory, records the
+// requests it receives, and can be told to fail on some endpoints:
+//
+//	srv := mattermosttest.NewServer()
+//	defer srv.Close()
+//	viper.Set("url", srv.URL)
+//	viper.Set("access-token", srv.Token)
+package mattermosttest
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultToken is the access token accepted by the servers created by NewServer.
+const DefaultToken = "mattermosttest-token"
+
+// DefaultVersion is the Mattermost version reported by the servers created by NewServer.
+const DefaultVersion = "9.11.0"
+
+// maxMemory is the maximum size in bytes of the uploaded files kept in memory.
+const maxMemory = 32 << 20
+
+// User is a Mattermost user.
+type User struct {
+	ID       string            `json:"id"`
+	Username string            `json:"username"`
+	Props    map[string]string `json:"props,omitempty"`
+
+	password string
+	mfaCode  string
+	// status is the status set through the API, and dndEndTime the end of the "dnd" status
+	// in seconds since the epoch, zero if none.
+	status     string
+	dndEndTime int64
+}
+
+// Channel is a Mattermost channel. The type is "O" (open), "P" (private), "D" (direct) or "G" (group).
+// The direct and group channels belong to no team.
+type Channel struct {
+	ID          string `json:"id"`
+	TeamID      string `json:"team_id"`
+	Name        string `json:"name"`
+	DisplayName string `json:"display_name"`
+	Type        string `json:"type"`
+	Header      string `json:"header"`
+	Purpose     string `json:"purpose"`
+	DeleteAt    int64  `json:"delete_at"`
+}
+
+// Post is a Mattermost post.
+type Post struct {
+	ID        string                 `json:"id"`
+	CreateAt  int64                  `json:"create_at"`
+	UpdateAt  int64                  `json:"update_at"`
+	UserID    string                 `json:"user_id"`
+	ChannelID string                 `json:"channel_id"`
+	RootID    string                 `json:"root_id"`
+	Message   string                 `json:"message"`
+	Props     map[string]interface{} `json:"props,omitempty"`
+	FileIDs   []string               `json:"file_ids,omitempty"`
+}
+
+// Reaction is an emoji reaction to a post.
+type Reaction struct {
+	UserID    string `json:"user_id"`
+	PostID    string `json:"post_id"`
+	EmojiName string `json:"emoji_name"`
+	CreateAt  int64  `json:"create_at"`
+}
+
+// FileInfo describes an uploaded file.
+type FileInfo struct {
+	ID        string `json:"id"`
+	ChannelID string `json:"channel_id"`
+	Name      string `json:"name"`
+	Size      int64  `json:"size"`
+	MimeType  string `json:"mime_type"`
+}
+
+// Request is a request received by the server.
+type Request struct {
+	Method string
+	Path   string
+	Query  url.Values
+	Header http.Header
+	Body   []byte
+	// RemoteAddr is the address of the client connection.
+	RemoteAddr string
+}
+
+// Server is a fake Mattermost server.
+type Server struct {
+	*httptest.Server
+	// Token is the access token expected in the 'Authorization: Bearer <token>' header.
+	Token string
+	// Version is the Mattermost version sent in the X-Version-Id header.
+	Version string
+
+	// localMode tells that the server listens on a Unix socket and requires no access token.
+	localMode bool
+
+	mu        sync.Mutex
+	nextID    int
+	me        *User
+	users     map[string]*User
+	channels  map[string]*Channel
+	members   map[string]map[string]bool
+	posts     map[string]*Post
+	postOrder []string
+	reactions []Reaction
+	files     map[string]*FileInfo
+	contents  map[string][]byte
+	requests  []Request
+	errors    map[string]int
+	// sessions contains the IDs of the users logged in, indexed by session token.
+	sessions map[string]string
+
+	// ssoService is the SSO service enabled by EnableSSO, and ssoProvider the URL of its identity provider.
+	ssoService  string
+	ssoProvider string
+	// ssoLogins contains the SSO logins waiting for the identity provider, indexed by state.
+	ssoLogins map[string]*ssoLogin
+	// loginCodes contains the completed SSO logins, indexed by the login code to exchange.
+	loginCodes map[string]*ssoLogin
+
+	// bots contains the bot accounts, indexed by user ID.
+	bots map[string]*Bot
+	// accessTokens contains the personal access tokens, indexed by token ID.
+	accessTokens map[string]*AccessToken
+
+	// teams contains the teams, indexed by ID, and teamMembers their members.
+	teams       map[string]*Team
+	teamMembers map[string]map[string]bool
+}
+
+// ssoLogin is an SSO login started through /oauth/{service}/mobile_login.
+type ssoLogin struct {
+	redirectTo    string
+	state         string
+	codeChallenge string
+	userID        string
+}
+
+// SessionTTL is the lifetime of the sessions opened through /users/login.
+const SessionTTL = 30 * 24 * time.Hour
+
+// userKey is the context key of the user authenticated by the request token.
+type userKey struct{}
+
+// NewServer starts a fake Mattermost server. The logged user is a bot named "bot".
+// The caller should call Close when finished, to shut it down.
+func NewServer() *Server {
+	s := newServer()
+	s.Server.Start()
+	return s
+}
+
+// NewTLSServer starts a fake Mattermost server using TLS and HTTP/2, with a self-signed certificate.
+// The caller should call Close when finished, to shut it down.
+func NewTLSServer() *Server {
+	s := newServer()
+	s.Server.EnableHTTP2 = true
+	s.Server.StartTLS()
+	return s
+}
+
+// NewLocalServer starts a fake Mattermost server in local mode: it listens on the given Unix
+// socket, its URL is in the form unix:///path/to/socket, and it requires no access token.
+// The caller should call Close when finished, to shut it down.
+func NewLocalServer(socket string) (*Server, error) {
+	l, err := net.Listen("unix", socket)
+	if err != nil {
+		return nil, err
+	}
+
+	s := newServer()
+	s.localMode = true
+	s.Server.Listener.Close()
+	s.Server.Listener = l
+	s.Server.Start()
+	s.URL = "unix://" + socket
+	return s, nil
+}
+
+// newServer returns a fake Mattermost server, not started yet.
+func newServer() *Server {
+	s := &Server{
+		Token:    DefaultToken,
+		Version:  DefaultVersion,
+		users:    make(map[string]*User),
+		channels: make(map[string]*Channel),
+		members:  make(map[string]map[string]bool),
+		posts:    make(map[string]*Post),
+		files:    make(map[string]*FileInfo),
+		contents: make(map[string][]byte),
+		errors:   make(map[string]int),
+		sessions: make(map[string]string),
+
+		ssoLogins:  make(map[string]*ssoLogin),
+		loginCodes: make(map[string]*ssoLogin),
+
+		bots:         make(map[string]*Bot),
+		accessTokens: make(map[string]*AccessToken),
+
+		teams:       make(map[string]*Team),
+		teamMembers: make(map[string]map[string]bool),
+	}
+	s.me = s.AddUser("bot")
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v4/system/ping", s.ping)
+	mux.HandleFunc("GET /api/v4/config/client", s.getClientConfig)
+	mux.HandleFunc("GET /oauth/{service}/mobile_login", s.startSSOLogin)
+	mux.HandleFunc("GET /signup/{service}/complete", s.completeSSOLogin)
+	mux.HandleFunc("POST /api/v4/users/login/sso/code-exchange", s.exchangeLoginCode)
+	mux.HandleFunc("GET /api/v4/users/me", s.getMe)
+	mux.HandleFunc("POST /api/v4/users/login", s.login)
+	mux.HandleFunc("POST /api/v4/users/logout", s.logout)
+	mux.HandleFunc("GET /api/v4/users/username/{username}", s.getUserByUsername)
+	mux.HandleFunc("GET /api/v4/users/{user_id}", s.getUser)
+	mux.HandleFunc("GET /api/v4/users/{user_id}/{resource}", s.getUserResource)
+	mux.HandleFunc("POST /api/v4/users/ids", s.getUsersByIDs)
+	mux.HandleFunc("POST /api/v4/users/{user_id}/tokens", s.createAccessToken)
+	mux.HandleFunc("PUT /api/v4/users/{user_id}/status", s.updateStatus)
+	mux.HandleFunc("PUT /api/v4/users/me/status/custom", s.updateCustomStatus)
+	mux.HandleFunc("DELETE /api/v4/users/me/status/custom", s.removeCustomStatus)
+	mux.HandleFunc("POST /api/v4/users/tokens/revoke", s.revokeAccessToken)
+	mux.HandleFunc("POST /api/v4/bots", s.createBot)
+	mux.HandleFunc("GET /api/v4/bots", s.getBots)
+	mux.HandleFunc("POST /api/v4/bots/{bot_user_id}/disable", s.disableBot)
+	mux.HandleFunc("GET /api/v4/teams/name/{name}", s.getTeamByName)
+	mux.HandleFunc("GET /api/v4/teams/{team_id}/{resource}", s.getTeamResource)
+	mux.HandleFunc("POST /api/v4/teams/{team_id}/members", s.addTeamMember)
+	mux.HandleFunc("DELETE /api/v4/teams/{team_id}/members/{user_id}", s.removeTeamMember)
+	mux.HandleFunc("GET /api/v4/teams/{team_id}/channels/name/{channel_name}", s.getChannelByName)
+	mux.HandleFunc("POST /api/v4/channels", s.createTeamChannel)
+	mux.HandleFunc("POST /api/v4/channels/direct", s.createDirectChannel)
+	mux.HandleFunc("POST /api/v4/channels/group", s.createGroupChannel)
+	mux.HandleFunc("GET /api/v4/channels/{channel_id}", s.getChannel)
+	mux.HandleFunc("DELETE /api/v4/channels/{channel_id}", s.archiveChannel)
+	mux.HandleFunc("POST /api/v4/channels/{channel_id}/restore", s.restoreChannel)
+	mux.HandleFunc("PUT /api/v4/channels/{channel_id}/patch", s.patchChannel)
+	mux.HandleFunc("PUT /api/v4/channels/{channel_id}/privacy", s.updateChannelPrivacy)
+	mux.HandleFunc("GET /api/v4/channels/{channel_id}/members", s.getChannelMembers)
+	mux.HandleFunc("POST /api/v4/channels/{channel_id}/members", s.addChannelMember)
+	mux.HandleFunc("DELETE /api/v4/channels/{channel_id}/members/{user_id}", s.removeChannelMember)
+	mux.HandleFunc("GET /api/v4/channels/{channel_id}/posts", s.getChannelPosts)
+	mux.HandleFunc("GET /api/v4/channels/{channel_id}/members/me", s.getChannelMember)
+	mux.HandleFunc("POST /api/v4/posts", s.createPost)
+	mux.HandleFunc("GET /api/v4/posts/{post_id}", s.getPost)
+	mux.HandleFunc("PUT /api/v4/posts/{post_id}/patch", s.patchPost)
+	mux.HandleFunc("GET /api/v4/posts/{post_id}/thread", s.getThread)
+	mux.HandleFunc("GET /api/v4/posts/{post_id}/reactions", s.getReactions)
+	mux.HandleFunc("POST /api/v4/reactions", s.addReaction)
+	mux.HandleFunc("POST /api/v4/files", s.uploadFiles)
+	mux.HandleFunc("GET /api/v4/files/{file_id}", s.getFile)
+	mux.HandleFunc("GET /api/v4/files/{file_id}/info", s.getFileInfo)
+	mux.HandleFunc("POST /api/v4/teams/{team_id}/posts/search", s.searchPosts)
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		writeError(w, http.StatusNotFound, "api.context.404.app_error", "Sorry, we could not find the page.")
+	})
+
+	s.Server = httptest.NewUnstartedServer(s.intercept(mux))
+	return s
+}
+
+// newID returns a new Mattermost-like 26 characters ID.
+func (s *Server) newID() string {
+	s.nextID++
+	return fmt.Sprintf("mmtest%020d", s.nextID)
+}
+
+// now returns the current time in milliseconds, like the Mattermost timestamps.
+func now() int64 {
+	return time.Now().UnixMilli()
+}
+
+// AddUser creates a user with the given username and returns it.
+func (s *Server) AddUser(username string) *User {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u := &User{ID: s.newID(), Username: username}
+	s.users[u.ID] = u
+	return u
+}
+
+// SetPassword sets the password of the given user, allowing it to login, and its MFA code
+// if not empty.
+func (s *Server) SetPassword(userID, password, mfaCode string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if u, found := s.users[userID]; found {
+		u.password, u.mfaCode = password, mfaCode
+	}
+}
+
+// EnableSSO enables the login through the given SSO service ("gitlab", "openid", ...), whose
+// identity provider has the given URL, like the one of an IdentityProvider. The users authenticated
+// by the identity provider are created when they do not exist.
+func (s *Server) EnableSSO(service, providerURL string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.ssoService, s.ssoProvider = service, providerURL
+}
+
+// RevokeSessions revokes all the sessions opened through /users/login and the SSO logins.
+func (s *Server) RevokeSessions() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sessions = make(map[string]string)
+}
+
+// AddChannel creates an open channel with the given name, whose only member is the logged user,
+// and returns it.
+func (s *Server) AddChannel(name string) *Channel {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c := &Channel{ID: s.newID(), Name: name, Type: "O"}
+	s.channels[c.ID] = c
+	s.members[c.ID] = map[string]bool{s.me.ID: true}
+	return c
+}
+
+// SetChannelMember adds the given user to the given channel, or removes it when member is false.
+func (s *Server) SetChannelMember(channelID, userID string, member bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.members[channelID] == nil {
+		s.members[channelID] = make(map[string]bool)
+	}
+	if member {
+		s.members[channelID][userID] = true
+	} else {
+		delete(s.members[channelID], userID)
+	}
+}
+
+// AddPost adds a post of the given user to the given channel, created at the given time, and returns it.
+// The post is a reply when rootID is set.
+func (s *Server) AddPost(channelID, userID, rootID, message string, createAt time.Time) *Post {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p := &Post{
+		ID:        s.newID(),
+		CreateAt:  createAt.UnixMilli(),
+		UpdateAt:  createAt.UnixMilli(),
+		UserID:    userID,
+		ChannelID: channelID,
+		RootID:    rootID,
+		Message:   message,
+	}
+	s.posts[p.ID] = p
+
+	// The posts are kept in creation order.
+	i := sort.Search(len(s.postOrder), func(i int) bool { return s.posts[s.postOrder[i]].CreateAt > p.CreateAt })
+	s.postOrder = append(s.postOrder[:i], append([]string{p.ID}, s.postOrder[i:]...)...)
+	return p
+}
+
+// AddReaction adds a reaction of the given user to the given post.
+func (s *Server) AddReaction(userID, postID, emojiName string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.reactions = append(s.reactions, Reaction{UserID: userID, PostID: postID, EmojiName: emojiName, CreateAt: now()})
+}
+
+// Me returns the logged user.
+func (s *Server) Me() *User {
+	return s.me
+}
+
+// Posts returns a copy of the posts, in creation order.
+func (s *Server) Posts() []Post {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var posts = make([]Post, 0, len(s.postOrder))
+	for _, id := range s.postOrder {
+		posts = append(posts, *s.posts[id])
+	}
+	return posts
+}
+
+// Requests returns the requests received by the server, in order.
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]Request(nil), s.requests...)
+}
+
+// SetError makes the server answer with the given HTTP status code to the requests with the given
+// method and path (for instance "POST", "/api/v4/posts"). A zero status code removes the error.
+func (s *Server) SetError(method, path string, status int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if status == 0 {
+		delete(s.errors, method+" "+path)
+	} else {
+		s.errors[method+" "+path] = status
+	}
+}
+
+// authenticate returns the user authenticated by the given Authorization header, or nil.
+// The caller must hold s.mu.
+func (s *Server) authenticate(authorization string) *User {
+	token, found := strings.CutPrefix(authorization, "Bearer ")
+	if !found {
+		return nil
+	}
+	if token == s.Token {
+		return s.me
+	}
+	if userID, found := s.sessions[token]; found {
+		return s.users[userID]
+	}
+	return s.authenticateAccessToken(token)
+}
+
+// user returns the user authenticated by the request token, the logged user in local mode.
+func user(r *http.Request) *User {
+	return r.Context().Value(userKey{}).(*User)
+}
+
+// intercept records the requests, checks the access token, and returns the errors set by SetError.
+func (s *Server) intercept(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		s.mu.Lock()
+		s.requests = append(s.requests, Request{
+			Method: r.Method,
+			Path:   r.URL.Path,
+			Query:  r.URL.Query(),
+			Header: r.Header.Clone(),
+			Body:   body,
+
+			RemoteAddr: r.RemoteAddr,
+		})
+		status, fail := s.errors[r.Method+" "+r.URL.Path]
+		user := s.authenticate(r.Header.Get("Authorization"))
+		s.mu.Unlock()
+
+		// Like in Mattermost, the ping, client config, login and SSO endpoints do not require a session.
+		public := !strings.HasPrefix(r.URL.Path, "/api/v4/") ||
+			r.URL.Path == "/api/v4/system/ping" ||
+			r.URL.Path == "/api/v4/config/client" ||
+			strings.HasPrefix(r.URL.Path, "/api/v4/users/login")
+		if user == nil && !s.localMode && !public {
+			writeError(w, http.StatusUnauthorized, "api.context.session_expired.app_error", "Invalid or expired session, please login again.")
+			return
+		}
+		if user == nil {
+			user = s.me
+		}
+		r = r.WithContext(context.WithValue(r.Context(), userKey{}, user))
+		if fail {
+			writeError(w, status, "mattermosttest.error", http.StatusText(status))
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// writeJSON sends the JSON encoding of v with the given HTTP status code.
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeError sends an error formatted like the Mattermost ones.
+func writeError(w http.ResponseWriter, status int, id, message string) {
+	writeJSON(w, status, map[string]interface{}{
+		"id":          id,
+		"message":     message,
+		"status_code": status,
+	})
+}
+
+// ping handles GET /api/v4/system/ping.
+func (s *Server) ping(w http.ResponseWriter, r *http.Request) {
+	var status = map[string]string{"status": "OK"}
+	if r.URL.Query().Get("get_server_status") == "true" {
+		status["database_status"] = "OK"
+		status["filestore_status"] = "OK"
+	}
+	w.Header().Set("X-Version-Id", s.Version+"."+s.Version+".mattermosttest.false")
+	writeJSON(w, http.StatusOK, status)
+}
+
+// getMe handles GET /api/v4/users/me.
+func (s *Server) getMe(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, user(r))
+}
+
+// login handles POST /api/v4/users/login.
+func (s *Server) login(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		LoginID  string `json:"login_id"`
+		Password string `json:"password"`
+		Token    string `json:"token"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "api.context.invalid_body_param.app_error", "Invalid or missing login in request body.")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var u *User
+	for _, candidate := range s.users {
+		if candidate.Username == req.LoginID {
+			u = candidate
+		}
+	}
+	if u == nil || u.password == "" || u.password != req.Password {
+		writeError(w, http.StatusUnauthorized, "api.user.login.invalid_credentials_email_username", "Enter a valid email or username and/or password.")
+		return
+	}
+	if u.mfaCode != "" && u.mfaCode != req.Token {
+		writeError(w, http.StatusUnauthorized, "mfa.validate_token.authenticate.app_error", "Invalid MFA token.")
+		return
+	}
+
+	token := s.newSession(u.ID)
+	w.Header().Set("Token", token)
+	http.SetCookie(w, &http.Cookie{
+		Name:     "MMAUTHTOKEN",
+		Value:    token,
+		Path:     "/",
+		MaxAge:   int(SessionTTL.Seconds()),
+		HttpOnly: true,
+	})
+	writeJSON(w, http.StatusOK, u)
+}
+
+// newSession opens a session for the given user and returns its token.
+// It must be called with the lock held.
+func (s *Server) newSession(userID string) string {
+	token := s.newID()
+	s.sessions[token] = userID
+	return token
+}
+
+// getClientConfig handles GET /api/v4/config/client.
+func (s *Server) getClientConfig(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var config = map[string]string{
+		"Version":                   s.Version,
+		"EnableSignUpWithGitLab":    "false",
+		"EnableSignUpWithGoogle":    "false",
+		"EnableSignUpWithOffice365": "false",
+		"EnableSignUpWithOpenId":    "false",
+	}
+	switch s.ssoService {
+	case "gitlab":
+		config["EnableSignUpWithGitLab"] = "true"
+	case "google":
+		config["EnableSignUpWithGoogle"] = "true"
+	case "office365":
+		config["EnableSignUpWithOffice365"] = "true"
+	case "openid":
+		config["EnableSignUpWithOpenId"] = "true"
+	}
+	writeJSON(w, http.StatusOK, config)
+}
+
+// startSSOLogin handles GET /oauth/{service}/mobile_login, redirecting to the identity provider.
+func (s *Server) startSSOLogin(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.ssoService == "" || r.PathValue("service") != s.ssoService {
+		writeError(w, http.StatusNotImplemented, "api.user.authorize_oauth_user.unsupported.app_error", "Unsupported OAuth service provider.")
+		return
+	}
+	query := r.URL.Query()
+	if query.Get("redirect_to") == "" || query.Get("code_challenge_method") != "S256" {
+		writeError(w, http.StatusBadRequest, "api.context.invalid_url_param.app_error", "Invalid or missing redirect_to or code_challenge.")
+		return
+	}
+
+	state := s.newID()
+	s.ssoLogins[state] = &ssoLogin{
+		redirectTo:    query.Get("redirect_to"),
+		state:         query.Get("state"),
+		codeChallenge: query.Get("code_challenge"),
+	}
+	http.Redirect(w, r, s.ssoProvider+"/authorize?"+url.Values{
+		"client_id":     {"mattermost"},
+		"response_type": {"code"},
+		"redirect_uri":  {s.Server.URL + "/signup/" + s.ssoService + "/complete"},
+		"state":         {state},
+	}.Encode(), http.StatusFound)
+}
+
+// completeSSOLogin handles GET /signup/{service}/complete, the redirection from the identity
+// provider: the user is identified and redirected to the client with a login code.
+func (s *Server) completeSSOLogin(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	login, found := s.ssoLogins[r.URL.Query().Get("state")]
+	delete(s.ssoLogins, r.URL.Query().Get("state"))
+	provider := s.ssoProvider
+	s.mu.Unlock()
+	if !found {
+		writeError(w, http.StatusBadRequest, "api.oauth.invalid_state_token.app_error", "Invalid state token.")
+		return
+	}
+
+	username, err := getProviderUsername(provider, r.URL.Query().Get("code"))
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, "api.user.authorize_oauth_user.token_failed.app_error", err.Error())
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var u *User
+	for _, candidate := range s.users {
+		if candidate.Username == username {
+			u = candidate
+		}
+	}
+	if u == nil {
+		u = &User{ID: s.newID(), Username: username}
+		s.users[u.ID] = u
+	}
+	login.userID = u.ID
+
+	code := s.newID()
+	s.loginCodes[code] = login
+	http.Redirect(w, r, login.redirectTo+"?"+url.Values{
+		"login_code": {code},
+		"state":      {login.state},
+	}.Encode(), http.StatusFound)
+}
+
+// getProviderUsername exchanges the authorization code with the identity provider at the given
+// URL and returns the name of the authenticated user.
+func getProviderUsername(provider, code string) (string, error) {
+	response, err := http.PostForm(provider+"/token", url.Values{
+		"grant_type": {"authorization_code"},
+		"code":       {code},
+	})
+	if err != nil {
+		return "", err
+	}
+	defer response.Body.Close()
+	var token struct {
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(response.Body).Decode(&token); err != nil || token.AccessToken == "" {
+		return "", fmt.Errorf("the authorization code has been rejected")
+	}
+
+	req, err := http.NewRequest(http.MethodGet, provider+"/userinfo", nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Authorization", "Bearer "+token.AccessToken)
+	response, err = http.DefaultClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer response.Body.Close()
+	var userinfo struct {
+		Username string `json:"preferred_username"`
+	}
+	if err := json.NewDecoder(response.Body).Decode(&userinfo); err != nil || userinfo.Username == "" {
+		return "", fmt.Errorf("the user info cannot be read")
+	}
+	return userinfo.Username, nil
+}
+
+// exchangeLoginCode handles POST /api/v4/users/login/sso/code-exchange.
+func (s *Server) exchangeLoginCode(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		LoginCode    string `json:"login_code"`
+		CodeVerifier string `json:"code_verifier"`
+		State        string `json:"state"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "api.context.invalid_body_param.app_error", "Invalid or missing login code in request body.")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	login, found := s.loginCodes[req.LoginCode]
+	delete(s.loginCodes, req.LoginCode)
+	challenge := sha256.Sum256([]byte(req.CodeVerifier))
+	if !found || login.state != req.State ||
+		login.codeChallenge != base64.RawURLEncoding.EncodeToString(challenge[:]) {
+		writeError(w, http.StatusBadRequest, "api.oauth.get_access_token.bad_request.app_error", "Invalid or expired login code.")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]string{
+		"token": s.newSession(login.userID),
+		"csrf":  s.newID(),
+	})
+}
+
+// logout handles POST /api/v4/users/logout.
+func (s *Server) logout(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.sessions, strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "))
+	writeJSON(w, http.StatusOK, map[string]string{"status": "OK"})
+}
+
+// getUserByUsername handles GET /api/v4/users/username/{username}.
+func (s *Server) getUserByUsername(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, u := range s.users {
+		if u.Username == r.PathValue("username") {
+			writeJSON(w, http.StatusOK, u)
+			return
+		}
+	}
+	writeError(w, http.StatusNotFound, "app.user.missing_account.const", "Unable to find the user.")
+}
+
+// getUser handles GET /api/v4/users/{user_id}.
+func (s *Server) getUser(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if u, found := s.users[r.PathValue("user_id")]; found {
+		writeJSON(w, http.StatusOK, u)
+		return
+	}
+	writeError(w, http.StatusNotFound, "app.user.missing_account.const", "Unable to find the user.")
+}
+
+// createChannel creates, or returns if it already exists, the direct or group channel
+// between the users whose IDs are sent in the request body.
+func (s *Server) createChannel(w http.ResponseWriter, r *http.Request, channelType string, minUsers, maxUsers int) {
+	var userIDs []string
+	if err := json.NewDecoder(r.Body).Decode(&userIDs); err != nil || len(userIDs) < minUsers || len(userIDs) > maxUsers {
+		writeError(w, http.StatusBadRequest, "api.context.invalid_body_param.app_error", "Invalid or missing user_ids in request body.")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, id := range userIDs {
+		if _, found := s.users[id]; !found {
+			writeError(w, http.StatusBadRequest, "api.context.invalid_url_param.app_error", "Invalid or missing user_id.")
+			return
+		}
+	}
+
+	ids := append([]string(nil), userIDs...)
+	sort.Strings(ids)
+	name := strings.Join(ids, "__")
+	for _, c := range s.channels {
+		if c.Type == channelType && c.Name == name {
+			writeJSON(w, http.StatusCreated, c)
+			return
+		}
+	}
+
+	c := &Channel{ID: s.newID(), Name: name, Type: channelType}
+	s.channels[c.ID] = c
+	s.members[c.ID] = make(map[string]bool)
+	for _, id := range userIDs {
+		s.members[c.ID][id] = true
+	}
+	writeJSON(w, http.StatusCreated, c)
+}
+
+// createDirectChannel handles POST /api/v4/channels/direct.
+func (s *Server) createDirectChannel(w http.ResponseWriter, r *http.Request) {
+	s.createChannel(w, r, "D", 2, 2)
+}
+
+// createGroupChannel handles POST /api/v4/channels/group.
+func (s *Server) createGroupChannel(w http.ResponseWriter, r *http.Request) {
+	s.createChannel(w, r, "G", 3, 8)
+}
+
+// getChannel handles GET /api/v4/channels/{channel_id}.
+func (s *Server) getChannel(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if c, found := s.channels[r.PathValue("channel_id")]; found {
+		writeJSON(w, http.StatusOK, c)
+		return
+	}
+	writeError(w, http.StatusNotFound, "app.channel.get.existing.app_error", "Unable to find the existing channel.")
+}
+
+// getChannelMember handles GET /api/v4/channels/{channel_id}/members/me.
+func (s *Server) getChannelMember(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	channelID := r.PathValue("channel_id")
+	me := user(r)
+	if !s.members[channelID][me.ID] {
+		writeError(w, http.StatusNotFound, "app.channel.get_member.missing.app_error", "No channel member found for that user ID and channel ID.")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{
+		"channel_id": channelID,
+		"user_id":    me.ID,
+		"roles":      "channel_user",
+	})
+}
+
+// writePostList sends the given page of the posts matching the given function, newest first,
+// as a Mattermost post list. All the posts are sent when perPage is not positive.
+// It must be called with the lock held.
+func (s *Server) writePostList(w http.ResponseWriter, page, perPage int, match func(*Post) bool) {
+	var order = []string{}
+	for i := len(s.postOrder) - 1; i >= 0; i-- {
+		if p := s.posts[s.postOrder[i]]; match(p) {
+			order = append(order, p.ID)
+		}
+	}
+	if perPage > 0 {
+		order = paginate(order, page, perPage)
+	}
+
+	var posts = make(map[string]*Post)
+	for _, id := range order {
+		posts[id] = s.posts[id]
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"order": order, "posts": posts})
+}
+
+// getChannelPosts handles GET /api/v4/channels/{channel_id}/posts.
+func (s *Server) getChannelPosts(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	channelID := r.PathValue("channel_id")
+	if _, found := s.channels[channelID]; !found {
+		writeError(w, http.StatusNotFound, "app.channel.get.existing.app_error", "Unable to find the existing channel.")
+		return
+	}
+	page, perPage := getPaging(r)
+	s.writePostList(w, page, perPage, func(p *Post) bool { return p.ChannelID == channelID })
+}
+
+// createPost handles POST /api/v4/posts.
+func (s *Server) createPost(w http.ResponseWriter, r *http.Request) {
+	var post Post
+	if err := json.NewDecoder(r.Body).Decode(&post); err != nil {
+		writeError(w, http.StatusBadRequest, "api.context.invalid_body_param.app_error", "Invalid or missing post in request body.")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, found := s.channels[post.ChannelID]
+	if !found {
+		writeError(w, http.StatusBadRequest, "api.context.invalid_body_param.app_error", "Invalid or missing channel_id in request body.")
+		return
+	}
+	if c.DeleteAt != 0 {
+		writeError(w, http.StatusForbidden, "api.post.create_post.can_not_post_to_deleted.error", "Can not post to deleted channel.")
+		return
+	}
+	if post.RootID != "" {
+		if root, found := s.posts[post.RootID]; !found || root.ChannelID != post.ChannelID {
+			writeError(w, http.StatusBadRequest, "api.post.create_post.root_id.app_error", "Invalid RootId parameter.")
+			return
+		}
+	}
+
+	post.ID = s.newID()
+	post.UserID = user(r).ID
+	post.CreateAt = now()
+	post.UpdateAt = post.CreateAt
+	s.posts[post.ID] = &post
+	s.postOrder = append(s.postOrder, post.ID)
+
+	writeJSON(w, http.StatusCreated, post)
+}
+
+// getPost handles GET /api/v4/posts/{post_id}.
+func (s *Server) getPost(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if p, found := s.posts[r.PathValue("post_id")]; found {
+		writeJSON(w, http.StatusOK, p)
+		return
+	}
+	writeError(w, http.StatusNotFound, "app.post.get.app_error", "Unable to get the post.")
+}
+
+// patchPost handles PUT /api/v4/posts/{post_id}/patch.
+func (s *Server) patchPost(w http.ResponseWriter, r *http.Request) {
+	var patch struct {
+		Message *string                 `json:"message"`
+		Props   *map[string]interface{} `json:"props"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&patch); err != nil {
+		writeError(w, http.StatusBadRequest, "api.context.invalid_body_param.app_error", "Invalid or missing patch in request body.")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p, found := s.posts[r.PathValue("post_id")]
+	if !found {
+		writeError(w, http.StatusNotFound, "app.post.get.app_error", "Unable to get the post.")
+		return
+	}
+	if patch.Message != nil {
+		p.Message = *patch.Message
+	}
+	if patch.Props != nil {
+		p.Props = *patch.Props
+	}
+	p.UpdateAt = now()
+
+	writeJSON(w, http.StatusOK, p)
+}
+
+// getThread handles GET /api/v4/posts/{post_id}/thread.
+func (s *Server) getThread(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	postID := r.PathValue("post_id")
+	p, found := s.posts[postID]
+	if !found {
+		writeError(w, http.StatusNotFound, "app.post.get.app_error", "Unable to get the post.")
+		return
+	}
+	if p.RootID != "" {
+		postID = p.RootID
+	}
+	s.writePostList(w, 0, 0, func(p *Post) bool { return p.ID == postID || p.RootID == postID })
+}
+
+// getReactions handles GET /api/v4/posts/{post_id}/reactions.
+func (s *Server) getReactions(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var reactions = []Reaction{}
+	for _, reaction := range s.reactions {
+		if reaction.PostID == r.PathValue("post_id") {
+			reactions = append(reactions, reaction)
+		}
+	}
+	writeJSON(w, http.StatusOK, reactions)
+}
+
+// addReaction handles POST /api/v4/reactions.
+func (s *Server) addReaction(w http.ResponseWriter, r *http.Request) {
+	var reaction Reaction
+	if err := json.NewDecoder(r.Body).Decode(&reaction); err != nil || reaction.EmojiName == "" {
+		writeError(w, http.StatusBadRequest, "api.context.invalid_body_param.app_error", "Invalid or missing reaction in request body.")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, found := s.posts[reaction.PostID]; !found {
+		writeError(w, http.StatusNotFound, "app.post.get.app_error", "Unable to get the post.")
+		return
+	}
+	reaction.CreateAt = now()
+	s.reactions = append(s.reactions, reaction)
+
+	writeJSON(w, http.StatusCreated, reaction)
+}
+
+// uploadFiles handles POST /api/v4/files (multipart form with a channel_id and files).
+func (s *Server) uploadFiles(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseMultipartForm(maxMemory); err != nil {
+		writeError(w, http.StatusBadRequest, "api.file.upload_file.read_request.app_error", "Unable to upload file(s).")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	channelID := r.FormValue("channel_id")
+	if _, found := s.channels[channelID]; !found {
+		writeError(w, http.StatusBadRequest, "api.context.invalid_body_param.app_error", "Invalid or missing channel_id.")
+		return
+	}
+
+	var infos = []*FileInfo{}
+	for _, headers := range r.MultipartForm.File {
+		for _, header := range headers {
+			f, err := header.Open()
+			if err != nil {
+				writeError(w, http.StatusBadRequest, "api.file.upload_file.read_request.app_error", err.Error())
+				return
+			}
+			content, err := io.ReadAll(f)
+			f.Close()
+			if err != nil {
+				writeError(w, http.StatusBadRequest, "api.file.upload_file.read_request.app_error", err.Error())
+				return
+			}
+
+			info := &FileInfo{
+				ID:        s.newID(),
+				ChannelID: channelID,
+				Name:      header.Filename,
+				Size:      int64(len(content)),
+				MimeType:  header.Header.Get("Content-Type"),
+			}
+			s.files[info.ID] = info
+			s.contents[info.ID] = content
+			infos = append(infos, info)
+		}
+	}
+
+	writeJSON(w, http.StatusCreated, map[string]interface{}{"file_infos": infos, "client_ids": []string{}})
+}
+
+// getFileInfo handles GET /api/v4/files/{file_id}/info.
+func (s *Server) getFileInfo(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if info, found := s.files[r.PathValue("file_id")]; found {
+		writeJSON(w, http.StatusOK, info)
+		return
+	}
+	writeError(w, http.StatusNotFound, "app.file_info.get.app_error", "Unable to get the file info.")
+}
+
+// getFile handles GET /api/v4/files/{file_id}.
+func (s *Server) getFile(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	info, found := s.files[r.PathValue("file_id")]
+	if !found {
+		writeError(w, http.StatusNotFound, "app.file_info.get.app_error", "Unable to get the file info.")
+		return
+	}
+	w.Header().Set("Content-Type", info.MimeType)
+	w.Write(s.contents[info.ID])
+}
@@ -0,0 +1,107 @@
+/*
+  Copyright 2026 Davide Madrisan <d.madrisan@proton.me>
+
+  Licensed under the Mozilla Public License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+      https://www.mozilla.org/en-US/MPL/2.0/
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+*/
+
+package mattermosttest
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// do sends a request to the server and decodes the JSON response into v.
+func do(t *testing.T, srv *Server, method, path, contentType string, body io.Reader, v interface{}) int {
+	t.Helper()
+
+	req, err := http.NewRequest(method, srv.URL+path, body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer "+srv.Token)
+	req.Header.Set("Content-Type", contentType)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if v != nil {
+		json.NewDecoder(resp.Body).Decode(v)
+	}
+	return resp.StatusCode
+}
+
+func TestServer(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+
+	alice, bob := srv.AddUser("alice"), srv.AddUser("bob")
+
+	var group Channel
+	ids := `["` + srv.Me().ID + `","` + alice.ID + `","` + bob.ID + `"]`
+	if status := do(t, srv, "POST", "/api/v4/channels/group", "application/json", strings.NewReader(ids), &group); status != http.StatusCreated || group.Type != "G" {
+		t.Fatal("unexpected group channel", status, group)
+	}
+
+	var root, reply Post
+	do(t, srv, "POST", "/api/v4/posts", "application/json",
+		strings.NewReader(`{"channel_id": "`+group.ID+`", "message": "Deploy?"}`), &root)
+	do(t, srv, "POST", "/api/v4/posts", "application/json",
+		strings.NewReader(`{"channel_id": "`+group.ID+`", "root_id": "`+root.ID+`", "message": "approve"}`), &reply)
+	if status := do(t, srv, "POST", "/api/v4/posts", "application/json",
+		strings.NewReader(`{"channel_id": "unknown", "message": "lost"}`), nil); status != http.StatusBadRequest {
+		t.Error("a post to an unknown channel should fail, got", status)
+	}
+
+	var thread struct {
+		Order []string        `json:"order"`
+		Posts map[string]Post `json:"posts"`
+	}
+	do(t, srv, "GET", "/api/v4/posts/"+reply.ID+"/thread", "", nil, &thread)
+	if len(thread.Order) != 2 || thread.Posts[reply.ID].RootID != root.ID {
+		t.Error("unexpected thread", thread)
+	}
+
+	srv.AddReaction(alice.ID, root.ID, "+1")
+	var reactions []Reaction
+	do(t, srv, "GET", "/api/v4/posts/"+root.ID+"/reactions", "", nil, &reactions)
+	if len(reactions) != 1 || reactions[0].UserID != alice.ID || reactions[0].EmojiName != "+1" {
+		t.Error("unexpected reactions", reactions)
+	}
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	mw.WriteField("channel_id", group.ID)
+	fw, _ := mw.CreateFormFile("files", "report.txt")
+	fw.Write([]byte("all good"))
+	mw.Close()
+
+	var upload struct {
+		FileInfos []FileInfo `json:"file_infos"`
+	}
+	do(t, srv, "POST", "/api/v4/files", mw.FormDataContentType(), &body, &upload)
+	if len(upload.FileInfos) != 1 || upload.FileInfos[0].Name != "report.txt" || upload.FileInfos[0].Size != 8 {
+		t.Fatal("unexpected upload", upload)
+	}
+
+	if status := do(t, srv, "GET", "/api/v4/unknown", "", nil, nil); status != http.StatusNotFound {
+		t.Error("an unknown endpoint should return 404, got", status)
+	}
+}
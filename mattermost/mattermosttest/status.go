@@ -0,0 +1,159 @@
+/*
+  Copyright 2026 Davide Madrisan <d.madrisan@proton.me>
+
+  Licensed under the Mozilla Public License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+      https://www.mozilla.org/en-US/MPL/2.0/
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+*/
+
+package mattermosttest
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// customStatusProp is the user property containing the JSON encoded custom status.
+const customStatusProp = "customStatus"
+
+// Status returns the status of the given user ("online" by default) and, if the status is
+// "dnd" with an end time, the time it ends.
+func (s *Server) Status(userID string) (string, time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u, found := s.users[userID]
+	if !found {
+		return "", time.Time{}
+	}
+	var end time.Time
+	if u.dndEndTime != 0 {
+		end = time.Unix(u.dndEndTime, 0)
+	}
+	return u.getStatus(), end
+}
+
+// CustomStatus returns the JSON encoded custom status of the given user, empty if not set.
+func (s *Server) CustomStatus(userID string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if u, found := s.users[userID]; found {
+		return u.Props[customStatusProp]
+	}
+	return ""
+}
+
+// getStatus returns the status of the user, "online" if never set.
+func (u *User) getStatus() string {
+	if u.status == "" {
+		return "online"
+	}
+	return u.status
+}
+
+// getStatus handles GET /api/v4/users/{user_id}/status.
+func (s *Server) getStatus(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u, found := s.users[r.PathValue("user_id")]
+	if !found {
+		writeError(w, http.StatusNotFound, "app.user.missing_account.const", "Unable to find the user.")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"user_id":          u.ID,
+		"status":           u.getStatus(),
+		"manual":           u.status != "",
+		"last_activity_at": now(),
+		"dnd_end_time":     u.dndEndTime,
+	})
+}
+
+// updateStatus handles PUT /api/v4/users/{user_id}/status.
+func (s *Server) updateStatus(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		UserID     string `json:"user_id"`
+		Status     string `json:"status"`
+		DNDEndTime int64  `json:"dnd_end_time"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.UserID != r.PathValue("user_id") {
+		writeError(w, http.StatusBadRequest, "api.context.invalid_body_param.app_error", "Invalid or missing status in request body.")
+		return
+	}
+	switch req.Status {
+	case "online", "away", "dnd", "offline":
+	default:
+		writeError(w, http.StatusBadRequest, "api.context.invalid_body_param.app_error", "Invalid or missing status in request body.")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u, found := s.users[req.UserID]
+	if !found {
+		writeError(w, http.StatusNotFound, "app.user.missing_account.const", "Unable to find the user.")
+		return
+	}
+	u.status, u.dndEndTime = req.Status, 0
+	if req.Status == "dnd" {
+		u.dndEndTime = req.DNDEndTime
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"user_id":      u.ID,
+		"status":       u.status,
+		"manual":       true,
+		"dnd_end_time": u.dndEndTime,
+	})
+}
+
+// updateCustomStatus handles PUT /api/v4/users/me/status/custom.
+func (s *Server) updateCustomStatus(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Emoji     string `json:"emoji"`
+		Text      string `json:"text"`
+		Duration  string `json:"duration,omitempty"`
+		ExpiresAt string `json:"expires_at,omitempty"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || (req.Emoji == "" && req.Text == "") {
+		writeError(w, http.StatusBadRequest, "api.custom_status.set_custom_statuses.update.app_error", "Failed to update the custom status.")
+		return
+	}
+	if req.ExpiresAt != "" {
+		if _, err := time.Parse(time.RFC3339, req.ExpiresAt); err != nil {
+			writeError(w, http.StatusBadRequest, "api.custom_status.set_custom_statuses.update.app_error", "Failed to update the custom status.")
+			return
+		}
+	}
+	data, _ := json.Marshal(req)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u := user(r)
+	if u.Props == nil {
+		u.Props = make(map[string]string)
+	}
+	u.Props[customStatusProp] = string(data)
+	writeJSON(w, http.StatusOK, map[string]string{"status": "OK"})
+}
+
+// removeCustomStatus handles DELETE /api/v4/users/me/status/custom.
+func (s *Server) removeCustomStatus(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(user(r).Props, customStatusProp)
+	writeJSON(w, http.StatusOK, map[string]string{"status": "OK"})
+}
@@ -0,0 +1,297 @@
+/*
+  Copyright 2026 Davide Madrisan <d.madrisan@proton.me>
+
+  Licensed under the Mozilla Public License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+      https://www.mozilla.org/en-US/MPL/2.0/
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+*/
+
+package mattermosttest
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Bot is a Mattermost bot account. Its user ID is the ID of the user created with it.
+type Bot struct {
+	UserID      string `json:"user_id"`
+	Username    string `json:"username"`
+	DisplayName string `json:"display_name"`
+	Description string `json:"description"`
+	OwnerID     string `json:"owner_id"`
+	CreateAt    int64  `json:"create_at"`
+	UpdateAt    int64  `json:"update_at"`
+	DeleteAt    int64  `json:"delete_at"`
+}
+
+// AccessToken is a personal access token. The token itself is only sent when it's created.
+type AccessToken struct {
+	ID          string `json:"id"`
+	Token       string `json:"token,omitempty"`
+	UserID      string `json:"user_id"`
+	Description string `json:"description"`
+	IsActive    bool   `json:"is_active"`
+
+	// sessionID is the ID of the session opened at the first use of the token,
+	// and firstUsed and lastUsed are its creation and last activity times.
+	sessionID string
+	firstUsed int64
+	lastUsed  int64
+}
+
+// SetAccessTokenUsage sets the times of the first and last use of the given access token,
+// as recorded by the session Mattermost opens when a token is used.
+func (s *Server) SetAccessTokenUsage(tokenID string, firstUsed, lastUsed time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if at, found := s.accessTokens[tokenID]; found {
+		if at.sessionID == "" {
+			at.sessionID = s.newID()
+		}
+		at.firstUsed, at.lastUsed = firstUsed.UnixMilli(), lastUsed.UnixMilli()
+	}
+}
+
+// authenticateAccessToken returns the user owning the given active personal access token,
+// recording its use, or nil. The caller must hold s.mu.
+func (s *Server) authenticateAccessToken(token string) *User {
+	for _, at := range s.accessTokens {
+		if at.Token != token || !at.IsActive {
+			continue
+		}
+		if bot, found := s.bots[at.UserID]; found && bot.DeleteAt != 0 {
+			return nil
+		}
+		if at.sessionID == "" {
+			at.sessionID = s.newID()
+			at.firstUsed = now()
+		}
+		at.lastUsed = now()
+		return s.users[at.UserID]
+	}
+	return nil
+}
+
+// getPaging returns the page and per_page query parameters of the request.
+func getPaging(r *http.Request) (int, int) {
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	perPage, err := strconv.Atoi(r.URL.Query().Get("per_page"))
+	if err != nil || perPage <= 0 {
+		perPage = 60
+	}
+	return page, perPage
+}
+
+// paginate returns the page of the given length of items.
+func paginate[T any](items []T, page, perPage int) []T {
+	start := page * perPage
+	if start >= len(items) {
+		return []T{}
+	}
+	return items[start:min(start+perPage, len(items))]
+}
+
+// createBot handles POST /api/v4/bots.
+func (s *Server) createBot(w http.ResponseWriter, r *http.Request) {
+	var req Bot
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Username == "" {
+		writeError(w, http.StatusBadRequest, "api.context.invalid_body_param.app_error", "Invalid or missing bot in request body.")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, u := range s.users {
+		if u.Username == req.Username {
+			writeError(w, http.StatusBadRequest, "app.user.save.username_exists.app_error", "An account with that username already exists.")
+			return
+		}
+	}
+
+	u := &User{ID: s.newID(), Username: req.Username}
+	s.users[u.ID] = u
+	bot := &Bot{
+		UserID:      u.ID,
+		Username:    req.Username,
+		DisplayName: req.DisplayName,
+		Description: req.Description,
+		OwnerID:     user(r).ID,
+		CreateAt:    now(),
+	}
+	bot.UpdateAt = bot.CreateAt
+	s.bots[u.ID] = bot
+
+	writeJSON(w, http.StatusCreated, bot)
+}
+
+// getBots handles GET /api/v4/bots.
+func (s *Server) getBots(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var bots = []*Bot{}
+	for _, bot := range s.bots {
+		if bot.DeleteAt == 0 || r.URL.Query().Get("include_deleted") == "true" {
+			bots = append(bots, bot)
+		}
+	}
+	sort.Slice(bots, func(i, j int) bool { return bots[i].UserID < bots[j].UserID })
+
+	page, perPage := getPaging(r)
+	writeJSON(w, http.StatusOK, paginate(bots, page, perPage))
+}
+
+// disableBot handles POST /api/v4/bots/{bot_user_id}/disable.
+func (s *Server) disableBot(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	bot, found := s.bots[r.PathValue("bot_user_id")]
+	if !found {
+		writeError(w, http.StatusNotFound, "store.sql_bot.get.missing.app_error", "Bot does not exist.")
+		return
+	}
+	if bot.DeleteAt == 0 {
+		bot.DeleteAt = now()
+		bot.UpdateAt = bot.DeleteAt
+	}
+	writeJSON(w, http.StatusOK, bot)
+}
+
+// createAccessToken handles POST /api/v4/users/{user_id}/tokens.
+func (s *Server) createAccessToken(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Description string `json:"description"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Description == "" {
+		writeError(w, http.StatusBadRequest, "model.user_access_token.is_valid.description.app_error", "Invalid description, must be 255 or less characters.")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	userID := r.PathValue("user_id")
+	if _, found := s.users[userID]; !found {
+		writeError(w, http.StatusBadRequest, "api.context.invalid_url_param.app_error", "Invalid or missing user_id.")
+		return
+	}
+
+	at := &AccessToken{
+		ID:          s.newID(),
+		Token:       s.newID(),
+		UserID:      userID,
+		Description: req.Description,
+		IsActive:    true,
+	}
+	s.accessTokens[at.ID] = at
+	writeJSON(w, http.StatusOK, at)
+}
+
+// getUserResource handles GET /api/v4/users/{user_id}/tokens, sessions, status, teams and channels.
+// They share a pattern, which would otherwise conflict with GET /api/v4/users/username/{username}.
+func (s *Server) getUserResource(w http.ResponseWriter, r *http.Request) {
+	switch r.PathValue("resource") {
+	case "tokens":
+		s.getAccessTokens(w, r)
+	case "sessions":
+		s.getSessions(w, r)
+	case "status":
+		s.getStatus(w, r)
+	case "teams":
+		s.getUserTeams(w, r)
+	case "channels":
+		s.getUserChannels(w, r)
+	default:
+		writeError(w, http.StatusNotFound, "api.context.404.app_error", "Sorry, we could not find the page.")
+	}
+}
+
+// userAccessTokens returns the access tokens of the given user, sorted by ID.
+// The caller must hold s.mu.
+func (s *Server) userAccessTokens(userID string) []*AccessToken {
+	var tokens = []*AccessToken{}
+	for _, at := range s.accessTokens {
+		if at.UserID == userID {
+			tokens = append(tokens, at)
+		}
+	}
+	sort.Slice(tokens, func(i, j int) bool { return tokens[i].ID < tokens[j].ID })
+	return tokens
+}
+
+// getAccessTokens handles GET /api/v4/users/{user_id}/tokens.
+func (s *Server) getAccessTokens(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var tokens = []AccessToken{}
+	for _, at := range s.userAccessTokens(r.PathValue("user_id")) {
+		token := *at
+		token.Token = ""
+		tokens = append(tokens, token)
+	}
+
+	page, perPage := getPaging(r)
+	writeJSON(w, http.StatusOK, paginate(tokens, page, perPage))
+}
+
+// getSessions handles GET /api/v4/users/{user_id}/sessions.
+// Only the sessions opened by the use of the personal access tokens are returned.
+func (s *Server) getSessions(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var sessions = []map[string]interface{}{}
+	for _, at := range s.userAccessTokens(r.PathValue("user_id")) {
+		if at.sessionID == "" {
+			continue
+		}
+		sessions = append(sessions, map[string]interface{}{
+			"id":               at.sessionID,
+			"user_id":          at.UserID,
+			"create_at":        at.firstUsed,
+			"last_activity_at": at.lastUsed,
+			"props": map[string]string{
+				"type":                 "UserAccessToken",
+				"user_access_token_id": at.ID,
+			},
+		})
+	}
+	writeJSON(w, http.StatusOK, sessions)
+}
+
+// revokeAccessToken handles POST /api/v4/users/tokens/revoke.
+func (s *Server) revokeAccessToken(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		TokenID string `json:"token_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "api.context.invalid_body_param.app_error", "Invalid or missing token_id in request body.")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, found := s.accessTokens[req.TokenID]; !found {
+		writeError(w, http.StatusNotFound, "app.user_access_token.get_by_token.app_error", "Unable to get user access token.")
+		return
+	}
+	delete(s.accessTokens, req.TokenID)
+	writeJSON(w, http.StatusOK, map[string]string{"status": "OK"})
+}
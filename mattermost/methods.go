@@ -18,73 +18,194 @@
 package mattermost
 
 import (
-	"crypto/tls"
+	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
 
+	"github.com/spf13/viper"
+
 	"github.com/madrisan/go-mattermost-notify/config"
 )
 
+// NewHTTPClient returns an HTTP client configured with the given options.
+// The clients use the process-wide shared transport, so their connections are reused.
+func NewHTTPClient(opts config.Options) *http.Client {
+	return &http.Client{
+		Timeout:   opts.ConnectionTimeout,
+		Transport: SharedTransport(opts),
+	}
+}
+
+// Response is the response of Mattermost to a query.
+type Response struct {
+	StatusCode int
+	Header     http.Header
+	// Data is the decoded JSON body of the response, nil if empty.
+	Data interface{}
+}
+
+// APIError is the error returned when Mattermost answers a query with a non-2xx status code.
+type APIError struct {
+	URL        string
+	StatusCode int
+	Header     http.Header
+	// ID and Message are the error identifier and message sent by Mattermost, if any.
+	ID      string
+	Message string
+}
+
+// Error returns the description of the API error.
+func (e *APIError) Error() string {
+	return fmt.Sprintf("the HTTP query to %s has ended with a %d (\"%s\") code",
+		e.URL, e.StatusCode, http.StatusText(e.StatusCode))
+}
+
 // queryAPIv4 makes a query to Mattermost using its REST API v4.
-func queryAPIv4(method, endpoint string, payload io.Reader, opts config.Options) (interface{}, error) {
+func queryAPIv4(method, endpoint string, payload io.Reader, opts config.Options) (*Response, error) {
 	baseURL, err := getURL()
 	if err != nil {
 		return nil, err
 	}
 
-	accessToken, err := getAccessToken()
-	if err != nil {
-		return nil, err
+	// The access token is optional in local mode.
+	var accessToken = viper.GetString("access-token")
+	var session *Session
+	var _, local = getSocketPath(baseURL)
+	if !local {
+		accessToken, session, err = getAccessToken()
+		var expired *sessionExpiredError
+		if errors.As(err, &expired) && expired.session.SSO != "" && SessionRenewer != nil {
+			// The session has just been renewed: it's not renewed again if rejected.
+			accessToken, err = renewSession(expired.profile, expired.session, opts)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// The SSO sessions have no known expiry: they are renewed when rejected by Mattermost,
+	// so the payload is kept for retrying the query.
+	renewable := SessionRenewer != nil && session != nil && session.SSO != ""
+	if renewable && payload != nil {
+		body, err := io.ReadAll(payload)
+		if err != nil {
+			return nil, err
+		}
+		payload = bytes.NewReader(body)
 	}
 
-	var bearer = forgeBearerAuthentication(accessToken)
+	response, err := query(method, baseURL, accessToken, endpoint, payload, opts)
+	var apiErr *APIError
+	if renewable && errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusUnauthorized {
+		if accessToken, err = renewSession(GetProfile(), session, opts); err != nil {
+			return nil, err
+		}
+		if seeker, ok := payload.(io.Seeker); ok {
+			if _, err := seeker.Seek(0, io.SeekStart); err != nil {
+				return nil, err
+			}
+		}
+		response, err = query(method, baseURL, accessToken, endpoint, payload, opts)
+	}
+	return response, err
+}
+
+// query makes a query to the Mattermost server with the given base URL, authenticated with
+// the given access token (if not empty).
+func query(method, baseURL, accessToken, endpoint string, payload io.Reader, opts config.Options) (*Response, error) {
+	var err error
 	var url = forgeAPIv4URL(baseURL, endpoint)
 
+	// In trace mode, the payload is read beforehand for being logged.
+	var logger = getLogger()
+	var requestBody []byte
+	if logger != nil && logger.Enabled(context.Background(), slog.LevelDebug) && payload != nil {
+		if requestBody, err = io.ReadAll(payload); err != nil {
+			return nil, err
+		}
+		payload = bytes.NewReader(requestBody)
+	}
+
 	req, err := http.NewRequest(method, url, payload)
 	if err != nil {
 		return nil, err
 	}
-	req.Header.Add("Authorization", bearer)
+	if accessToken != "" {
+		req.Header.Add("Authorization", forgeBearerAuthentication(accessToken))
+	}
 	req.Header.Add("Accept", "application/json")
 	req.Header.Add("Content-Type", "application/json; charset=utf8")
 
-	tr := &http.Transport{
-		TLSClientConfig: &tls.Config{
-			InsecureSkipVerify: opts.SkipTLSVerify,
-		},
+	var timings *queryTimings
+	if logger != nil {
+		req, timings = traceRequest(req)
+		logger.Info("HTTP request", "method", method, "url", url, "headers", redactHeaders(req.Header))
+		if requestBody != nil {
+			logger.Debug("HTTP request body", "method", method, "url", url, "body", redactBody(requestBody))
+		}
 	}
 
-	client := &http.Client{
-		Timeout:   opts.ConnectionTimeout,
-		Transport: tr,
+	client := opts.HTTPClient
+	if client == nil {
+		client = NewHTTPClient(opts)
 	}
 	response, err := client.Do(req)
 	if err != nil {
+		if logger != nil {
+			logger.Error("HTTP query failed", append([]any{"method", method, "url", url, "error", err}, timings.attrs()...)...)
+		}
 		return nil, err
 	}
 
-	if response.StatusCode < 200 || response.StatusCode > 299 {
-		statusCodeText := http.StatusText(response.StatusCode)
-		return nil, fmt.Errorf("the HTTP query to %s has ended with a %d (\"%s\") code",
-			url, response.StatusCode, statusCodeText)
-	}
-
 	// Read body
 	defer response.Body.Close()
 
 	body, err := io.ReadAll(response.Body)
+
+	if logger != nil {
+		logger.Info("HTTP response", append([]any{"method", method, "url", url, "status", response.StatusCode},
+			append(timings.attrs(), "headers", redactHeaders(response.Header))...)...)
+		logger.Debug("HTTP response body", "method", method, "url", url, "body", redactBody(body))
+	}
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		var apiErr struct {
+			ID      string `json:"id"`
+			Message string `json:"message"`
+		}
+		json.Unmarshal(body, &apiErr)
+		return nil, &APIError{
+			URL:        url,
+			StatusCode: response.StatusCode,
+			Header:     response.Header,
+			ID:         apiErr.ID,
+			Message:    apiErr.Message,
+		}
+	}
+
 	if err != nil {
 		return nil, err
 	}
 
 	var data interface{}
-	if err := json.Unmarshal([]byte(body), &data); err != nil {
-		return nil, err
+	if len(body) > 0 {
+		if err := json.Unmarshal([]byte(body), &data); err != nil {
+			return nil, err
+		}
 	}
 
-	return data, nil
+	return &Response{StatusCode: response.StatusCode, Header: response.Header, Data: data}, nil
+}
+
+// Do makes a query to Mattermost with the given method and returns the full response.
+// An *APIError is returned when Mattermost answers with a non-2xx status code.
+func Do(method, endpoint string, payload io.Reader, opts config.Options) (*Response, error) {
+	return queryAPIv4(method, endpoint, payload, opts)
 }
 
 // Get makes a query of type GET to Mattermost.
@@ -94,7 +215,7 @@ func Get(endpoint string, opts config.Options) (interface{}, error) {
 		return nil, err
 	}
 
-	return response, nil
+	return response.Data, nil
 }
 
 // Post makes a query of type POST to Mattermost.
@@ -104,5 +225,25 @@ func Post(endpoint string, payload io.Reader, opts config.Options) (interface{},
 		return nil, err
 	}
 
-	return response, nil
+	return response.Data, nil
+}
+
+// Put makes a query of type PUT to Mattermost.
+func Put(endpoint string, payload io.Reader, opts config.Options) (interface{}, error) {
+	response, err := queryAPIv4(http.MethodPut, endpoint, payload, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return response.Data, nil
+}
+
+// Delete makes a query of type DELETE to Mattermost.
+func Delete(endpoint string, opts config.Options) (interface{}, error) {
+	response, err := queryAPIv4(http.MethodDelete, endpoint, nil, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return response.Data, nil
 }
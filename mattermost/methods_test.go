@@ -0,0 +1,124 @@
+/*
+  Copyright 2026 Davide Madrisan <d.madrisan@proton.me>
+
+  Licensed under the Mozilla Public License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+      https://www.mozilla.org/en-US/MPL/2.0/
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+*/
+
+package mattermost
+
+import (
+	"bytes"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"github.com/madrisan/go-mattermost-notify/config"
+	"github.com/madrisan/go-mattermost-notify/mattermost/mattermosttest"
+)
+
+// useServer points the Mattermost URL and access token to the given fake server.
+func useServer(t testing.TB, srv *mattermosttest.Server) {
+	oldURL, oldToken := viper.Get("url"), viper.Get("access-token")
+	t.Cleanup(func() {
+		viper.Set("url", oldURL)
+		viper.Set("access-token", oldToken)
+	})
+	viper.Set("url", srv.URL)
+	viper.Set("access-token", srv.Token)
+}
+
+func TestQueryAPIv4(t *testing.T) {
+	srv := mattermosttest.NewServer()
+	defer srv.Close()
+	useServer(t, srv)
+
+	channel := srv.AddChannel("town-square")
+	opts := config.Options{ConnectionTimeout: 5 * time.Second}
+
+	payload, err := CreateMsgPayload("#00FF00", channel.ID, "CI", "Build succeeded", "Build")
+	if err != nil {
+		t.Fatal(err)
+	}
+	response, err := Post("/posts", bytes.NewReader(payload), opts)
+	if err != nil {
+		t.Fatal("Post has failed:", err)
+	}
+	postID, _ := response.(map[string]interface{})["id"].(string)
+
+	if _, err := Put("/posts/"+postID+"/patch", strings.NewReader(`{"message": "updated"}`), opts); err != nil {
+		t.Fatal("Put has failed:", err)
+	}
+	response, err = Get("/posts/"+postID, opts)
+	if err != nil {
+		t.Fatal("Get has failed:", err)
+	}
+	if v := response.(map[string]interface{})["message"]; v != "updated" {
+		t.Error("the post should have been updated, got", v)
+	}
+
+	posts := srv.Posts()
+	if len(posts) != 1 || posts[0].ChannelID != channel.ID || posts[0].Message != "updated" {
+		t.Error("unexpected posts", posts)
+	}
+
+	requests := srv.Requests()
+	if len(requests) != 3 || requests[0].Header.Get("Content-Type") != "application/json; charset=utf8" {
+		t.Error("unexpected requests", requests)
+	}
+
+	srv.SetError(http.MethodGet, "/api/v4/users/me", http.StatusServiceUnavailable)
+	_, err = Get("/users/me", opts)
+	if err == nil || !strings.Contains(err.Error(), `503 ("Service Unavailable")`) {
+		t.Error("a 503 error was expected, got", err)
+	}
+
+	viper.Set("access-token", "wrong")
+	if _, err := Get("/users/me", opts); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Error("a 401 error was expected, got", err)
+	}
+}
+
+func TestLocalMode(t *testing.T) {
+	srv, err := mattermosttest.NewLocalServer(filepath.Join(t.TempDir(), "mattermost_local.socket"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Close()
+	useServer(t, srv)
+	viper.Set("access-token", "")
+
+	if !IsLocalMode() {
+		t.Fatal("the URL", srv.URL, "should enable the local mode")
+	}
+
+	channel := srv.AddChannel("town-square")
+	payload, err := CreateMsgPayload("#00FF00", channel.ID, "CI", "Build succeeded", "Build")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Post("/posts", bytes.NewReader(payload), config.Options{}); err != nil {
+		t.Fatal("Post through the local mode socket has failed:", err)
+	}
+
+	requests := srv.Requests()
+	if len(requests) != 1 || requests[0].Path != "/api/v4/posts" || requests[0].Header.Get("Authorization") != "" {
+		t.Error("unexpected requests", requests)
+	}
+	if len(srv.Posts()) != 1 {
+		t.Error("the message should have been posted")
+	}
+}
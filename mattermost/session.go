@@ -0,0 +1,232 @@
+/*
+  Copyright 2026 Davide Madrisan <d.madrisan@proton.me>
+
+  Licensed under the Mozilla Public License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+      https://www.mozilla.org/en-US/MPL/2.0/
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+*/
+
+package mattermost
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"github.com/madrisan/go-mattermost-notify/config"
+	"github.com/madrisan/go-mattermost-notify/fileutil"
+)
+
+// DefaultProfile is the profile used when none is set at command-line or in the environment.
+const DefaultProfile = "default"
+
+// sessionCookie is the cookie containing the session token, whose expiry is the session one.
+const sessionCookie = "MMAUTHTOKEN"
+
+// profileRegexp matches the valid profile names, which are used as file names.
+var profileRegexp = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// The identifiers of the errors returned by Mattermost when a valid MFA code is required.
+var mfaErrorIDs = []string{
+	"mfa.validate_token.authenticate.app_error",
+	"api.user.check_user_mfa.bad_code.app_error",
+}
+
+// Session is a Mattermost session opened by the login command, cached per profile.
+type Session struct {
+	URL      string `json:"url"`
+	Token    string `json:"token"`
+	UserID   string `json:"user_id"`
+	Username string `json:"username"`
+	// ExpiresAt is the expiry of the session, zero if unknown.
+	ExpiresAt time.Time `json:"expires_at,omitempty"`
+	// SSO is the SSO service the session has been opened with, empty for a password login.
+	SSO string `json:"sso,omitempty"`
+}
+
+// Expired tells whether the session has expired.
+func (s *Session) Expired() bool {
+	return !s.ExpiresAt.IsZero() && time.Now().After(s.ExpiresAt)
+}
+
+// GetProfile returns the profile set at command-line or via the environment variable
+// MATTERMOST_PROFILE, or DefaultProfile.
+func GetProfile() string {
+	if profile := viper.GetString("profile"); profile != "" {
+		return profile
+	}
+	return DefaultProfile
+}
+
+// CheckProfile returns an error if the given profile name is not valid.
+func CheckProfile(profile string) error {
+	if !profileRegexp.MatchString(profile) {
+		return fmt.Errorf("invalid profile name \"%s\"", profile)
+	}
+	return nil
+}
+
+// getSessionPath returns the path of the session file of the given profile, located in the
+// directory set by the 'sessions.dir' key of the configuration file or in the user config directory.
+func getSessionPath(profile string) (string, error) {
+	if err := CheckProfile(profile); err != nil {
+		return "", err
+	}
+
+	dir := viper.GetString("sessions.dir")
+	if dir == "" {
+		configDir, err := os.UserConfigDir()
+		if err != nil {
+			return "", fmt.Errorf("cannot find the sessions directory: %v", err)
+		}
+		dir = filepath.Join(configDir, "go-mattermost-notify", "sessions")
+	}
+	return filepath.Join(dir, profile+".json"), nil
+}
+
+// LoadSession returns the cached session of the given profile, or nil if there is none.
+func LoadSession(profile string) (*Session, error) {
+	path, err := getSessionPath(profile)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var s Session
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("invalid session file %s: %v", path, err)
+	}
+	return &s, nil
+}
+
+// SaveSession caches the session of the given profile in a file readable only by the user.
+func SaveSession(profile string, s *Session) error {
+	path, err := getSessionPath(profile)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+
+	return fileutil.WriteFile(path, data)
+}
+
+// DeleteSession removes the cached session of the given profile.
+func DeleteSession(profile string) error {
+	path, err := getSessionPath(profile)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// IsMFARequired tells whether the login has failed because a valid MFA code is required.
+func IsMFARequired(err error) bool {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	for _, id := range mfaErrorIDs {
+		if apiErr.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// getSessionExpiry returns the expiry of the session cookie set in the login response, zero if unknown.
+func getSessionExpiry(header http.Header) time.Time {
+	for _, cookie := range (&http.Response{Header: header}).Cookies() {
+		if cookie.Name != sessionCookie {
+			continue
+		}
+		if cookie.MaxAge > 0 {
+			return time.Now().Add(time.Duration(cookie.MaxAge) * time.Second)
+		}
+		return cookie.Expires
+	}
+	return time.Time{}
+}
+
+// Login opens a session on the Mattermost server set at command-line, in the environment, or in
+// the configuration file, through the endpoint /users/login. The MFA code can be left empty when
+// the user has not enabled the multi-factor authentication.
+func Login(loginID, password, mfaCode string, opts config.Options) (*Session, error) {
+	baseURL := viper.GetString("url")
+	if baseURL == "" {
+		return nil, fmt.Errorf("the Mattermost URL has not been set")
+	}
+
+	payload, err := json.Marshal(map[string]string{
+		"login_id": loginID,
+		"password": password,
+		"token":    mfaCode,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := query(http.MethodPost, baseURL, "", "/users/login", bytes.NewReader(payload), opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return newSession(baseURL, response)
+}
+
+// newSession returns the session opened by a login query.
+func newSession(baseURL string, response *Response) (*Session, error) {
+	token := response.Header.Get("Token")
+	if token == "" {
+		return nil, fmt.Errorf("no session token has been returned by Mattermost")
+	}
+	user, _ := response.Data.(map[string]interface{})
+	userID, _ := user["id"].(string)
+	username, _ := user["username"].(string)
+
+	return &Session{
+		URL:       baseURL,
+		Token:     token,
+		UserID:    userID,
+		Username:  username,
+		ExpiresAt: getSessionExpiry(response.Header),
+	}, nil
+}
+
+// Logout revokes the given session through the endpoint /users/logout.
+func Logout(s *Session, opts config.Options) error {
+	_, err := query(http.MethodPost, s.URL, s.Token, "/users/logout", nil, opts)
+	return err
+}
@@ -0,0 +1,98 @@
+/*
+  Copyright 2026 Davide Madrisan <d.madrisan@proton.me>
+
+  Licensed under the Mozilla Public License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+      https://www.mozilla.org/en-US/MPL/2.0/
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+*/
+
+package mattermost
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+func TestGetSessionExpiry(t *testing.T) {
+	header := http.Header{}
+	header.Add("Set-Cookie", "MMUSERID=abc; Path=/")
+	header.Add("Set-Cookie", "MMAUTHTOKEN=xyz; Path=/; Max-Age=3600; HttpOnly")
+	if v := time.Until(getSessionExpiry(header)); v < 59*time.Minute || v > time.Hour {
+		t.Error("the session should expire in one hour, got", v)
+	}
+
+	if v := getSessionExpiry(http.Header{}); !v.IsZero() {
+		t.Error("the expiry should be unknown, got", v)
+	}
+}
+
+func TestSessionAccessToken(t *testing.T) {
+	oldURL, oldToken, oldDir, oldProfile := viper.Get("url"), viper.Get("access-token"), viper.Get("sessions.dir"), viper.Get("profile")
+	defer func() {
+		viper.Set("url", oldURL)
+		viper.Set("access-token", oldToken)
+		viper.Set("sessions.dir", oldDir)
+		viper.Set("profile", oldProfile)
+	}()
+	viper.Set("url", "")
+	viper.Set("access-token", "")
+	viper.Set("sessions.dir", t.TempDir())
+	viper.Set("profile", "prod")
+
+	if _, _, err := getAccessToken(); err == nil {
+		t.Fatal("an error was expected without session")
+	}
+
+	session := &Session{URL: "https://mattermost.example.com", Token: "session-token", ExpiresAt: time.Now().Add(time.Hour)}
+	if err := SaveSession("prod", session); err != nil {
+		t.Fatal(err)
+	}
+	if token, _, err := getAccessToken(); err != nil || token != "session-token" {
+		t.Errorf("expected the session token, got %s (%v)", token, err)
+	}
+	if url, err := getURL(); err != nil || url != session.URL {
+		t.Errorf("expected the session URL, got %s (%v)", url, err)
+	}
+
+	viper.Set("url", "https://other.example.com")
+	if _, _, err := getAccessToken(); err == nil {
+		t.Error("the session of another server should not be used")
+	}
+	viper.Set("url", session.URL)
+
+	viper.Set("access-token", "personal-token")
+	if token, _, _ := getAccessToken(); token != "personal-token" {
+		t.Error("the access token should have precedence over the session, got", token)
+	}
+	viper.Set("access-token", "")
+
+	session.ExpiresAt = time.Now().Add(-time.Minute)
+	if err := SaveSession("prod", session); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := getAccessToken(); err == nil || !strings.Contains(err.Error(), "expired") {
+		t.Error("an expired session error was expected, got", err)
+	}
+
+	if err := DeleteSession("prod"); err != nil {
+		t.Fatal(err)
+	}
+	if s, err := LoadSession("prod"); s != nil || err != nil {
+		t.Error("the session should have been deleted", s, err)
+	}
+	if _, err := LoadSession("../prod"); err == nil {
+		t.Error("the invalid profile names should be rejected")
+	}
+}
@@ -0,0 +1,238 @@
+/*
+  Copyright 2026 Davide Madrisan <d.madrisan@proton.me>
+
+  Licensed under the Mozilla Public License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+      https://www.mozilla.org/en-US/MPL/2.0/
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+*/
+
+package mattermost
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/madrisan/go-mattermost-notify/config"
+)
+
+// ssoServices are the SSO services supported by Mattermost, with the client configuration
+// key telling whether they are enabled, in order of preference.
+var ssoServices = []struct {
+	name      string
+	configKey string
+}{
+	{"gitlab", "EnableSignUpWithGitLab"},
+	{"openid", "EnableSignUpWithOpenId"},
+	{"google", "EnableSignUpWithGoogle"},
+	{"office365", "EnableSignUpWithOffice365"},
+}
+
+// SSOTimeout is the maximum time allowed to the user for authenticating with the identity provider.
+var SSOTimeout = 5 * time.Minute
+
+// SessionRenewer, when set, is called to open a new session when the cached SSO session of the
+// current profile has expired or has been revoked. The new session replaces the cached one and
+// the query is retried.
+var SessionRenewer func(expired *Session, opts config.Options) (*Session, error)
+
+// sessionExpiredError is the error returned when the cached session of a profile has expired.
+type sessionExpiredError struct {
+	profile string
+	session *Session
+}
+
+// Error returns the description of the session expiry.
+func (e *sessionExpiredError) Error() string {
+	return fmt.Sprintf("the session of the profile %s has expired, please login again", e.profile)
+}
+
+// GetSSOService returns the first SSO service enabled in the Mattermost server with the given URL.
+func GetSSOService(baseURL string, opts config.Options) (string, error) {
+	response, err := query(http.MethodGet, baseURL, "", "/config/client?format=old", nil, opts)
+	if err != nil {
+		return "", err
+	}
+
+	clientConfig, _ := response.Data.(map[string]interface{})
+	for _, service := range ssoServices {
+		if enabled, _ := clientConfig[service.configKey].(string); enabled == "true" {
+			return service.name, nil
+		}
+	}
+	return "", fmt.Errorf("no SSO service is enabled in Mattermost")
+}
+
+// randomString returns a URL-safe random string made from n random bytes.
+func randomString(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// getCodeChallenge returns the PKCE S256 code challenge of the given code verifier.
+func getCodeChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// ssoCallback is the result of the SSO login sent by Mattermost to the local callback.
+type ssoCallback struct {
+	loginCode string
+	err       error
+}
+
+// waitSSOCallback serves the local callback the browser is redirected to at the end of the SSO login,
+// and returns the login code sent by Mattermost.
+func waitSSOCallback(l net.Listener, state string) (string, error) {
+	var result = make(chan ssoCallback, 1)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /callback", func(w http.ResponseWriter, r *http.Request) {
+		var cb ssoCallback
+		query := r.URL.Query()
+		switch {
+		case query.Get("error") != "":
+			cb.err = fmt.Errorf("the SSO login has failed: %s", query.Get("error"))
+		case query.Get("state") != state:
+			cb.err = fmt.Errorf("the SSO login has failed: state mismatch")
+		case query.Get("login_code") == "":
+			cb.err = fmt.Errorf("the SSO login has failed: no login code")
+		default:
+			cb.loginCode = query.Get("login_code")
+		}
+
+		if cb.err != nil {
+			http.Error(w, cb.err.Error(), http.StatusBadRequest)
+		} else {
+			fmt.Fprintln(w, "The login is completed, you can close this window.")
+		}
+		select {
+		case result <- cb:
+		default:
+		}
+	})
+
+	srv := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go srv.Serve(l)
+	defer srv.Shutdown(context.Background())
+
+	select {
+	case cb := <-result:
+		return cb.loginCode, cb.err
+	case <-time.After(SSOTimeout):
+		return "", fmt.Errorf("the SSO login has not been completed within %s", SSOTimeout)
+	}
+}
+
+// SSOLogin opens a session on the Mattermost server with the given URL through the given SSO
+// service ("gitlab", "openid", ...). The login URL is passed to openBrowser, and the user is
+// redirected to a local callback at the end of the login. The login code received there is
+// exchanged for a session token, using PKCE so that it cannot be used by anyone else.
+func SSOLogin(baseURL, service string, openBrowser func(url string) error, opts config.Options) (*Session, error) {
+	verifier, err := randomString(32)
+	if err != nil {
+		return nil, err
+	}
+	state, err := randomString(16)
+	if err != nil {
+		return nil, err
+	}
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, fmt.Errorf("cannot listen for the SSO callback: %v", err)
+	}
+	defer l.Close()
+
+	loginURL := getAPIBaseURL(baseURL) + "/oauth/" + url.PathEscape(service) + "/mobile_login?" + url.Values{
+		"redirect_to":           {"http://" + l.Addr().String() + "/callback"},
+		"state":                 {state},
+		"code_challenge":        {getCodeChallenge(verifier)},
+		"code_challenge_method": {"S256"},
+	}.Encode()
+	if err := openBrowser(loginURL); err != nil {
+		return nil, err
+	}
+
+	loginCode, err := waitSSOCallback(l, state)
+	if err != nil {
+		return nil, err
+	}
+
+	payload, err := json.Marshal(map[string]string{
+		"login_code":    loginCode,
+		"code_verifier": verifier,
+		"state":         state,
+	})
+	if err != nil {
+		return nil, err
+	}
+	response, err := query(http.MethodPost, baseURL, "", "/users/login/sso/code-exchange", bytes.NewReader(payload), opts)
+	if err != nil {
+		return nil, err
+	}
+	tokens, _ := response.Data.(map[string]interface{})
+	token, _ := tokens["token"].(string)
+	if token == "" {
+		return nil, fmt.Errorf("no session token has been returned by Mattermost")
+	}
+
+	// The session expiry is not returned by the code exchange: the session is renewed
+	// when Mattermost rejects it.
+	response, err = query(http.MethodGet, baseURL, token, "/users/me", nil, opts)
+	if err != nil {
+		return nil, err
+	}
+	response.Header.Set("Token", token)
+	session, err := newSession(baseURL, response)
+	if err != nil {
+		return nil, err
+	}
+	session.SSO = service
+	return session, nil
+}
+
+// renewMu serializes the session renewals, so the concurrent queries rejected at the same
+// time (like the ones of the batch mode workers) only prompt the user once.
+var renewMu sync.Mutex
+
+// renewSession opens a new session with SessionRenewer in place of the given one of the given
+// profile, caches it, and returns its token.
+func renewSession(profile string, session *Session, opts config.Options) (string, error) {
+	renewMu.Lock()
+	defer renewMu.Unlock()
+
+	// The session may have been renewed by a concurrent query in the meantime.
+	if cached, _ := LoadSession(profile); cached != nil && cached.Token != session.Token && !cached.Expired() {
+		return cached.Token, nil
+	}
+
+	renewed, err := SessionRenewer(session, opts)
+	if err != nil {
+		return "", err
+	}
+	if err := SaveSession(profile, renewed); err != nil {
+		return "", err
+	}
+	return renewed.Token, nil
+}
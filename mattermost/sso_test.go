@@ -0,0 +1,133 @@
+/*
+  Copyright 2026 Davide Madrisan <d.madrisan@proton.me>
+
+  Licensed under the Mozilla Public License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+      https://www.mozilla.org/en-US/MPL/2.0/
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+*/
+
+package mattermost
+
+import (
+	"bytes"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"github.com/madrisan/go-mattermost-notify/config"
+	"github.com/madrisan/go-mattermost-notify/mattermost/mattermosttest"
+)
+
+// browse follows the redirections of the SSO login like a browser would do.
+func browse(url string) error {
+	go func() {
+		if response, err := http.Get(url); err == nil {
+			response.Body.Close()
+		}
+	}()
+	return nil
+}
+
+func TestGetCodeChallenge(t *testing.T) {
+	// The example of the RFC 7636, appendix B.
+	verifier := "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+	if v := getCodeChallenge(verifier); v != "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM" {
+		t.Error("unexpected code challenge", v)
+	}
+}
+
+func TestSSOLogin(t *testing.T) {
+	idp := mattermosttest.NewIdentityProvider("alice")
+	defer idp.Close()
+	srv := mattermosttest.NewServer()
+	defer srv.Close()
+
+	if _, err := GetSSOService(srv.URL, config.Options{}); err == nil {
+		t.Error("no SSO service should be enabled")
+	}
+	srv.EnableSSO("gitlab", idp.URL)
+	service, err := GetSSOService(srv.URL, config.Options{})
+	if err != nil || service != "gitlab" {
+		t.Fatalf("expected gitlab, got %s (%v)", service, err)
+	}
+
+	session, err := SSOLogin(srv.URL, service, browse, config.Options{})
+	if err != nil {
+		t.Fatal("the SSO login has failed:", err)
+	}
+	if session.Username != "alice" || session.SSO != "gitlab" || session.Token == "" || session.URL != srv.URL {
+		t.Errorf("unexpected session %+v", session)
+	}
+
+	// The login through a disabled service ends in the browser with an error page.
+	oldTimeout := SSOTimeout
+	defer func() { SSOTimeout = oldTimeout }()
+	SSOTimeout = 100 * time.Millisecond
+	if _, err := SSOLogin(srv.URL, "google", browse, config.Options{}); err == nil {
+		t.Error("the login through a disabled SSO service should fail")
+	}
+}
+
+func TestSSOSessionRenewal(t *testing.T) {
+	idp := mattermosttest.NewIdentityProvider("alice")
+	defer idp.Close()
+	srv := mattermosttest.NewServer()
+	defer srv.Close()
+	srv.EnableSSO("openid", idp.URL)
+	channel := srv.AddChannel("town-square")
+
+	oldURL, oldToken, oldDir, oldRenewer := viper.Get("url"), viper.Get("access-token"), viper.Get("sessions.dir"), SessionRenewer
+	defer func() {
+		viper.Set("url", oldURL)
+		viper.Set("access-token", oldToken)
+		viper.Set("sessions.dir", oldDir)
+		SessionRenewer = oldRenewer
+	}()
+	viper.Set("url", srv.URL)
+	viper.Set("access-token", "")
+	viper.Set("sessions.dir", t.TempDir())
+
+	var renewals int
+	SessionRenewer = func(expired *Session, opts config.Options) (*Session, error) {
+		renewals++
+		return SSOLogin(expired.URL, expired.SSO, browse, opts)
+	}
+
+	session, err := SSOLogin(srv.URL, "openid", browse, config.Options{})
+	if err != nil {
+		t.Fatal("the SSO login has failed:", err)
+	}
+	if err := SaveSession(GetProfile(), session); err != nil {
+		t.Fatal(err)
+	}
+
+	// The revoked session is renewed, and the query retried with the same payload.
+	srv.RevokeSessions()
+	payload := []byte(`{"channel_id": "` + channel.ID + `", "message": "hello"}`)
+	if _, err := Post("/posts", bytes.NewReader(payload), config.Options{}); err != nil {
+		t.Fatal("the query should have succeeded after the renewal:", err)
+	}
+	if posts := srv.Posts(); renewals != 1 || len(posts) != 1 || posts[0].Message != "hello" {
+		t.Errorf("unexpected posts %+v after %d renewals", posts, renewals)
+	}
+	if cached, _ := LoadSession(GetProfile()); cached == nil || cached.Token == session.Token {
+		t.Error("the renewed session should have been cached")
+	}
+
+	// The sessions opened with a password are not renewed.
+	SessionRenewer = nil
+	srv.RevokeSessions()
+	if _, err := Get("/users/me", config.Options{}); err == nil {
+		t.Error("the revoked session should be rejected")
+	}
+}
@@ -0,0 +1,163 @@
+/*
+  Copyright 2026 Davide Madrisan <d.madrisan@proton.me>
+
+  Licensed under the Mozilla Public License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+      https://www.mozilla.org/en-US/MPL/2.0/
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+*/
+
+package mattermost
+
+import (
+	"crypto/tls"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptrace"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// logOutput is where the HTTP queries are logged in verbose and trace modes.
+var logOutput io.Writer = os.Stderr
+
+// redacted replaces the secrets in the logs.
+const redacted = "[REDACTED]"
+
+// redactedHeaders are the HTTP headers containing secrets.
+var redactedHeaders = []string{"Authorization", "Cookie", "Set-Cookie", "Token"}
+
+// redactedBodyRegexp matches the secrets in the JSON bodies.
+var redactedBodyRegexp = regexp.MustCompile(`("(?:password|token|access_token|refresh_token|client_secret|login_code|code_verifier)"\s*:\s*)"(?:[^"\\]|\\.)*"`)
+
+// getLogger returns the logger of the HTTP queries set at command-line (flags --verbose and
+// --trace, and --log-format text or json), or nil when the queries must not be logged.
+// The request and response bodies are logged at debug level, enabled by the trace mode.
+func getLogger() *slog.Logger {
+	var level slog.Level
+	switch {
+	case viper.GetBool("trace"):
+		level = slog.LevelDebug
+	case viper.GetBool("verbose"):
+		level = slog.LevelInfo
+	default:
+		return nil
+	}
+
+	var opts = &slog.HandlerOptions{Level: level}
+	if strings.EqualFold(viper.GetString("log-format"), "json") {
+		return slog.New(slog.NewJSONHandler(logOutput, opts))
+	}
+	return slog.New(slog.NewTextHandler(logOutput, opts))
+}
+
+// redactHeaders returns a copy of the HTTP headers with the secrets redacted.
+// The authentication scheme of the Authorization header is kept.
+func redactHeaders(header http.Header) http.Header {
+	h := header.Clone()
+	for _, name := range redactedHeaders {
+		values := h.Values(name)
+		for i, value := range values {
+			if scheme, _, found := strings.Cut(value, " "); found && name == "Authorization" {
+				values[i] = scheme + " " + redacted
+			} else {
+				values[i] = redacted
+			}
+		}
+	}
+	return h
+}
+
+// redactBody returns the body with the values of the JSON secret keys redacted.
+func redactBody(body []byte) string {
+	return redactedBodyRegexp.ReplaceAllString(string(body), `${1}"`+redacted+`"`)
+}
+
+// queryTimings records the timings of an HTTP query through httptrace.
+// The hooks may be called concurrently, for instance when the IPv4 and IPv6 addresses of the
+// server are dialed in parallel, so the timings are protected by a mutex.
+type queryTimings struct {
+	mu       sync.Mutex
+	start    time.Time
+	dnsStart time.Time
+	dnsDone  time.Time
+	// connectStarts are the start times of the connections being dialed, by network and address.
+	connectStarts map[string]time.Time
+	connect       time.Duration
+	tlsStart      time.Time
+	tlsDone       time.Time
+	firstByte     time.Time
+}
+
+// set records the current time in the given timing.
+func (t *queryTimings) set(timing *time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	*timing = time.Now()
+}
+
+// connectStart records the start of a connection to the given address.
+func (t *queryTimings) connectStart(network, addr string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.connectStarts[network+"/"+addr] = time.Now()
+}
+
+// connectDone records the duration of the connection to the given address, if it succeeded.
+func (t *queryTimings) connectDone(network, addr string, err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if start, found := t.connectStarts[network+"/"+addr]; found && err == nil {
+		t.connect = time.Since(start)
+	}
+}
+
+// traceRequest returns a copy of the request recording its timings.
+func traceRequest(req *http.Request) (*http.Request, *queryTimings) {
+	t := &queryTimings{start: time.Now(), connectStarts: make(map[string]time.Time)}
+	trace := &httptrace.ClientTrace{
+		DNSStart:             func(httptrace.DNSStartInfo) { t.set(&t.dnsStart) },
+		DNSDone:              func(httptrace.DNSDoneInfo) { t.set(&t.dnsDone) },
+		ConnectStart:         t.connectStart,
+		ConnectDone:          t.connectDone,
+		TLSHandshakeStart:    func() { t.set(&t.tlsStart) },
+		TLSHandshakeDone:     func(tls.ConnectionState, error) { t.set(&t.tlsDone) },
+		GotFirstResponseByte: func() { t.set(&t.firstByte) },
+	}
+	return req.WithContext(httptrace.WithClientTrace(req.Context(), trace)), t
+}
+
+// since returns the duration between two times, or zero if one of them is unset
+// (for instance, no DNS resolution occurs when a connection is reused).
+func since(start, end time.Time) time.Duration {
+	if start.IsZero() || end.IsZero() {
+		return 0
+	}
+	return end.Sub(start)
+}
+
+// attrs returns the timings as log attributes.
+func (t *queryTimings) attrs() []any {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return []any{
+		slog.Duration("duration", time.Since(t.start)),
+		slog.Duration("dns", since(t.dnsStart, t.dnsDone)),
+		slog.Duration("connect", t.connect),
+		slog.Duration("tls", since(t.tlsStart, t.tlsDone)),
+		slog.Duration("ttfb", since(t.start, t.firstByte)),
+	}
+}
@@ -0,0 +1,150 @@
+/*
+  Copyright 2026 Davide Madrisan <d.madrisan@proton.me>
+
+  Licensed under the Mozilla Public License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+      https://www.mozilla.org/en-US/MPL/2.0/
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+*/
+
+package mattermost
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptrace"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"github.com/madrisan/go-mattermost-notify/config"
+	"github.com/madrisan/go-mattermost-notify/mattermost/mattermosttest"
+)
+
+func TestRedactHeaders(t *testing.T) {
+	t.Parallel()
+
+	h := http.Header{}
+	h.Set("Authorization", "Bearer s3cr3t")
+	h.Set("Token", "s3cr3t")
+	h.Set("Accept", "application/json")
+
+	r := redactHeaders(h)
+	if r.Get("Authorization") != "Bearer [REDACTED]" || r.Get("Token") != "[REDACTED]" || r.Get("Accept") != "application/json" {
+		t.Error("unexpected redacted headers", r)
+	}
+	if h.Get("Authorization") != "Bearer s3cr3t" {
+		t.Error("the original headers should not be modified")
+	}
+}
+
+func TestRedactBody(t *testing.T) {
+	t.Parallel()
+
+	body := `{"login_id": "alice", "password": "p@ss \"word\"", "token" : "s3cr3t"}`
+	shouldBe := `{"login_id": "alice", "password": "[REDACTED]", "token" : "[REDACTED]"}`
+	if v := redactBody([]byte(body)); v != shouldBe {
+		t.Error("expected", shouldBe, "got", v)
+	}
+
+	body = `{"code_verifier":"v3r1f13r","login_code":"c0d3","state":"st4t3"}`
+	shouldBe = `{"code_verifier":"[REDACTED]","login_code":"[REDACTED]","state":"st4t3"}`
+	if v := redactBody([]byte(body)); v != shouldBe {
+		t.Error("expected", shouldBe, "got", v)
+	}
+}
+
+func TestQueryTimingsConnect(t *testing.T) {
+	t.Parallel()
+
+	req, _ := http.NewRequest(http.MethodGet, "http://localhost", nil)
+	req, timings := traceRequest(req)
+	trace := httptrace.ContextClientTrace(req.Context())
+
+	// The IPv4 and IPv6 addresses are dialed in parallel: only the successful connection is timed.
+	var wg sync.WaitGroup
+	for _, addr := range []string{"127.0.0.1:80", "[::1]:80"} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			trace.ConnectStart("tcp", addr)
+			if addr == "127.0.0.1:80" {
+				time.Sleep(10 * time.Millisecond)
+				trace.ConnectDone("tcp", addr, nil)
+			} else {
+				trace.ConnectDone("tcp", addr, errors.New("connection refused"))
+			}
+		}()
+	}
+	wg.Wait()
+
+	var connect time.Duration
+	for _, attr := range timings.attrs() {
+		if attr := attr.(slog.Attr); attr.Key == "connect" {
+			connect = attr.Value.Duration()
+		}
+	}
+	if connect < 10*time.Millisecond {
+		t.Error("unexpected connect duration", connect)
+	}
+}
+
+func TestQueryTrace(t *testing.T) {
+	srv := mattermosttest.NewServer()
+	defer srv.Close()
+	useServer(t, srv)
+
+	var logs bytes.Buffer
+	oldLogOutput := logOutput
+	defer func() {
+		logOutput = oldLogOutput
+		viper.Set("trace", false)
+		viper.Set("log-format", "")
+	}()
+	logOutput = &logs
+	viper.Set("trace", true)
+	viper.Set("log-format", "json")
+
+	channel := srv.AddChannel("town-square")
+	payload := `{"channel_id": "` + channel.ID + `", "message": "hello"}`
+	if _, err := Post("/posts", strings.NewReader(payload), config.Options{}); err != nil {
+		t.Fatal(err)
+	}
+	if len(srv.Posts()) != 1 {
+		t.Error("the payload should be sent after being logged")
+	}
+
+	if strings.Contains(logs.String(), srv.Token) {
+		t.Error("the token should be redacted", logs.String())
+	}
+
+	var messages []string
+	for _, line := range strings.Split(strings.TrimSpace(logs.String()), "\n") {
+		var entry map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatal("invalid JSON log line", line)
+		}
+		messages = append(messages, entry["msg"].(string))
+		if entry["msg"] == "HTTP response" {
+			if entry["status"] != float64(http.StatusCreated) || entry["ttfb"] == nil {
+				t.Error("unexpected response log", line)
+			}
+		}
+	}
+	if v := strings.Join(messages, ", "); v != "HTTP request, HTTP request body, HTTP response, HTTP response body" {
+		t.Error("unexpected log messages", v)
+	}
+}
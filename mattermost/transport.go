@@ -0,0 +1,153 @@
+/*
+  Copyright 2026 Davide Madrisan <d.madrisan@proton.me>
+
+  Licensed under the Mozilla Public License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+      https://www.mozilla.org/en-US/MPL/2.0/
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+*/
+
+package mattermost
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"github.com/madrisan/go-mattermost-notify/config"
+)
+
+// The default connection pool settings, used when not set in the options
+// or in the 'http' section of the configuration file.
+const (
+	defaultMaxIdleConns        = 100
+	defaultMaxIdleConnsPerHost = 16
+	defaultIdleConnTimeout     = 90 * time.Second
+)
+
+// transportKey identifies the settings of a shared transport.
+type transportKey struct {
+	// socket is the path of the Mattermost local mode socket, if any.
+	socket              string
+	skipTLSVerify       bool
+	maxIdleConns        int
+	maxIdleConnsPerHost int
+	idleConnTimeout     time.Duration
+}
+
+var (
+	transportsMu sync.Mutex
+	// transports contains the transports shared by all the queries of the process,
+	// one for each set of settings (in practice, only one).
+	transports = make(map[transportKey]*http.Transport)
+)
+
+// getTransportKey returns the transport settings of the given options, falling back to the
+// 'http' section of the configuration file and then to the defaults.
+func getTransportKey(opts config.Options) transportKey {
+	socket, _ := getSocketPath(viper.GetString("url"))
+	var key = transportKey{
+		socket:              socket,
+		skipTLSVerify:       opts.SkipTLSVerify,
+		maxIdleConns:        opts.MaxIdleConns,
+		maxIdleConnsPerHost: opts.MaxIdleConnsPerHost,
+		idleConnTimeout:     opts.IdleConnTimeout,
+	}
+	if key.maxIdleConns <= 0 {
+		key.maxIdleConns = viper.GetInt("http.max-idle-conns")
+	}
+	if key.maxIdleConns <= 0 {
+		key.maxIdleConns = defaultMaxIdleConns
+	}
+	if key.maxIdleConnsPerHost <= 0 {
+		key.maxIdleConnsPerHost = viper.GetInt("http.max-idle-conns-per-host")
+	}
+	if key.maxIdleConnsPerHost <= 0 {
+		key.maxIdleConnsPerHost = defaultMaxIdleConnsPerHost
+	}
+	if key.idleConnTimeout <= 0 {
+		key.idleConnTimeout = viper.GetDuration("http.idle-conn-timeout")
+	}
+	if key.idleConnTimeout <= 0 {
+		key.idleConnTimeout = defaultIdleConnTimeout
+	}
+	return key
+}
+
+// newTransport returns a transport with keep-alive and HTTP/2 enabled, configured with the given settings.
+func newTransport(key transportKey) *http.Transport {
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+	}
+	dial := dialer.DialContext
+	if key.socket != "" {
+		// The connections to the local mode host are dialed to the Mattermost socket,
+		// the other ones (like the delayed ChatOps responses) are left unchanged.
+		dial = func(ctx context.Context, network, addr string) (net.Conn, error) {
+			if addr == localModeHost+":80" {
+				return dialer.DialContext(ctx, "unix", key.socket)
+			}
+			return dialer.DialContext(ctx, network, addr)
+		}
+	}
+
+	proxy := func(req *http.Request) (*url.URL, error) {
+		if req.URL.Host == localModeHost {
+			return nil, nil
+		}
+		return http.ProxyFromEnvironment(req)
+	}
+
+	return &http.Transport{
+		Proxy:                 proxy,
+		DialContext:           dial,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          key.maxIdleConns,
+		MaxIdleConnsPerHost:   key.maxIdleConnsPerHost,
+		IdleConnTimeout:       key.idleConnTimeout,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: time.Second,
+		TLSClientConfig: &tls.Config{
+			InsecureSkipVerify: key.skipTLSVerify,
+		},
+	}
+}
+
+// SharedTransport returns the transport shared by all the queries of the process made with the
+// given options, so the connections to Mattermost are reused instead of being opened (and their
+// TLS handshake made) for each query.
+func SharedTransport(opts config.Options) *http.Transport {
+	key := getTransportKey(opts)
+
+	transportsMu.Lock()
+	defer transportsMu.Unlock()
+	tr, found := transports[key]
+	if !found {
+		tr = newTransport(key)
+		transports[key] = tr
+	}
+	return tr
+}
+
+// CloseIdleConnections closes the idle connections of the shared transports.
+func CloseIdleConnections() {
+	transportsMu.Lock()
+	defer transportsMu.Unlock()
+	for _, tr := range transports {
+		tr.CloseIdleConnections()
+	}
+}
@@ -0,0 +1,155 @@
+/*
+  Copyright 2026 Davide Madrisan <d.madrisan@proton.me>
+
+  Licensed under the Mozilla Public License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+      https://www.mozilla.org/en-US/MPL/2.0/
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+*/
+
+package mattermost
+
+import (
+	"bytes"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"github.com/madrisan/go-mattermost-notify/config"
+	"github.com/madrisan/go-mattermost-notify/mattermost/mattermosttest"
+)
+
+func TestSharedTransport(t *testing.T) {
+	oldValue := viper.Get("http.max-idle-conns-per-host")
+	defer viper.Set("http.max-idle-conns-per-host", oldValue)
+
+	tr := SharedTransport(config.Options{ConnectionTimeout: time.Second})
+	if tr != SharedTransport(config.Options{ConnectionTimeout: time.Minute}) {
+		t.Error("the options with the same transport settings should share the transport")
+	}
+	if tr == SharedTransport(config.Options{SkipTLSVerify: true}) {
+		t.Error("the transport skipping the certificate check should not be shared")
+	}
+	if tr.MaxIdleConnsPerHost != defaultMaxIdleConnsPerHost || !tr.ForceAttemptHTTP2 {
+		t.Error("unexpected transport settings", tr.MaxIdleConnsPerHost, tr.ForceAttemptHTTP2)
+	}
+
+	viper.Set("http.max-idle-conns-per-host", 32)
+	if v := SharedTransport(config.Options{}).MaxIdleConnsPerHost; v != 32 {
+		t.Error("the configuration key http.max-idle-conns-per-host should be used, got", v)
+	}
+	if v := SharedTransport(config.Options{MaxIdleConnsPerHost: 8}).MaxIdleConnsPerHost; v != 8 {
+		t.Error("the options should override the configuration file, got", v)
+	}
+}
+
+func TestConnectionReuse(t *testing.T) {
+	srv := mattermosttest.NewTLSServer()
+	defer srv.Close()
+	useServer(t, srv)
+
+	opts := config.Options{SkipTLSVerify: true, IdleConnTimeout: time.Minute}
+	for i := 0; i < 4; i++ {
+		if _, err := Get("/users/me", opts); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var connections = make(map[string]bool)
+	for _, r := range srv.Requests() {
+		connections[r.RemoteAddr] = true
+	}
+	if len(connections) != 1 {
+		t.Errorf("the connection should be reused, got %d connections", len(connections))
+	}
+}
+
+// benchmarkFanOut makes the queries of a post to a user (users/me, users/username,
+// channels/direct and posts) with a client returned by newClient.
+func benchmarkFanOut(b *testing.B, newClient func() *http.Client) {
+	srv := mattermosttest.NewTLSServer()
+	defer srv.Close()
+	useServer(b, srv)
+	alice := srv.AddUser("alice")
+	channel := srv.AddChannel("town-square")
+	post, err := CreateMsgPayload("#00FF00", channel.ID, "CI", "Build succeeded", "Build")
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		opts := config.Options{SkipTLSVerify: true}
+		for _, query := range []func() error{
+			func() error { _, err := Get("/users/me", withClient(opts, newClient)); return err },
+			func() error { _, err := Get("/users/username/alice", withClient(opts, newClient)); return err },
+			func() error {
+				payload := []byte(`["` + srv.Me().ID + `", "` + alice.ID + `"]`)
+				_, err := Post("/channels/direct", bytes.NewReader(payload), withClient(opts, newClient))
+				return err
+			},
+			func() error {
+				_, err := Post("/posts", bytes.NewReader(post), withClient(opts, newClient))
+				return err
+			},
+		} {
+			if err := query(); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+// benchmarkBatch makes concurrent queries, as the batch mode of the post command does,
+// with a client returned by newClient.
+func benchmarkBatch(b *testing.B, newClient func() *http.Client) {
+	srv := mattermosttest.NewTLSServer()
+	defer srv.Close()
+	useServer(b, srv)
+
+	b.SetParallelism(4)
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		opts := config.Options{SkipTLSVerify: true}
+		for pb.Next() {
+			if _, err := Get("/users/me", withClient(opts, newClient)); err != nil {
+				b.Error(err)
+				return
+			}
+		}
+	})
+}
+
+// withClient returns the options with the client returned by newClient, if not nil.
+func withClient(opts config.Options, newClient func() *http.Client) config.Options {
+	if newClient != nil {
+		opts.HTTPClient = newClient()
+	}
+	return opts
+}
+
+// newTransportClient returns a client with its own transport, as it was done for each query
+// before the shared transport. Its connection cannot be reused, so it is not kept alive.
+func newTransportClient() *http.Client {
+	tr := newTransport(getTransportKey(config.Options{SkipTLSVerify: true}))
+	tr.DisableKeepAlives = true
+	return &http.Client{Transport: tr}
+}
+
+func BenchmarkFanOut(b *testing.B) {
+	b.Run("new-transport", func(b *testing.B) { benchmarkFanOut(b, newTransportClient) })
+	b.Run("shared-transport", func(b *testing.B) { benchmarkFanOut(b, nil) })
+}
+
+func BenchmarkBatch(b *testing.B) {
+	b.Run("new-transport", func(b *testing.B) { benchmarkBatch(b, newTransportClient) })
+	b.Run("shared-transport", func(b *testing.B) { benchmarkBatch(b, nil) })
+}
@@ -26,10 +26,42 @@ import (
 	"github.com/spf13/viper"
 )
 
+// unixSocketPrefix is the prefix of the URLs of the Unix socket exposed by the Mattermost
+// local mode, like unix:///var/tmp/mattermost_local.socket.
+const unixSocketPrefix = "unix://"
+
+// localModeHost is the host of the API URLs when Mattermost is reached through its local
+// mode socket. The connections to this host are dialed to the socket.
+const localModeHost = "mattermost.socket"
+
+// getSocketPath returns the path of the Mattermost local mode socket when the given URL
+// is in the form unix:///path/to/socket.
+func getSocketPath(baseURL string) (string, bool) {
+	path, found := strings.CutPrefix(baseURL, unixSocketPrefix)
+	return path, found && path != ""
+}
+
+// IsLocalMode tells whether Mattermost is reached through its local mode Unix socket,
+// which requires no access token.
+func IsLocalMode() bool {
+	_, found := getSocketPath(viper.GetString("url"))
+	return found
+}
+
+// getAPIBaseURL returns the base URL of the API requests: the given URL or, for a local mode
+// socket, an HTTP URL whose connections are dialed to the socket.
+func getAPIBaseURL(baseURL string) string {
+	if _, found := getSocketPath(baseURL); found {
+		return "http://" + localModeHost
+	}
+	return baseURL
+}
+
 // forgeAPIv4URL returns the Mattermost APIv4 URL for the given endpoint.
+// The URL of a local mode socket is mapped to the HTTP URL returned by getAPIBaseURL.
 func forgeAPIv4URL(baseURL, endpoint string) string {
 	var url = fmt.Sprintf("%s/api/v4/%s",
-		strings.TrimRight(baseURL, "/"),
+		strings.TrimRight(getAPIBaseURL(baseURL), "/"),
 		strings.TrimLeft(endpoint, "/"))
 	return url
 }
@@ -39,46 +71,137 @@ func forgeBearerAuthentication(accessToken string) string {
 	return "Bearer " + accessToken
 }
 
-// getAccessToken returns the Mattermost token set at command-line or via the environment variable MATTERMOST_ACCESS_TOKEN.
-func getAccessToken() (string, error) {
+// getAccessToken returns the Mattermost token set at command-line or via the environment variable MATTERMOST_ACCESS_TOKEN,
+// or the token of the session of the current profile opened by the login command. The session
+// is returned too, nil when the token is not the one of a session.
+func getAccessToken() (string, *Session, error) {
 	accessToken := viper.GetString("access-token")
-	if accessToken == "" {
-		return "", fmt.Errorf("the Mattermost Access Token has not been set")
+	if accessToken != "" {
+		return accessToken, nil, nil
+	}
+
+	profile := GetProfile()
+	session, err := LoadSession(profile)
+	if err != nil {
+		return "", nil, err
+	}
+	if session == nil || (session.URL != viper.GetString("url") && viper.GetString("url") != "") {
+		return "", nil, fmt.Errorf("the Mattermost Access Token has not been set")
+	}
+	if session.Expired() {
+		return "", nil, &sessionExpiredError{profile: profile, session: session}
 	}
-	return accessToken, nil
+	return session.Token, session, nil
 }
 
-// getUrl returns the Mattermost URL set at command-line or via the environment variable MATTERMOST_URL.
+// getUrl returns the Mattermost URL set at command-line or via the environment variable MATTERMOST_URL,
+// or the URL of the session of the current profile opened by the login command.
 func getURL() (string, error) {
 	baseURL := viper.GetString("url")
+	if baseURL == "" {
+		if session, _ := LoadSession(GetProfile()); session != nil {
+			baseURL = session.URL
+		}
+	}
 	if baseURL == "" {
 		return "", fmt.Errorf("the Mattermost URL has not been set")
 	}
 	return baseURL, nil
 }
 
-// CreateMsgPayload forges the payload containing the message to be posted to Mattermost
-func CreateMsgPayload(
-	attachmentColor,
-	mattermostChannelID,
-	messageAuthor, messageContent, messageTitle string) ([]byte, error) {
+// CheckSettings returns an error if the Mattermost URL, or the access token when required, has not been set.
+func CheckSettings() error {
+	baseURL, err := getURL()
+	if err != nil {
+		return err
+	}
+	if _, local := getSocketPath(baseURL); local {
+		return nil
+	}
+	_, _, err = getAccessToken()
+	return err
+}
+
+// MsgField is a field displayed in a table inside a message attachment.
+type MsgField struct {
+	Title string `json:"title"`
+	Value string `json:"value"`
+	Short bool   `json:"short"`
+}
 
-	type MsgAttachment struct {
-		Author string `json:"author_name"`
-		Color  string `json:"color"`
-		Title  string `json:"title"`
-		Text   string `json:"text"`
+// MsgActionOption is an option of a select menu.
+type MsgActionOption struct {
+	Text  string `json:"text"`
+	Value string `json:"value"`
+}
+
+// MsgIntegration is the integration called by Mattermost when a user clicks on an action.
+// The context is sent back to the integration URL with the action request.
+type MsgIntegration struct {
+	URL     string                 `json:"url"`
+	Context map[string]interface{} `json:"context,omitempty"`
+}
+
+// MsgAction is an interactive button or select menu of a message attachment.
+type MsgAction struct {
+	ID          string            `json:"id"`
+	Name        string            `json:"name"`
+	Type        string            `json:"type,omitempty"`
+	Options     []MsgActionOption `json:"options,omitempty"`
+	Integration MsgIntegration    `json:"integration"`
+}
+
+// MsgAttachment is the attachment containing the message posted to Mattermost.
+type MsgAttachment struct {
+	Author  string      `json:"author_name"`
+	Color   string      `json:"color"`
+	Title   string      `json:"title"`
+	Text    string      `json:"text"`
+	Fields  []MsgField  `json:"fields,omitempty"`
+	Actions []MsgAction `json:"actions,omitempty"`
+}
+
+// MsgProperties contains the properties of a message posted to Mattermost.
+type MsgProperties struct {
+	Attachments []MsgAttachment `json:"attachments"`
+}
+
+// MsgPayload is used to create the JSON payload used when posting a message to Mattermost.
+type MsgPayload struct {
+	ID         string        `json:"channel_id"`
+	Properties MsgProperties `json:"props"`
+	RootID     string        `json:"root_id,omitempty"`
+}
+
+// MsgOption sets an optional property of the payload created by CreateMsgPayload.
+type MsgOption func(*MsgPayload)
+
+// WithFields adds the given fields to the message attachment.
+func WithFields(fields []MsgField) MsgOption {
+	return func(p *MsgPayload) {
+		p.Properties.Attachments[0].Fields = append(p.Properties.Attachments[0].Fields, fields...)
 	}
+}
 
-	type MsgProperties struct {
-		Attachments []MsgAttachment `json:"attachments"`
+// WithActions adds the given buttons and select menus to the message attachment.
+func WithActions(actions []MsgAction) MsgOption {
+	return func(p *MsgPayload) {
+		p.Properties.Attachments[0].Actions = append(p.Properties.Attachments[0].Actions, actions...)
 	}
+}
 
-	// MsgPayload is used to create the JSON payload used when posting a message to Mattermost.
-	type MsgPayload struct {
-		ID         string        `json:"channel_id"`
-		Properties MsgProperties `json:"props"`
+// WithRootID posts the message as a reply in the thread of the post with the given ID.
+func WithRootID(rootID string) MsgOption {
+	return func(p *MsgPayload) {
+		p.RootID = rootID
 	}
+}
+
+// CreateMsgPayload forges the payload containing the message to be posted to Mattermost
+func CreateMsgPayload(
+	attachmentColor,
+	mattermostChannelID,
+	messageAuthor, messageContent, messageTitle string, options ...MsgOption) ([]byte, error) {
 
 	data := MsgPayload{
 		ID: mattermostChannelID,
@@ -94,6 +217,10 @@ func CreateMsgPayload(
 		},
 	}
 
+	for _, option := range options {
+		option(&data)
+	}
+
 	payload, err := json.Marshal(data)
 	if err != nil {
 		return nil, err
@@ -114,6 +114,11 @@ func TestForgeAPIv4URL(t *testing.T) {
 			"/users/me",
 			"http://example.com/mattermost/api/v4/users/me",
 		},
+		{
+			"unix:///var/tmp/mattermost_local.socket",
+			"/posts",
+			"http://mattermost.socket/api/v4/posts",
+		},
 	}
 
 	t.Run("apiv4_url", func(t *testing.T) {
@@ -0,0 +1,263 @@
+/*
+  Copyright 2026 Davide Madrisan <d.madrisan@proton.me>
+
+  Licensed under the Mozilla Public License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+      https://www.mozilla.org/en-US/MPL/2.0/
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+*/
+
+package mattermost
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/madrisan/go-mattermost-notify/config"
+)
+
+const (
+	// wsMinBackoff is the time to wait before the first reconnection attempt.
+	wsMinBackoff = time.Second
+	// wsMaxBackoff is the maximum time to wait between two reconnection attempts.
+	wsMaxBackoff = time.Minute
+	// wsReadTimeout is the maximum time without any message (pings included) from Mattermost.
+	wsReadTimeout = 2 * time.Minute
+)
+
+// EventBroadcast tells to whom a WebSocket event has been sent.
+type EventBroadcast struct {
+	ChannelID string `json:"channel_id,omitempty"`
+	TeamID    string `json:"team_id,omitempty"`
+	UserID    string `json:"user_id,omitempty"`
+}
+
+// Event is an event sent by Mattermost through its WebSocket API.
+type Event struct {
+	Event     string                 `json:"event"`
+	Data      map[string]interface{} `json:"data"`
+	Broadcast EventBroadcast         `json:"broadcast"`
+	Seq       int64                  `json:"seq"`
+}
+
+// ChannelID returns the ID of the channel the event is related to, or an empty string.
+func (e *Event) ChannelID() string {
+	if e.Broadcast.ChannelID != "" {
+		return e.Broadcast.ChannelID
+	}
+	if id, ok := e.Data["channel_id"].(string); ok {
+		return id
+	}
+	return ""
+}
+
+// EventHandler is called for each event received by Listen.
+// Listen stops and returns the error returned by the handler, if any.
+type EventHandler func(Event) error
+
+// forgeWebSocketURL returns the URL of the Mattermost WebSocket API. When connectionID is set,
+// the URL asks Mattermost to resume the connection from the given sequence number.
+func forgeWebSocketURL(baseURL, connectionID string, sequence int64) (string, error) {
+	u, err := url.Parse(forgeAPIv4URL(baseURL, "/websocket"))
+	if err != nil {
+		return "", err
+	}
+
+	switch u.Scheme {
+	case "http":
+		u.Scheme = "ws"
+	case "https":
+		u.Scheme = "wss"
+	default:
+		return "", fmt.Errorf("unsupported URL scheme for WebSocket: %s", u.Scheme)
+	}
+
+	if connectionID != "" {
+		q := u.Query()
+		q.Set("connection_id", connectionID)
+		q.Set("sequence_number", strconv.FormatInt(sequence, 10))
+		u.RawQuery = q.Encode()
+	}
+
+	return u.String(), nil
+}
+
+// wsSession tracks the WebSocket connection, so that it can be resumed after a disconnection
+// without losing any event.
+type wsSession struct {
+	connectionID string
+	// sequence is the sequence number of the next expected event.
+	sequence int64
+}
+
+// receive updates the session with the given event.
+// It returns an error if some events have been lost.
+func (s *wsSession) receive(e *Event) error {
+	if e.Event == "hello" {
+		if id, _ := e.Data["connection_id"].(string); id != s.connectionID {
+			// New connection: Mattermost could not resume the previous one.
+			s.connectionID = id
+			s.sequence = e.Seq + 1
+		}
+		return nil
+	}
+
+	var err error
+	if e.Seq != s.sequence {
+		err = fmt.Errorf("%d WebSocket events have been lost", e.Seq-s.sequence)
+	}
+	s.sequence = e.Seq + 1
+
+	return err
+}
+
+// listenOnce opens a WebSocket connection and calls handler for each event until the connection
+// is closed or ctx is done. The connected function is called once the connection is established
+// and the lost function when some events have been lost.
+func listenOnce(ctx context.Context, baseURL, accessToken string, session *wsSession, opts config.Options,
+	handler EventHandler, connected func(), lost func(error)) error {
+
+	wsURL, err := forgeWebSocketURL(baseURL, session.connectionID, session.sequence)
+	if err != nil {
+		return err
+	}
+
+	dialer := websocket.Dialer{
+		HandshakeTimeout: opts.ConnectionTimeout,
+		TLSClientConfig: &tls.Config{
+			InsecureSkipVerify: opts.SkipTLSVerify,
+		},
+	}
+	header := http.Header{}
+	header.Add("Authorization", forgeBearerAuthentication(accessToken))
+
+	conn, response, err := dialer.DialContext(ctx, wsURL, header)
+	if err != nil {
+		if response != nil {
+			return fmt.Errorf("the WebSocket connection to %s has ended with a %d (\"%s\") code",
+				wsURL, response.StatusCode, http.StatusText(response.StatusCode))
+		}
+		return err
+	}
+	defer conn.Close()
+	connected()
+
+	// Unblock ReadMessage when the context is done.
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-done:
+		}
+	}()
+
+	conn.SetReadDeadline(time.Now().Add(wsReadTimeout))
+	conn.SetPingHandler(func(data string) error {
+		conn.SetReadDeadline(time.Now().Add(wsReadTimeout))
+		return conn.WriteControl(websocket.PongMessage, []byte(data), time.Now().Add(10*time.Second))
+	})
+
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return err
+		}
+		conn.SetReadDeadline(time.Now().Add(wsReadTimeout))
+
+		var e Event
+		if err := json.Unmarshal(data, &e); err != nil {
+			return fmt.Errorf("invalid WebSocket event: %v", err)
+		}
+		if e.Event == "" {
+			// Reply to an action sent by the client.
+			continue
+		}
+
+		if err := session.receive(&e); err != nil {
+			lost(err)
+		}
+		if err := handler(e); err != nil {
+			return &handlerError{err}
+		}
+	}
+}
+
+// handlerError is an error returned by the event handler, which stops Listen.
+type handlerError struct {
+	err error
+}
+
+// Error returns the error message of the handler error.
+func (e *handlerError) Error() string {
+	return e.err.Error()
+}
+
+// Listen connects to the Mattermost WebSocket API and calls handler for each received event,
+// until ctx is done. After a disconnection, it reconnects with an exponential backoff and
+// resumes the event stream from the last received event. The connection errors are reported
+// to the onError function, if not nil.
+func Listen(ctx context.Context, opts config.Options, handler EventHandler, onError func(error)) error {
+	if onError == nil {
+		onError = func(error) {}
+	}
+
+	baseURL, err := getURL()
+	if err != nil {
+		return err
+	}
+	if _, local := getSocketPath(baseURL); local {
+		return fmt.Errorf("the WebSocket API is not available through the Mattermost local mode socket")
+	}
+	accessToken, _, err := getAccessToken()
+	if err != nil {
+		return err
+	}
+
+	var session wsSession
+	var backoff = wsMinBackoff
+
+	for {
+		err := listenOnce(ctx, baseURL, accessToken, &session, opts, handler,
+			func() { backoff = wsMinBackoff },
+			onError)
+
+		if ctx.Err() != nil {
+			return nil
+		}
+		if herr, ok := err.(*handlerError); ok {
+			return herr.err
+		}
+		onError(fmt.Errorf("%v (reconnecting in %s)", err, backoff))
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(backoff):
+		}
+
+		backoff *= 2
+		if backoff > wsMaxBackoff {
+			backoff = wsMaxBackoff
+		}
+	}
+}
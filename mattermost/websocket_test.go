@@ -0,0 +1,137 @@
+/*
+  Copyright 2026 Davide Madrisan <d.madrisan@proton.me>
+
+  Licensed under the Mozilla Public License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+      https://www.mozilla.org/en-US/MPL/2.0/
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+*/
+
+package mattermost
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/spf13/viper"
+
+	"github.com/madrisan/go-mattermost-notify/config"
+)
+
+func TestForgeWebSocketURL(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		baseURL      string
+		connectionID string
+		sequence     int64
+		shouldBe     string
+	}{
+		{"http://example.com/mattermost/", "", 0, "ws://example.com/mattermost/api/v4/websocket"},
+		{"https://example.com", "abc", 42, "wss://example.com/api/v4/websocket?connection_id=abc&sequence_number=42"},
+	}
+
+	for _, tc := range cases {
+		v, err := forgeWebSocketURL(tc.baseURL, tc.connectionID, tc.sequence)
+		if err != nil || v != tc.shouldBe {
+			t.Error("For", tc.baseURL, "expected", tc.shouldBe, "got", v, err)
+		}
+	}
+
+	if _, err := forgeWebSocketURL("ftp://example.com", "", 0); err == nil {
+		t.Error("forgeWebSocketURL should fail for unsupported schemes")
+	}
+}
+
+func TestListen(t *testing.T) {
+	var mu sync.Mutex
+	var queries []string
+	var connections int
+
+	upgrader := websocket.Upgrader{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		mu.Lock()
+		queries = append(queries, r.URL.RawQuery)
+		connections++
+		first := connections == 1
+		mu.Unlock()
+
+		if first {
+			conn.WriteMessage(websocket.TextMessage,
+				[]byte(`{"event":"hello","data":{"connection_id":"conn1"},"seq":0}`))
+			for seq := 1; seq <= 2; seq++ {
+				conn.WriteMessage(websocket.TextMessage, []byte(fmt.Sprintf(
+					`{"event":"posted","data":{"channel_id":"channel1"},"broadcast":{},"seq":%d}`, seq)))
+			}
+			// Close the connection to force a reconnection.
+			return
+		}
+
+		conn.WriteMessage(websocket.TextMessage,
+			[]byte(`{"event":"hello","data":{"connection_id":"conn1"},"seq":3}`))
+		conn.WriteMessage(websocket.TextMessage,
+			[]byte(`{"event":"typing","data":{},"broadcast":{"channel_id":"channel2"},"seq":3}`))
+		time.Sleep(time.Second)
+	}))
+	defer srv.Close()
+
+	oldURL, oldToken := viper.Get("url"), viper.Get("access-token")
+	defer func() {
+		viper.Set("url", oldURL)
+		viper.Set("access-token", oldToken)
+	}()
+	viper.Set("url", srv.URL)
+	viper.Set("access-token", "token")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var events []string
+	handler := func(e Event) error {
+		events = append(events, fmt.Sprintf("%s:%s:%d", e.Event, e.ChannelID(), e.Seq))
+		if e.Event == "typing" {
+			cancel()
+		}
+		return nil
+	}
+	var lost []error
+	onError := func(err error) {
+		lost = append(lost, err)
+	}
+
+	if err := Listen(ctx, config.Options{ConnectionTimeout: 5 * time.Second}, handler, onError); err != nil {
+		t.Fatal("Listen has failed:", err)
+	}
+
+	if fmt.Sprint(events) != "[hello::0 posted:channel1:1 posted:channel1:2 hello::3 typing:channel2:3]" {
+		t.Error("unexpected events", events)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(queries) != 2 || queries[1] != "connection_id=conn1&sequence_number=3" {
+		t.Error("the second connection should resume the first one, got queries", queries)
+	}
+}
@@ -0,0 +1,190 @@
+/*
+  Copyright 2026 Davide Madrisan <d.madrisan@proton.me>
+
+  Licensed under the Mozilla Public License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+      https://www.mozilla.org/en-US/MPL/2.0/
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+*/
+
+// Package spool implements a local storage for the Mattermost posts that could not be delivered.
+package spool
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/madrisan/go-mattermost-notify/fileutil"
+)
+
+// Item is a Mattermost post that could not be delivered.
+type Item struct {
+	// Created is the time the post has been spooled.
+	Created time.Time `json:"created"`
+	// Destination is the channel ID or the @username the post is addressed to.
+	Destination string `json:"destination"`
+	// Endpoint is the Mattermost APIv4 endpoint the payload must be sent to.
+	Endpoint string `json:"endpoint"`
+	// Payload is the JSON payload of the post.
+	Payload json.RawMessage `json:"payload"`
+	// Error is the error that prevented the post from being delivered.
+	Error string `json:"error,omitempty"`
+}
+
+// Entry is an Item stored in the spool directory.
+type Entry struct {
+	// Name is the name of the file containing the item.
+	Name string
+	Item
+}
+
+// Spool is a directory containing the items waiting to be delivered.
+type Spool struct {
+	dir string
+}
+
+// fileExt is the extension of the files containing the spooled items.
+const fileExt = ".json"
+
+// lockFile is the name of the file locking the spool.
+const lockFile = ".lock"
+
+// RejectedDir is the subdirectory of the spool containing the items that cannot be delivered.
+const RejectedDir = "rejected"
+
+// ErrLocked is returned by Lock when the spool is already locked.
+var ErrLocked = fileutil.ErrLocked
+
+// New returns a spool stored in the given directory.
+func New(dir string) *Spool {
+	return &Spool{dir: dir}
+}
+
+// Dir returns the directory containing the spooled items.
+func (s *Spool) Dir() string {
+	return s.dir
+}
+
+// Add stores the given item in the spool and returns the name of the file containing it.
+// The file names sort in the order the items have been added.
+func (s *Spool) Add(item Item) (string, error) {
+	if item.Created.IsZero() {
+		item.Created = time.Now()
+	}
+
+	data, err := json.Marshal(item)
+	if err != nil {
+		return "", err
+	}
+
+	if err := os.MkdirAll(s.dir, 0700); err != nil {
+		return "", err
+	}
+
+	// The random suffix keeps apart the items added at the same time by concurrent processes.
+	var suffix [8]byte
+	if _, err := rand.Read(suffix[:]); err != nil {
+		return "", err
+	}
+	name := fmt.Sprintf("%020d-%x%s", item.Created.UnixNano(), suffix, fileExt)
+
+	// A concurrent flush never reads a partial item.
+	if err := fileutil.WriteFile(filepath.Join(s.dir, name), data); err != nil {
+		return "", err
+	}
+
+	return name, nil
+}
+
+// List returns the spooled items in the order they have been added.
+func (s *Spool) List() ([]Entry, error) {
+	files, err := os.ReadDir(s.dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var entries []Entry
+	for _, f := range files {
+		if f.IsDir() || !strings.HasSuffix(f.Name(), fileExt) || strings.HasPrefix(f.Name(), ".") {
+			continue
+		}
+
+		data, err := os.ReadFile(filepath.Join(s.dir, f.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		var entry = Entry{Name: f.Name()}
+		if err := json.Unmarshal(data, &entry.Item); err != nil {
+			return nil, fmt.Errorf("invalid spool file %s: %v", f.Name(), err)
+		}
+		entries = append(entries, entry)
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name < entries[j].Name
+	})
+
+	return entries, nil
+}
+
+// Remove deletes the item stored in the file with the given name.
+func (s *Spool) Remove(name string) error {
+	return os.Remove(filepath.Join(s.dir, filepath.Base(name)))
+}
+
+// Reject moves the item stored in the file with the given name to the RejectedDir subdirectory,
+// recording the error that prevents it from being delivered, and returns its new path.
+func (s *Spool) Reject(name string, cause error) (string, error) {
+	name = filepath.Base(name)
+	data, err := os.ReadFile(filepath.Join(s.dir, name))
+	if err != nil {
+		return "", err
+	}
+
+	var item Item
+	if err := json.Unmarshal(data, &item); err != nil {
+		return "", fmt.Errorf("invalid spool file %s: %v", name, err)
+	}
+	item.Error = cause.Error()
+	if data, err = json.Marshal(item); err != nil {
+		return "", err
+	}
+
+	dir := filepath.Join(s.dir, RejectedDir)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, name)
+	if err := fileutil.WriteFile(path, data); err != nil {
+		return "", err
+	}
+
+	return path, s.Remove(name)
+}
+
+// Lock locks the spool, so that its items are not delivered twice by concurrent flushes,
+// and returns the function releasing the lock. It fails with ErrLocked when the spool is
+// already locked.
+func (s *Spool) Lock() (func() error, error) {
+	if err := os.MkdirAll(s.dir, 0700); err != nil {
+		return nil, err
+	}
+	return fileutil.Lock(filepath.Join(s.dir, lockFile))
+}
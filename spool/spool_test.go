@@ -0,0 +1,125 @@
+/*
+  Copyright 2026 Davide Madrisan <d.madrisan@proton.me>
+
+  Licensed under the Mozilla Public License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+      https://www.mozilla.org/en-US/MPL/2.0/
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+*/
+
+package spool
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSpool(t *testing.T) {
+	s := New(filepath.Join(t.TempDir(), "spool"))
+
+	entries, err := s.List()
+	if err != nil || len(entries) != 0 {
+		t.Fatalf("List on a missing directory: expected no entries, got %v (%v)", entries, err)
+	}
+
+	now := time.Now()
+	destinations := []string{"@alice", "rybfbdi9ojy8xxxjjxc88kh3me", "@bob"}
+	for i, dest := range destinations {
+		_, err := s.Add(Item{
+			Created:     now.Add(time.Duration(i) * time.Second),
+			Destination: dest,
+			Endpoint:    "/posts",
+			Payload:     json.RawMessage(`{"channel_id":""}`),
+		})
+		if err != nil {
+			t.Fatal("Add has failed:", err)
+		}
+	}
+
+	entries, err = s.List()
+	if err != nil {
+		t.Fatal("List has failed:", err)
+	}
+	if len(entries) != len(destinations) {
+		t.Fatalf("expected %d entries, got %d", len(destinations), len(entries))
+	}
+	for i, entry := range entries {
+		if entry.Destination != destinations[i] {
+			t.Errorf("entry %d: expected destination %s, got %s", i, destinations[i], entry.Destination)
+		}
+		if string(entry.Payload) != `{"channel_id":""}` {
+			t.Errorf("entry %d: unexpected payload %s", i, entry.Payload)
+		}
+	}
+
+	if err := s.Remove(entries[0].Name); err != nil {
+		t.Fatal("Remove has failed:", err)
+	}
+	entries, err = s.List()
+	if err != nil || len(entries) != len(destinations)-1 {
+		t.Fatalf("expected %d entries after Remove, got %d (%v)", len(destinations)-1, len(entries), err)
+	}
+	if entries[0].Destination != destinations[1] {
+		t.Errorf("expected destination %s, got %s", destinations[1], entries[0].Destination)
+	}
+}
+
+func TestReject(t *testing.T) {
+	s := New(filepath.Join(t.TempDir(), "spool"))
+
+	name, err := s.Add(Item{Destination: "@alice", Endpoint: "/posts", Payload: json.RawMessage(`{}`)})
+	if err != nil {
+		t.Fatal("Add has failed:", err)
+	}
+	path, err := s.Reject(name, errors.New("forbidden"))
+	if err != nil {
+		t.Fatal("Reject has failed:", err)
+	}
+	if path != filepath.Join(s.Dir(), RejectedDir, name) {
+		t.Error("unexpected path of the rejected item:", path)
+	}
+	if entries, err := s.List(); err != nil || len(entries) != 0 {
+		t.Errorf("expected no entries after Reject, got %v (%v)", entries, err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var item Item
+	if err := json.Unmarshal(data, &item); err != nil || item.Destination != "@alice" || item.Error != "forbidden" {
+		t.Errorf("unexpected rejected item %+v (%v)", item, err)
+	}
+}
+
+func TestLock(t *testing.T) {
+	s := New(filepath.Join(t.TempDir(), "spool"))
+
+	unlock, err := s.Lock()
+	if err != nil {
+		t.Fatal("Lock has failed:", err)
+	}
+	if _, err := s.Lock(); !errors.Is(err, ErrLocked) {
+		t.Error("expected ErrLocked, got", err)
+	}
+	if err := unlock(); err != nil {
+		t.Fatal("unlock has failed:", err)
+	}
+
+	unlock, err = s.Lock()
+	if err != nil {
+		t.Fatal("Lock has failed after unlock:", err)
+	}
+	unlock()
+}
@@ -0,0 +1,130 @@
+/*
+  Copyright 2026 Davide Madrisan <d.madrisan@proton.me>
+
+  Licensed under the Mozilla Public License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+      https://www.mozilla.org/en-US/MPL/2.0/
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+*/
+
+// Package state implements the local JSON files shared by concurrent go-mattermost-notify processes.
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/madrisan/go-mattermost-notify/fileutil"
+)
+
+const (
+	// lockRetryDelay is the time to wait before trying again to acquire a busy lock.
+	lockRetryDelay = 10 * time.Millisecond
+	// lockTimeout is the maximum time allowed for acquiring a lock.
+	lockTimeout = 10 * time.Second
+)
+
+// File is a JSON file protected by a lock file, so that it can be updated by concurrent processes.
+type File struct {
+	path string
+}
+
+// New returns the state file with the given path.
+func New(path string) *File {
+	return &File{path: path}
+}
+
+// Path returns the path of the state file.
+func (f *File) Path() string {
+	return f.path
+}
+
+// lock acquires the lock protecting the state file, waiting at most lockTimeout for the other
+// processes to release it, and returns the function releasing it.
+func (f *File) lock() (func() error, error) {
+	if err := os.MkdirAll(filepath.Dir(f.path), 0700); err != nil {
+		return nil, err
+	}
+
+	var lockPath = f.path + ".lock"
+	var deadline = time.Now().Add(lockTimeout)
+
+	for {
+		unlock, err := fileutil.Lock(lockPath)
+		if !errors.Is(err, fileutil.ErrLocked) {
+			return unlock, err
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("timeout while waiting for the lock %s", lockPath)
+		}
+		time.Sleep(lockRetryDelay)
+	}
+}
+
+// read decodes the content of the state file into v. A missing file leaves v unchanged.
+func (f *File) read(v interface{}) error {
+	data, err := os.ReadFile(f.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	if len(data) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("invalid state file %s: %v", f.path, err)
+	}
+	return nil
+}
+
+// write atomically replaces the content of the state file with the JSON encoding of v.
+func (f *File) write(v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return fileutil.WriteFile(f.path, data)
+}
+
+// Load decodes the content of the state file into v.
+func (f *File) Load(v interface{}) error {
+	unlock, err := f.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	return f.read(v)
+}
+
+// Update decodes the content of the state file into v, calls fn, and saves v if fn returns nil.
+// No other process can access the state file in the meantime.
+func (f *File) Update(v interface{}, fn func() error) error {
+	unlock, err := f.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if err := f.read(v); err != nil {
+		return err
+	}
+	if err := fn(); err != nil {
+		return err
+	}
+
+	return f.write(v)
+}
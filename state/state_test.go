@@ -0,0 +1,68 @@
+/*
+  Copyright 2026 Davide Madrisan <d.madrisan@proton.me>
+
+  Licensed under the Mozilla Public License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+      https://www.mozilla.org/en-US/MPL/2.0/
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+*/
+
+package state
+
+import (
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func TestUpdate(t *testing.T) {
+	f := New(filepath.Join(t.TempDir(), "state", "counters.json"))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var counters map[string]int
+			err := f.Update(&counters, func() error {
+				if counters == nil {
+					counters = make(map[string]int)
+				}
+				counters["updates"]++
+				return nil
+			})
+			if err != nil {
+				t.Error("Update has failed:", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	var counters map[string]int
+	if err := f.Load(&counters); err != nil {
+		t.Fatal("Load has failed:", err)
+	}
+	if counters["updates"] != 20 {
+		t.Error("expected 20 updates, got", counters["updates"])
+	}
+
+	err := f.Update(&counters, func() error {
+		counters["updates"] = 0
+		return fmt.Errorf("rollback")
+	})
+	if err == nil {
+		t.Fatal("Update should return the error of the update function")
+	}
+	counters = nil
+	if err := f.Load(&counters); err != nil || counters["updates"] != 20 {
+		t.Error("a failed update should not change the state file", counters, err)
+	}
+}